@@ -0,0 +1,42 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/markus-wa/demoinfocs-golang/v5/pkg/demoinfocs/cstv/server"
+)
+
+// Run like this: go run cstv_server.go -demo /path/to/demo.dem -addr localhost:8080 -speed 4
+func main() {
+	fl := new(flag.FlagSet)
+
+	demoPathPtr := fl.String("demo", "", "Demo file `path`")
+	addrPtr := fl.String("addr", "localhost:8080", "Address to listen on")
+	speedPtr := fl.Float64("speed", 1, "Playback speed, 1 is real-time")
+
+	err := fl.Parse(os.Args[1:])
+	checkError(err)
+
+	f, err := os.Open(*demoPathPtr)
+	checkError(err)
+
+	s, err := server.New(f, server.Options{Speed: *speedPtr})
+	checkError(err)
+
+	err = f.Close()
+	checkError(err)
+
+	fmt.Printf("Serving %d fragments at http://%s\n", s.Fragments(), *addrPtr)
+
+	err = http.ListenAndServe(*addrPtr, s)
+	checkError(err)
+}
+
+func checkError(err error) {
+	if err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,53 @@
+package demotest
+
+// BitWriter writes bits LSB first, the way bitread.BitReader reads them.
+type BitWriter struct {
+	buf   []byte
+	nBits int
+}
+
+// WriteBits writes the n lowest bits of v.
+func (w *BitWriter) WriteBits(v uint64, n int) {
+	for i := 0; i < n; i++ {
+		if w.nBits%8 == 0 {
+			w.buf = append(w.buf, 0)
+		}
+
+		if v&(1<<i) != 0 {
+			w.buf[w.nBits/8] |= 1 << (w.nBits % 8)
+		}
+
+		w.nBits++
+	}
+}
+
+// WriteBit writes a single bit.
+func (w *BitWriter) WriteBit(b bool) {
+	if b {
+		w.WriteBits(1, 1)
+	} else {
+		w.WriteBits(0, 1)
+	}
+}
+
+// WriteUBitInt writes v the way bitread.BitReader.ReadUBitInt() reads it.
+func (w *BitWriter) WriteUBitInt(v uint) {
+	switch {
+	case v < 16:
+		w.WriteBits(uint64(v), 6)
+	case v < 1<<8:
+		w.WriteBits(uint64(v&15|16), 6)
+		w.WriteBits(uint64(v>>4), 4)
+	case v < 1<<12:
+		w.WriteBits(uint64(v&15|32), 6)
+		w.WriteBits(uint64(v>>4), 8)
+	default:
+		w.WriteBits(uint64(v&15|48), 6)
+		w.WriteBits(uint64(v>>4), 28)
+	}
+}
+
+// Bytes returns the bits written so far, padded with zeros to a full byte.
+func (w *BitWriter) Bytes() []byte {
+	return w.buf
+}
@@ -0,0 +1,239 @@
+// Package demotest builds small synthetic CS2 demos and CSTV broadcasts for tests that can't rely on real demo files.
+package demotest
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strconv"
+	"testing"
+
+	"github.com/golang/snappy"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/markus-wa/demoinfocs-golang/v5/pkg/demoinfocs/msg"
+)
+
+// Demo is a builder for PBDEMS2 demos and CSTV broadcasts.
+// All methods return the Demo so calls can be chained.
+type Demo struct {
+	t         testing.TB
+	buf       bytes.Buffer
+	broadcast bool
+}
+
+// New returns a demo file consisting of the PBDEMS2 header and a DEM_FileHeader frame for the map de_test.
+func New(t testing.TB) *Demo {
+	t.Helper()
+
+	d := &Demo{t: t}
+	d.buf.WriteString("PBDEMS2\x00")
+	d.buf.Write(make([]byte, 8)) // file info & spawn groups offsets
+
+	return d.fileHeader()
+}
+
+// NewBroadcast returns a CSTV broadcast consisting of a DEM_FileHeader frame for the map de_test.
+// Broadcasts have no file header and a different frame encoding than demo files.
+func NewBroadcast(t testing.TB) *Demo {
+	t.Helper()
+
+	d := &Demo{t: t, broadcast: true}
+
+	return d.fileHeader()
+}
+
+func (d *Demo) fileHeader() *Demo {
+	return d.Frame(msg.EDemoCommands_DEM_FileHeader, -1, &msg.CDemoFileHeader{
+		DemoFileStamp:   proto.String("PBDEMS2"),
+		NetworkProtocol: proto.Int32(14070),
+		ServerName:      proto.String("test server"),
+		ClientName:      proto.String("SourceTV Demo"),
+		MapName:         proto.String("de_test"),
+		GameDirectory:   proto.String("csgo"),
+		BuildNum:        proto.Int32(10093),
+		DemoVersionGuid: proto.String("8e9d71ab-04a1-4c01-bb61-acfede27c046"),
+	})
+}
+
+// Offset returns the current byte offset in the demo, i.e. the position of the next frame.
+func (d *Demo) Offset() int {
+	return d.buf.Len()
+}
+
+// Frame adds a frame containing the marshalled message.
+func (d *Demo) Frame(cmd msg.EDemoCommands, tick int32, m proto.Message) *Demo {
+	d.t.Helper()
+
+	b, err := proto.Marshal(m)
+	assert.NoError(d.t, err)
+
+	return d.RawFrame(cmd, tick, b)
+}
+
+// CompressedFrame adds a snappy compressed frame containing the marshalled message.
+func (d *Demo) CompressedFrame(cmd msg.EDemoCommands, tick int32, m proto.Message) *Demo {
+	d.t.Helper()
+
+	b, err := proto.Marshal(m)
+	assert.NoError(d.t, err)
+
+	return d.RawFrame(cmd|msg.EDemoCommands_DEM_IsCompressed, tick, snappy.Encode(nil, b))
+}
+
+// RawFrame adds a frame with the given payload as is.
+func (d *Demo) RawFrame(cmd msg.EDemoCommands, tick int32, payload []byte) *Demo {
+	d.buf.Write(binary.AppendUvarint(nil, uint64(cmd)))
+
+	if d.broadcast {
+		d.buf.Write(binary.LittleEndian.AppendUint32(nil, uint32(tick)))
+		d.buf.WriteByte(0)
+		d.buf.Write(binary.LittleEndian.AppendUint32(nil, uint32(len(payload))))
+	} else {
+		d.buf.Write(binary.AppendUvarint(nil, uint64(uint32(tick))))
+		d.buf.Write(binary.AppendUvarint(nil, uint64(len(payload))))
+	}
+
+	d.buf.Write(payload)
+
+	return d
+}
+
+// Signon adds a DEM_SignonPacket frame containing the given net-messages, followed by a DEM_SyncTick frame.
+func (d *Demo) Signon(msgs ...NetMsg) *Demo {
+	d.packetFrame(msg.EDemoCommands_DEM_SignonPacket, -1, msgs...)
+
+	return d.Frame(msg.EDemoCommands_DEM_SyncTick, -1, &msg.CDemoSyncTick{})
+}
+
+// Packet adds a DEM_Packet frame containing the given net-messages.
+func (d *Demo) Packet(tick int32, msgs ...NetMsg) *Demo {
+	return d.packetFrame(msg.EDemoCommands_DEM_Packet, tick, msgs...)
+}
+
+// packetFrame adds a DEM_Packet or DEM_SignonPacket frame containing the given net-messages.
+// In broadcasts the frame contains the packet data as is instead of a marshalled CDemoPacket.
+func (d *Demo) packetFrame(cmd msg.EDemoCommands, tick int32, msgs ...NetMsg) *Demo {
+	if d.broadcast {
+		return d.RawFrame(cmd, tick, d.PacketData(msgs...))
+	}
+
+	return d.Frame(cmd, tick, &msg.CDemoPacket{Data: d.PacketData(msgs...)})
+}
+
+// Packets adds an empty, compressed DEM_Packet frame for each of the given ticks.
+func (d *Demo) Packets(ticks ...int32) *Demo {
+	for _, tick := range ticks {
+		d.CompressedFrame(msg.EDemoCommands_DEM_Packet, tick, &msg.CDemoPacket{})
+	}
+
+	return d
+}
+
+// TickPacket adds a DEM_Packet frame containing only a net_Tick message.
+func (d *Demo) TickPacket(tick int32) *Demo {
+	return d.Packet(tick, TickMsg(tick))
+}
+
+// FullPacket adds a DEM_FullPacket frame (keyframe) containing the given net-messages.
+func (d *Demo) FullPacket(tick int32, msgs ...NetMsg) *Demo {
+	return d.FullPacketWithTables(tick, nil, msgs...)
+}
+
+// StringTablesFullPacket adds a DEM_FullPacket frame (keyframe) containing the given string tables.
+func (d *Demo) StringTablesFullPacket(tick int32, tables ...*msg.CDemoStringTablesTableT) *Demo {
+	return d.FullPacketWithTables(tick, tables)
+}
+
+// FullPacketWithTables adds a DEM_FullPacket frame (keyframe) containing the given string tables and net-messages.
+func (d *Demo) FullPacketWithTables(tick int32, tables []*msg.CDemoStringTablesTableT, msgs ...NetMsg) *Demo {
+	return d.Frame(msg.EDemoCommands_DEM_FullPacket, tick, &msg.CDemoFullPacket{
+		StringTable: &msg.CDemoStringTables{Tables: tables},
+		Packet:      &msg.CDemoPacket{Data: d.PacketData(msgs...)},
+	})
+}
+
+// FileInfo adds a DEM_FileInfo frame and points the file info offset of the demo header to it.
+func (d *Demo) FileInfo(tick int32, info *msg.CDemoFileInfo) *Demo {
+	d.t.Helper()
+
+	if d.broadcast {
+		d.t.Fatal("broadcasts have no file info offset")
+	}
+
+	binary.LittleEndian.PutUint32(d.buf.Bytes()[8:12], uint32(d.Offset()))
+
+	return d.Frame(msg.EDemoCommands_DEM_FileInfo, tick, info)
+}
+
+// Stop adds a DEM_Stop frame. In broadcasts DEM_Stop has no size and payload.
+func (d *Demo) Stop(tick int32) *Demo {
+	if d.broadcast {
+		d.buf.Write(binary.AppendUvarint(nil, uint64(msg.EDemoCommands_DEM_Stop)))
+		d.buf.Write(binary.LittleEndian.AppendUint32(nil, uint32(tick)))
+		d.buf.WriteByte(0)
+
+		return d
+	}
+
+	return d.Frame(msg.EDemoCommands_DEM_Stop, tick, &msg.CDemoStop{})
+}
+
+// Bytes returns the demo built so far.
+func (d *Demo) Bytes() []byte {
+	return d.buf.Bytes()
+}
+
+// NetMsg is a net-message of a DEM_Packet frame.
+type NetMsg struct {
+	Type int32
+	Msg  proto.Message
+}
+
+// TickMsg returns a net_Tick message for the given tick.
+func TickMsg(tick int32) NetMsg {
+	return NetMsg{int32(msg.NET_Messages_net_Tick), &msg.CNETMsg_Tick{Tick: proto.Uint32(uint32(tick))}}
+}
+
+// PacketData returns the net-messages encoded as the data of a DEM_Packet frame.
+func (d *Demo) PacketData(msgs ...NetMsg) []byte {
+	d.t.Helper()
+
+	var w BitWriter
+
+	for _, m := range msgs {
+		b, err := proto.Marshal(m.Msg)
+		assert.NoError(d.t, err)
+
+		w.WriteUBitInt(uint(m.Type))
+
+		for _, v := range binary.AppendUvarint(nil, uint64(len(b))) {
+			w.WriteBits(uint64(v), 8)
+		}
+
+		for _, v := range b {
+			w.WriteBits(uint64(v), 8)
+		}
+	}
+
+	return w.Bytes()
+}
+
+// UserInfoTable returns a userinfo string table with the given players, indexed by player slot.
+func UserInfoTable(t testing.TB, players map[int]*msg.CMsgPlayerInfo) *msg.CDemoStringTablesTableT {
+	t.Helper()
+
+	tab := &msg.CDemoStringTablesTableT{TableName: proto.String("userinfo")}
+
+	for slot, info := range players {
+		b, err := proto.Marshal(info)
+		assert.NoError(t, err)
+
+		tab.Items = append(tab.Items, &msg.CDemoStringTablesItemsT{
+			Str:  proto.String(strconv.Itoa(slot)),
+			Data: b,
+		})
+	}
+
+	return tab
+}
@@ -0,0 +1,517 @@
+package demotest
+
+import (
+	"encoding/binary"
+	"math"
+	"slices"
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/markus-wa/demoinfocs-golang/v5/pkg/demoinfocs/msg"
+)
+
+// Class is a server class, its serializer has the same name and consists of the given fields.
+type Class struct {
+	ID     int32
+	Name   string
+	Fields []Field
+}
+
+// Field is a property of a server class.
+//
+// Fields with sub-fields are pointer tables, e.g. m_pGameRules of CCSGameRulesProxy.
+// Their Type is the name of the nested serializer followed by '*' and their values are addressed as
+// "<field>.<sub-field>", like the parser names them.
+//
+// Only simple types are supported (bool, signed & unsigned integers, float32 without quantization, strings and Vector).
+type Field struct {
+	Name   string
+	Type   string
+	Fields []Field
+}
+
+func (f Field) serializerName() string {
+	if len(f.Fields) == 0 {
+		return ""
+	}
+
+	return strings.TrimSuffix(f.Type, "*")
+}
+
+// EntityOp is the kind of change of an EntityUpdate.
+type EntityOp int
+
+const (
+	EntityOpCreate EntityOp = iota
+	EntityOpUpdate
+	EntityOpLeave
+	EntityOpDelete
+)
+
+// EntityUpdate is a single entry of a PacketEntities message.
+type EntityUpdate struct {
+	Op     EntityOp
+	Index  int32
+	Class  int32          // Only for EntityOpCreate
+	Serial int32          // Only for EntityOpCreate
+	Props  map[string]any // Property values by name, for EntityOpCreate & EntityOpUpdate
+}
+
+// Create returns an EntityUpdate that creates an entity of the given class.
+func Create(index, classID int32, props map[string]any) EntityUpdate {
+	return EntityUpdate{Op: EntityOpCreate, Index: index, Class: classID, Props: props}
+}
+
+// Update returns an EntityUpdate that changes properties of an existing entity.
+func Update(index int32, props map[string]any) EntityUpdate {
+	return EntityUpdate{Op: EntityOpUpdate, Index: index, Props: props}
+}
+
+// Leave returns an EntityUpdate for an entity leaving the PVS.
+func Leave(index int32) EntityUpdate {
+	return EntityUpdate{Op: EntityOpLeave, Index: index}
+}
+
+// Delete returns an EntityUpdate that deletes an entity.
+func Delete(index int32) EntityUpdate {
+	return EntityUpdate{Op: EntityOpDelete, Index: index}
+}
+
+// Entities encodes server classes and entity updates the way the sendtablescs2 parser reads them.
+type Entities struct {
+	t          testing.TB
+	classes    []*Class
+	classByID  map[int32]*Class
+	classOf    map[int32]*Class // Class of each entity, by index
+	maxClasses int32
+}
+
+// NewEntities returns an encoder for the given classes.
+func NewEntities(t testing.TB, classes ...*Class) *Entities {
+	t.Helper()
+
+	e := &Entities{
+		t:          t,
+		classes:    classes,
+		classByID:  make(map[int32]*Class),
+		classOf:    make(map[int32]*Class),
+		maxClasses: 1,
+	}
+
+	for _, c := range classes {
+		e.classByID[c.ID] = c
+		e.maxClasses = max(e.maxClasses, c.ID+1)
+	}
+
+	return e
+}
+
+// Signon is like Demo.Signon() but also adds the svc_ServerInfo message as well as the
+// DEM_SendTables & DEM_ClassInfo frames needed to decode entities.
+func (e *Entities) Signon(d *Demo, msgs ...NetMsg) *Demo {
+	d.packetFrame(msg.EDemoCommands_DEM_SignonPacket, -1, e.ServerInfo())
+	d.Frame(msg.EDemoCommands_DEM_SendTables, -1, e.SendTables())
+	d.Frame(msg.EDemoCommands_DEM_ClassInfo, -1, e.ClassInfo())
+
+	return d.Signon(msgs...)
+}
+
+// ServerInfo returns a svc_ServerInfo message with MaxClasses matching the classes.
+func (e *Entities) ServerInfo() NetMsg {
+	return NetMsg{Type: int32(msg.SVC_Messages_svc_ServerInfo), Msg: &msg.CSVCMsg_ServerInfo{
+		MaxClasses: proto.Int32(e.maxClasses),
+		MaxClients: proto.Int32(64),
+	}}
+}
+
+// ClassInfo returns the DEM_ClassInfo message for all classes.
+func (e *Entities) ClassInfo() *msg.CDemoClassInfo {
+	m := &msg.CDemoClassInfo{}
+
+	for _, c := range e.classes {
+		m.Classes = append(m.Classes, &msg.CDemoClassInfoClassT{
+			ClassId:     proto.Int32(c.ID),
+			NetworkName: proto.String(c.Name),
+		})
+	}
+
+	return m
+}
+
+// SendTables returns the DEM_SendTables message containing the serializers of all classes.
+func (e *Entities) SendTables() *msg.CDemoSendTables {
+	e.t.Helper()
+
+	ser := &msg.CSVCMsg_FlattenedSerializer{}
+	symbols := make(map[string]int32)
+
+	sym := func(s string) *int32 {
+		if s == "" {
+			return nil
+		}
+
+		i, ok := symbols[s]
+		if !ok {
+			i = int32(len(ser.Symbols))
+			symbols[s] = i
+			ser.Symbols = append(ser.Symbols, s)
+		}
+
+		return proto.Int32(i)
+	}
+
+	var addSerializer func(name string, fields []Field)
+
+	addSerializer = func(name string, fields []Field) {
+		s := &msg.ProtoFlattenedSerializerT{
+			SerializerNameSym: sym(name),
+			SerializerVersion: proto.Int32(0),
+		}
+
+		for _, f := range fields {
+			// nested serializers need to be known before the fields referencing them
+			if f.serializerName() != "" {
+				addSerializer(f.serializerName(), f.Fields)
+			}
+
+			s.FieldsIndex = append(s.FieldsIndex, int32(len(ser.Fields)))
+			ser.Fields = append(ser.Fields, &msg.ProtoFlattenedSerializerFieldT{
+				VarTypeSym:             sym(f.Type),
+				VarNameSym:             sym(f.Name),
+				FieldSerializerNameSym: sym(f.serializerName()),
+			})
+		}
+
+		ser.Serializers = append(ser.Serializers, s)
+	}
+
+	for _, c := range e.classes {
+		addSerializer(c.Name, c.Fields)
+	}
+
+	b, err := proto.Marshal(ser)
+	if err != nil {
+		e.t.Fatal(err)
+	}
+
+	return &msg.CDemoSendTables{Data: append(binary.AppendUvarint(nil, uint64(len(b))), b...)}
+}
+
+// PacketEntities returns a svc_PacketEntities message with the given updates, which must be ordered by index.
+// Full (non-delta) messages replace all existing entities, like the ones of DEM_FullPacket frames.
+func (e *Entities) PacketEntities(delta bool, updates ...EntityUpdate) NetMsg {
+	return NetMsg{Type: int32(msg.SVC_Messages_svc_PacketEntities), Msg: e.PacketEntitiesMsg(delta, updates...)}
+}
+
+// PacketEntitiesMsg is like PacketEntities but returns the message itself.
+func (e *Entities) PacketEntitiesMsg(delta bool, updates ...EntityUpdate) *msg.CSVCMsg_PacketEntities {
+	e.t.Helper()
+
+	var w BitWriter
+
+	classIDBits := int(math.Log(float64(e.maxClasses))/math.Log(2)) + 1
+	index := int32(-1)
+
+	for _, u := range updates {
+		if u.Index <= index {
+			e.t.Fatalf("entity updates must be ordered by index, got %d after %d", u.Index, index)
+		}
+
+		w.WriteUBitInt(uint(u.Index - index - 1))
+		index = u.Index
+
+		switch u.Op {
+		case EntityOpCreate:
+			class := e.classByID[u.Class]
+			if class == nil {
+				e.t.Fatalf("unknown class %d", u.Class)
+			}
+
+			e.classOf[u.Index] = class
+
+			w.WriteBits(2, 2)
+			w.WriteBits(uint64(u.Class), classIDBits)
+			w.WriteBits(uint64(u.Serial), 17)
+			w.WriteBits(0, 8) // unused varint
+
+			e.writeFields(&w, class, u.Props)
+
+		case EntityOpUpdate:
+			class := e.classOf[u.Index]
+			if class == nil {
+				e.t.Fatalf("unknown entity %d", u.Index)
+			}
+
+			w.WriteBits(0, 2)
+			e.writeFields(&w, class, u.Props)
+
+		case EntityOpLeave:
+			w.WriteBits(1, 2)
+
+		case EntityOpDelete:
+			w.WriteBits(3, 2)
+			delete(e.classOf, u.Index)
+		}
+	}
+
+	return &msg.CSVCMsg_PacketEntities{
+		MaxEntries:     proto.Int32(2048),
+		UpdatedEntries: proto.Int32(int32(len(updates))),
+		LegacyIsDelta:  proto.Bool(delta),
+		EntityData:     w.Bytes(),
+	}
+}
+
+type fieldValue struct {
+	path []int
+	typ  string
+	val  any
+}
+
+// lookupField returns the field path and type of a property.
+func lookupField(fields []Field, name string) ([]int, string, bool) {
+	for i, f := range fields {
+		if f.Name == name {
+			if len(f.Fields) > 0 {
+				return []int{i}, "bool", true
+			}
+
+			return []int{i}, f.Type, true
+		}
+
+		sub, ok := strings.CutPrefix(name, f.Name+".")
+		if ok && len(f.Fields) > 0 {
+			path, typ, found := lookupField(f.Fields, sub)
+			if found {
+				return append([]int{i}, path...), typ, true
+			}
+		}
+	}
+
+	return nil, "", false
+}
+
+func (e *Entities) writeFields(w *BitWriter, class *Class, props map[string]any) {
+	e.t.Helper()
+
+	var values []fieldValue
+
+	for name, v := range props {
+		path, typ, ok := lookupField(class.Fields, name)
+		if !ok {
+			e.t.Fatalf("class %s has no property %s", class.Name, name)
+		}
+
+		values = append(values, fieldValue{path: path, typ: typ, val: v})
+
+		// pointer tables need to be enabled before their fields are set
+		if len(path) > 1 && props[class.Fields[path[0]].Name] == nil {
+			values = append(values, fieldValue{path: path[:1], typ: "bool", val: true})
+		}
+	}
+
+	slices.SortFunc(values, func(a, b fieldValue) int {
+		return slices.Compare(a.path, b.path)
+	})
+
+	values = slices.CompactFunc(values, func(a, b fieldValue) bool {
+		return slices.Equal(a.path, b.path)
+	})
+
+	prev := []int{-1}
+
+	for _, v := range values {
+		writeFieldPathOps(w, prev, v.path)
+		prev = v.path
+	}
+
+	writeOp(w, opFieldPathEncodeFinish)
+
+	for _, v := range values {
+		writeValue(w, v.typ, v.val)
+	}
+}
+
+// Huffman codes of the field path operations used by the encoder, the first character is the first bit.
+const (
+	opPlusOne               = "0"
+	opPlusTwo               = "1110"
+	opPlusThree             = "110010"
+	opPlusFour              = "11011111"
+	opPlusN                 = "11010"
+	opPushN                 = "1101100011000100"
+	opPopNPlusN             = "1101100011000001"
+	opFieldPathEncodeFinish = "10"
+)
+
+func writeOp(w *BitWriter, code string) {
+	for _, c := range code {
+		w.WriteBit(c == '1')
+	}
+}
+
+// writeFieldPathOps writes the operations that turn the field path prev into next.
+// next must be greater than prev.
+func writeFieldPathOps(w *BitWriter, prev, next []int) {
+	i := 0
+	for i < len(prev) && i < len(next) && prev[i] == next[i] {
+		i++
+	}
+
+	if i == len(prev) {
+		writePushN(w, next[i:])
+
+		return
+	}
+
+	delta := next[i] - prev[i]
+
+	if pops := len(prev) - 1 - i; pops > 0 {
+		writeOp(w, opPopNPlusN)
+		writeUBitVarFP(w, uint(pops))
+		writeVarInt32(w, int32(delta))
+	} else {
+		switch delta {
+		case 1:
+			writeOp(w, opPlusOne)
+		case 2:
+			writeOp(w, opPlusTwo)
+		case 3:
+			writeOp(w, opPlusThree)
+		case 4:
+			writeOp(w, opPlusFour)
+		default:
+			writeOp(w, opPlusN)
+			writeUBitVarFP(w, uint(delta-5))
+		}
+	}
+
+	if len(next) > i+1 {
+		writePushN(w, next[i+1:])
+	}
+}
+
+func writePushN(w *BitWriter, path []int) {
+	writeOp(w, opPushN)
+	w.WriteUBitInt(uint(len(path)))
+	w.WriteUBitInt(0)
+
+	for _, v := range path {
+		writeUBitVarFP(w, uint(v))
+	}
+}
+
+func writeUBitVarFP(w *BitWriter, v uint) {
+	switch {
+	case v < 1<<2:
+		w.WriteBits(1, 1)
+		w.WriteBits(uint64(v), 2)
+	case v < 1<<4:
+		w.WriteBits(2, 2)
+		w.WriteBits(uint64(v), 4)
+	case v < 1<<10:
+		w.WriteBits(4, 3)
+		w.WriteBits(uint64(v), 10)
+	case v < 1<<17:
+		w.WriteBits(8, 4)
+		w.WriteBits(uint64(v), 17)
+	default:
+		w.WriteBits(0, 4)
+		w.WriteBits(uint64(v), 31)
+	}
+}
+
+func writeVarUint(w *BitWriter, v uint64) {
+	for _, b := range binary.AppendUvarint(nil, v) {
+		w.WriteBits(uint64(b), 8)
+	}
+}
+
+func writeVarInt32(w *BitWriter, v int32) {
+	writeVarUint(w, uint64(uint32(v<<1)^uint32(v>>31)))
+}
+
+func writeFloat32(w *BitWriter, v float32) {
+	w.WriteBits(uint64(math.Float32bits(v)), 32)
+}
+
+func writeValue(w *BitWriter, typ string, v any) {
+	if i := strings.IndexAny(typ, "<[*"); i != -1 {
+		typ = strings.TrimSpace(typ[:i]) // e.g. char[129]
+	}
+
+	switch typ {
+	case "bool":
+		w.WriteBit(v.(bool))
+
+	case "int8", "int16", "int32", "HSequence", "CEntityIndex":
+		writeVarInt32(w, int32(toInt64(v)))
+
+	case "uint64", "CStrongHandle":
+		writeVarUint(w, uint64(toInt64(v)))
+
+	case "float32", "GameTime_t":
+		writeFloat32(w, toFloat32(v))
+
+	case "Vector":
+		for _, c := range v.([]float32) {
+			writeFloat32(w, c)
+		}
+
+	case "char", "CUtlString", "CUtlSymbolLarge":
+		for _, b := range []byte(v.(string)) {
+			w.WriteBits(uint64(b), 8)
+		}
+
+		w.WriteBits(0, 8)
+
+	default: // uint8, uint16, uint32, handles & enums
+		writeVarUint(w, uint64(uint32(toInt64(v))))
+	}
+}
+
+func toInt64(v any) int64 {
+	switch x := v.(type) {
+	case int:
+		return int64(x)
+	case int8:
+		return int64(x)
+	case int16:
+		return int64(x)
+	case int32:
+		return int64(x)
+	case int64:
+		return x
+	case uint:
+		return int64(x)
+	case uint8:
+		return int64(x)
+	case uint16:
+		return int64(x)
+	case uint32:
+		return int64(x)
+	case uint64:
+		return int64(x)
+	case bool:
+		if x {
+			return 1
+		}
+
+		return 0
+	}
+
+	panic("unsupported integer value")
+}
+
+func toFloat32(v any) float32 {
+	switch x := v.(type) {
+	case float32:
+		return x
+	case float64:
+		return float32(x)
+	}
+
+	return float32(toInt64(v))
+}
@@ -0,0 +1,409 @@
+package demoinfocs
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/gob"
+	"fmt"
+	"io"
+
+	"github.com/pkg/errors"
+
+	bit "github.com/markus-wa/demoinfocs-golang/v5/internal/bitread"
+	"github.com/markus-wa/demoinfocs-golang/v5/pkg/demoinfocs/cstv"
+	"github.com/markus-wa/demoinfocs-golang/v5/pkg/demoinfocs/msg"
+)
+
+// Checkpoint errors
+var (
+	// ErrCheckpointsDisabled signals that Parser.Checkpoint() was called without ParserConfig.EnableCheckpoints.
+	ErrCheckpointsDisabled = errors.New("checkpoints require ParserConfig.EnableCheckpoints (ErrCheckpointsDisabled)")
+
+	// ErrInvalidCheckpoint signals that the data passed to ResumeParser() isn't a checkpoint or was written by an incompatible version.
+	ErrInvalidCheckpoint = errors.New("invalid or incompatible checkpoint (ErrInvalidCheckpoint)")
+)
+
+// checkpointVersion must be incremented whenever the checkpoint format changes.
+const checkpointVersion = 2
+
+// journalFrame is a frame as it was read from the demo (payload still compressed if it was).
+type journalFrame struct {
+	Cmd     msg.EDemoCommands
+	Tick    uint32
+	Payload []byte
+}
+
+// frameJournal records the frames needed to restore the parser state, see ParserConfig.EnableCheckpoints.
+// This is the same data SeekToTick() restores state from, plus the frames that set up the demo.
+type frameJournal struct {
+	signon        []journalFrame // Frames before the first DEM_FullPacket (file header, send tables, class info, string tables etc.)
+	sinceKeyframe []journalFrame // The last DEM_FullPacket frame and all frames after it, nil until the first DEM_FullPacket
+}
+
+func (j *frameJournal) record(h frameHeader, payload []byte) {
+	msgType := h.cmd & ^msg.EDemoCommands_DEM_IsCompressed
+
+	f := journalFrame{
+		Cmd:     h.cmd,
+		Tick:    h.tick,
+		Payload: payload,
+	}
+
+	switch {
+	case msgType == msg.EDemoCommands_DEM_Stop:
+		// Nothing to restore, replaying it would end the replay early
+
+	case msgType == msg.EDemoCommands_DEM_FullPacket:
+		// Entities & string tables are restored from the keyframe, earlier frames aren't needed anymore
+		j.sinceKeyframe = []journalFrame{f}
+
+	case j.sinceKeyframe == nil:
+		j.signon = append(j.signon, f)
+
+	default:
+		j.sinceKeyframe = append(j.sinceKeyframe, f)
+	}
+}
+
+// checkpoint is the serialised parser state written by Parser.Checkpoint().
+type checkpoint struct {
+	Version       int
+	Format        DemoFormat
+	Offset        int64         // Byte offset of the next frame, relative to the start of the demo
+	CSTVPosition  cstv.Position // Position of the next frame in the broadcast, only set for DemoFormatCSTVBroadcast
+	Frame         int
+	IngameTick    int
+	Header        header
+	Signon        []journalFrame
+	SinceKeyframe []journalFrame
+
+	// State that is built from net-messages before the last keyframe and can't be restored by replaying frames
+	ConVars         map[string]string
+	RoundStartTicks map[int]int
+}
+
+/*
+Checkpoint writes the current state of the parser to w, so parsing can be continued later via ResumeParser(),
+e.g. after a restart of the process.
+
+Requires ParserConfig.EnableCheckpoints.
+Must not be called from event or net-message handlers or while ParseToEnd() is running,
+call it between ParseNextFrame() calls instead.
+
+Checkpoints contain the frames that set up the demo (send tables, server classes, string tables etc.),
+the last DEM_FullPacket frame (keyframe) and all frames after it, the position in the demo and the ConVars.
+On resume, string tables, server classes and entity state are restored by replaying these frames
+(the same way as SeekToTick() does), which also restores the game state that is based on them (players, teams, rules, grenades etc.).
+
+For CSTV broadcasts the position is the fragment of the next frame (see cstv.Reader.Position()),
+which requires the Parser to read from a *cstv.Reader, e.g. via NewCSTVBroadcastParser().
+
+See also: ResumeParser() & ResumeCSTVBroadcastParserWithConfig()
+*/
+func (p *parser) Checkpoint(w io.Writer) error {
+	if !p.config.EnableCheckpoints {
+		return ErrCheckpointsDisabled
+	}
+
+	if p.header == nil {
+		_, err := p.parseHeader()
+		if err != nil {
+			return err
+		}
+	}
+
+	p.msgDispatcher.SyncAllQueues()
+
+	err := p.error()
+	if err != nil {
+		return errors.Wrap(err, "can't create checkpoint of a failed parser")
+	}
+
+	offset := p.bitReaderOffset
+
+	// The BitReader of resumed growing files is only created once parsing continues
+	if p.bitReader != nil {
+		offset += int64(p.bitReader.ActualPosition() >> 3)
+	}
+
+	var cstvPos cstv.Position
+
+	if p.config.Format == DemoFormatCSTVBroadcast {
+		if p.cstvReader == nil {
+			return errors.New("checkpoints of CSTV broadcasts require a *cstv.Reader as input")
+		}
+
+		cstvPos = p.cstvReader.Position(offset)
+	}
+
+	cp := checkpoint{
+		Version:         checkpointVersion,
+		Format:          p.config.Format,
+		Offset:          offset,
+		CSTVPosition:    cstvPos,
+		Frame:           p.framesRead,
+		IngameTick:      p.gameState.ingameTick,
+		Header:          *p.header,
+		Signon:          p.journal.signon,
+		SinceKeyframe:   p.journal.sinceKeyframe,
+		ConVars:         p.gameState.rules.conVars,
+		RoundStartTicks: p.roundStartTicks,
+	}
+
+	err = gob.NewEncoder(w).Encode(cp)
+	if err != nil {
+		return errors.Wrap(err, "failed to write checkpoint")
+	}
+
+	return nil
+}
+
+// ResumeParser restores a Parser from a checkpoint written by Parser.Checkpoint() and continues parsing demostream.
+// Uses DefaultParserConfig with EnableCheckpoints, so the resumed Parser can create checkpoints as well.
+//
+// See also: ResumeParserWithConfig()
+func ResumeParser(checkpoint, demostream io.Reader) (Parser, error) {
+	config := DefaultParserConfig
+	config.EnableCheckpoints = true
+
+	return ResumeParserWithConfig(checkpoint, demostream, config)
+}
+
+/*
+ResumeParserWithConfig restores a Parser from a checkpoint written by Parser.Checkpoint() and continues parsing demostream.
+
+The demostream must provide the same demo as the checkpointed Parser, from the beginning.
+If it's an io.ReadSeeker it's seeked to the position of the checkpoint (and SeekToTick() etc. can be used),
+otherwise it's read up to that position.
+config.Format is taken from the checkpoint.
+
+No game events are dispatched for the frames that are replayed from the checkpoint, handlers can be registered
+on the returned Parser and will receive the events of the first frame after the checkpoint.
+
+Returns ErrInvalidCheckpoint if the checkpoint can't be read or is of a CSTV broadcast,
+use ResumeCSTVBroadcastParserWithConfig() for those.
+*/
+func ResumeParserWithConfig(checkpoint, demostream io.Reader, config ParserConfig) (Parser, error) {
+	cp, err := readCheckpoint(checkpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	if cp.Format == DemoFormatCSTVBroadcast {
+		return nil, fmt.Errorf("%w: checkpoints of CSTV broadcasts must be resumed with ResumeCSTVBroadcastParserWithConfig()", ErrInvalidCheckpoint)
+	}
+
+	return resumeParser(cp, demostream, cp.Offset, config)
+}
+
+// ResumeCSTVBroadcastParserWithConfig restores a Parser from a checkpoint of a CSTV broadcast parser
+// and continues the broadcast at the fragment of the checkpoint.
+//
+// See also: ResumeCSTVBroadcastParserWithConfigContext()
+func ResumeCSTVBroadcastParserWithConfig(checkpoint io.Reader, baseUrl string, config ParserConfig) (Parser, error) {
+	return ResumeCSTVBroadcastParserWithConfigContext(context.Background(), checkpoint, baseUrl, config)
+}
+
+/*
+ResumeCSTVBroadcastParserWithConfigContext restores a Parser from a checkpoint of a CSTV broadcast parser
+and continues the broadcast at the fragment of the checkpoint.
+All HTTP requests to the CSTV server are aborted once the context is done.
+
+The baseUrl must be the one of the checkpointed broadcast, which must still provide the fragment of the checkpoint.
+Only the fragments from there on are requested, config.CSTVOptions.Resume is set from the checkpoint.
+
+Like ResumeParserWithConfig(), no game events are dispatched for the frames that are replayed from the checkpoint.
+
+Returns ErrInvalidCheckpoint if the checkpoint can't be read or isn't of a CSTV broadcast.
+*/
+func ResumeCSTVBroadcastParserWithConfigContext(ctx context.Context, checkpoint io.Reader, baseUrl string, config ParserConfig) (Parser, error) {
+	cp, err := readCheckpoint(checkpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	if cp.Format != DemoFormatCSTVBroadcast {
+		return nil, fmt.Errorf("%w: not a checkpoint of a CSTV broadcast, use ResumeParserWithConfig()", ErrInvalidCheckpoint)
+	}
+
+	opts := config.CSTVOptions
+	if opts.Backoff == nil {
+		opts.Backoff = cstv.DefaultBackoff(config.CSTVTimeout)
+	}
+
+	opts.Resume = &cp.CSTVPosition
+
+	r, err := cstv.NewReaderWithOptions(ctx, baseUrl, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create CSTV reader: %w", err)
+	}
+
+	// The Reader starts at the position of the checkpoint
+	return resumeParser(cp, r, 0, config)
+}
+
+// resumeParser replays the checkpoint and continues parsing demostream at the given offset.
+func resumeParser(cp *checkpoint, demostream io.Reader, offset int64, config ParserConfig) (Parser, error) {
+	config.Format = cp.Format
+
+	p := newParserWithoutInput(config)
+
+	err := p.replayCheckpoint(cp)
+	if err != nil {
+		_ = p.Close()
+
+		return nil, errors.Wrap(err, "failed to replay checkpoint")
+	}
+
+	err = p.continueAt(demostream, offset)
+	if err != nil {
+		_ = p.Close()
+
+		return nil, err
+	}
+
+	return p, nil
+}
+
+func readCheckpoint(r io.Reader) (*checkpoint, error) {
+	var cp checkpoint
+
+	err := gob.NewDecoder(r).Decode(&cp)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidCheckpoint, err)
+	}
+
+	if cp.Version != checkpointVersion {
+		return nil, fmt.Errorf("%w: unsupported version %d, expected %d", ErrInvalidCheckpoint, cp.Version, checkpointVersion)
+	}
+
+	return &cp, nil
+}
+
+// replayCheckpoint parses the frames of the checkpoint without dispatching events and restores the remaining state.
+func (p *parser) replayCheckpoint(cp *checkpoint) error {
+	journalReader := bit.NewSmallBitReader(bytes.NewReader(cp.replayStream()))
+	p.bitReader = journalReader
+
+	_, err := p.parseHeader()
+	if err != nil {
+		return err
+	}
+
+	p.ensureMsgQueue()
+
+	frames := len(cp.Signon) + len(cp.SinceKeyframe)
+
+	err = p.replayWhile(func() bool {
+		return p.framesRead < frames
+	})
+	if err != nil {
+		return err
+	}
+
+	*p.header = cp.Header
+	p.currentFrame = cp.Frame
+	p.framesRead = cp.Frame
+	p.gameState.ingameTick = cp.IngameTick
+
+	if cp.ConVars != nil {
+		p.gameState.rules.conVars = cp.ConVars
+	}
+
+	if cp.RoundStartTicks != nil {
+		p.roundStartTicks = cp.RoundStartTicks
+	}
+
+	p.bitReader = nil
+
+	return errors.Wrap(journalReader.Pool(), "failed to release checkpoint reader")
+}
+
+// continueAt switches the BitReader to the given demo stream, at the given byte offset from the start of the demo.
+func (p *parser) continueAt(demostream io.Reader, offset int64) error {
+	p.bitReaderOffset = offset
+
+	if p.config.Format == DemoFormatFile {
+		var err error
+
+		demostream, err = decompress(demostream)
+		if err != nil {
+			return err
+		}
+	}
+
+	if seeker, ok := demostream.(io.ReadSeeker); ok && p.config.Format != DemoFormatCSTVBroadcast {
+		// Not all io.Seekers can actually seek (e.g. os.Stdin)
+		start, err := seeker.Seek(0, io.SeekCurrent)
+		if err == nil {
+			_, err = seeker.Seek(start+offset, io.SeekStart)
+			if err != nil {
+				return errors.Wrap(err, "failed to seek to checkpoint")
+			}
+
+			// Growing files don't support SeekToTick()
+			if p.config.Format == DemoFormatFile {
+				p.demoSeeker = seeker
+				p.demoStartOffset = start
+				demostream = readOnly{seeker}
+			}
+
+			offset = 0
+		}
+	}
+
+	if offset > 0 {
+		_, err := io.CopyN(io.Discard, demostream, offset)
+		if err != nil {
+			return fmt.Errorf("failed to skip to checkpoint: %w", ErrUnexpectedEndOfDemo)
+		}
+	}
+
+	switch p.config.Format {
+	case DemoFormatFile:
+		p.bitReader = bit.NewLargeBitReader(demostream)
+
+	case DemoFormatGrowingFile:
+		p.growingFile = newGrowingFileReader(demostream, p.config.GrowingFileTimeout, true)
+
+	default:
+		p.cstvReader, _ = demostream.(*cstv.Reader)
+		p.bitReader = bit.NewSmallBitReader(demostream)
+	}
+
+	return nil
+}
+
+// replayStream encodes the frames of the checkpoint in the demo format they were read in.
+func (cp *checkpoint) replayStream() []byte {
+	var b []byte
+
+	if cp.Format != DemoFormatCSTVBroadcast {
+		b = append(b, "PBDEMS2\x00"...)
+		b = append(b, make([]byte, 8)...) // file info & spawn groups offsets
+	}
+
+	for _, frames := range [][]journalFrame{cp.Signon, cp.SinceKeyframe} {
+		for _, f := range frames {
+			b = appendFrame(b, cp.Format, f)
+		}
+	}
+
+	return b
+}
+
+func appendFrame(b []byte, format DemoFormat, f journalFrame) []byte {
+	b = binary.AppendUvarint(b, uint64(f.Cmd))
+
+	if format == DemoFormatCSTVBroadcast {
+		b = binary.LittleEndian.AppendUint32(b, f.Tick)
+		b = append(b, 0)
+		b = binary.LittleEndian.AppendUint32(b, uint32(len(f.Payload)))
+	} else {
+		b = binary.AppendUvarint(b, uint64(f.Tick))
+		b = binary.AppendUvarint(b, uint64(len(f.Payload)))
+	}
+
+	return append(b, f.Payload...)
+}
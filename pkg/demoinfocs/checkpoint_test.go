@@ -0,0 +1,468 @@
+package demoinfocs
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/markus-wa/demoinfocs-golang/v5/internal/demotest"
+	common "github.com/markus-wa/demoinfocs-golang/v5/pkg/demoinfocs/common"
+	"github.com/markus-wa/demoinfocs-golang/v5/pkg/demoinfocs/events"
+	"github.com/markus-wa/demoinfocs-golang/v5/pkg/demoinfocs/msg"
+)
+
+const checkpointTestTick = 7
+
+func testConVarMsg(name, value string) demotest.NetMsg {
+	return demotest.NetMsg{Type: int32(msg.NET_Messages_net_SetConVar), Msg: &msg.CNETMsg_SetConVar{
+		Convars: &msg.CMsg_CVars{
+			Cvars: []*msg.CMsg_CVars_CVar{{Name: proto.String(name), Value: proto.String(value)}},
+		},
+	}}
+}
+
+func testSayTextMsg(text string) demotest.NetMsg {
+	return demotest.NetMsg{Type: int32(msg.EBaseUserMessages_UM_SayText), Msg: &msg.CUserMessageSayText{Text: proto.String(text)}}
+}
+
+// checkpointTestDemo returns a demo with keyframes at ticks 1, 5 & 12 and state changes before and after checkpointTestTick.
+func checkpointTestDemo(t *testing.T) []byte {
+	t.Helper()
+
+	players := map[int]*msg.CMsgPlayerInfo{
+		0: {Name: proto.String("alice"), Xuid: proto.Uint64(76561198000000001), Userid: proto.Int32(1)},
+		1: {Name: proto.String("bob"), Xuid: proto.Uint64(76561198000000002), Userid: proto.Int32(2)},
+	}
+
+	d := demotest.New(t).Packet(0, testConVarMsg("mp_signon", "1"))
+	d.StringTablesFullPacket(1, demotest.UserInfoTable(t, players))
+	d.TickPacket(2).Packet(3, testConVarMsg("mp_before_keyframe", "2")).TickPacket(4)
+	d.StringTablesFullPacket(5, demotest.UserInfoTable(t, players))
+	d.TickPacket(6).Packet(7, testSayTextMsg("before checkpoint"))
+	d.Packet(8, testConVarMsg("mp_after_checkpoint", "3")).Packet(9, testSayTextMsg("after checkpoint"))
+	d.Packet(10, demotest.NetMsg{Type: 9999, Msg: &msg.CNETMsg_NOP{}}).TickPacket(11)
+
+	players[2] = &msg.CMsgPlayerInfo{Name: proto.String("carol"), Xuid: proto.Uint64(76561198000000003), Userid: proto.Int32(3)}
+	d.StringTablesFullPacket(12, demotest.UserInfoTable(t, players))
+
+	return d.TickPacket(13).Stop(14).Bytes()
+}
+
+func checkpointTestConfig() ParserConfig {
+	return ParserConfig{
+		MsgQueueBufferSize: 0,
+		EnableCheckpoints:  true,
+	}
+}
+
+// recordEvents records a comparable description of all events that are dispatched by the parser.
+func recordEvents(p Parser) *[]string {
+	var descs []string
+
+	p.RegisterEventHandler(func(e any) {
+		desc := fmt.Sprintf("tick %d: %T", p.GameState().IngameTick(), e)
+
+		switch e := e.(type) {
+		case events.PlayerConnect:
+			desc += " " + e.Player.Name
+		case events.ConVarsUpdated, events.SayText, events.UnknownNetMessage, events.PlayerInfo:
+			desc += fmt.Sprintf(" %+v", e)
+		}
+
+		descs = append(descs, desc)
+	})
+
+	return &descs
+}
+
+// playerNames returns the names of the players from the userinfo string table.
+// checkpointTestDemo contains no entities, so GameState().Participants() is always empty.
+func playerNames(p Parser) []string {
+	names := make([]string, 0)
+
+	for _, info := range p.(*parser).rawPlayers {
+		names = append(names, info.Name)
+	}
+
+	return names
+}
+
+// parseToCheckpoint parses the demo until checkpointTestTick and writes a checkpoint.
+func parseToCheckpoint(t *testing.T, demo []byte) (Parser, *bytes.Buffer) {
+	t.Helper()
+
+	p := NewParserWithConfig(bytes.NewReader(demo), checkpointTestConfig())
+	t.Cleanup(func() {
+		assert.NoError(t, p.Close())
+	})
+
+	for p.GameState().IngameTick() < checkpointTestTick {
+		_, err := p.ParseNextFrame()
+		assert.NoError(t, err)
+	}
+
+	var cp bytes.Buffer
+
+	assert.NoError(t, p.Checkpoint(&cp))
+
+	return p, &cp
+}
+
+func TestResumeParser(t *testing.T) {
+	demo := checkpointTestDemo(t)
+
+	tests := map[string]func() io.Reader{
+		"seekable": func() io.Reader { return bytes.NewReader(demo) },
+		"stream":   func() io.Reader { return struct{ io.Reader }{bytes.NewReader(demo)} },
+	}
+
+	for name, demostream := range tests {
+		t.Run(name, func(t *testing.T) {
+			uninterrupted, cp := parseToCheckpoint(t, demo)
+
+			resumed, err := ResumeParserWithConfig(cp, demostream(), checkpointTestConfig())
+			assert.NoError(t, err)
+
+			defer resumed.Close()
+
+			assert.Equal(t, uninterrupted.GameState().IngameTick(), resumed.GameState().IngameTick())
+			assert.Equal(t, uninterrupted.CurrentFrame(), resumed.CurrentFrame())
+			assert.Equal(t, uninterrupted.GameState().Rules().ConVars(), resumed.GameState().Rules().ConVars())
+			assert.ElementsMatch(t, playerNames(uninterrupted), playerNames(resumed))
+
+			expected, actual := recordEvents(uninterrupted), recordEvents(resumed)
+
+			assert.NoError(t, uninterrupted.ParseToEnd())
+			assert.NoError(t, resumed.ParseToEnd())
+
+			assert.NotEmpty(t, *expected)
+			assert.Equal(t, *expected, *actual)
+			assert.Equal(t, uninterrupted.GameState().IngameTick(), resumed.GameState().IngameTick())
+			assert.Equal(t, uninterrupted.CurrentFrame(), resumed.CurrentFrame())
+			assert.Equal(t, uninterrupted.GameState().Rules().ConVars(), resumed.GameState().Rules().ConVars())
+			assert.ElementsMatch(t, playerNames(uninterrupted), playerNames(resumed))
+		})
+	}
+}
+
+func TestResumeParser_Chained(t *testing.T) {
+	demo := checkpointTestDemo(t)
+	uninterrupted, cp := parseToCheckpoint(t, demo)
+
+	resumed, err := ResumeParser(cp, bytes.NewReader(demo))
+	assert.NoError(t, err)
+
+	defer resumed.Close()
+
+	for i := 0; i < 6; i++ {
+		_, err = resumed.ParseNextFrame()
+		assert.NoError(t, err)
+	}
+
+	// The second checkpoint is after the keyframe at tick 12
+	var cp2 bytes.Buffer
+
+	assert.NoError(t, resumed.Checkpoint(&cp2))
+
+	resumedTwice, err := ResumeParser(&cp2, bytes.NewReader(demo))
+	assert.NoError(t, err)
+
+	defer resumedTwice.Close()
+
+	assert.Equal(t, 13, resumedTwice.GameState().IngameTick())
+	assert.ElementsMatch(t, []string{"alice", "bob", "carol"}, playerNames(resumedTwice))
+
+	for uninterrupted.GameState().IngameTick() < 13 {
+		_, err = uninterrupted.ParseNextFrame()
+		assert.NoError(t, err)
+	}
+
+	expected, actual := recordEvents(uninterrupted), recordEvents(resumedTwice)
+
+	assert.NoError(t, uninterrupted.ParseToEnd())
+	assert.NoError(t, resumedTwice.ParseToEnd())
+	assert.Equal(t, *expected, *actual)
+	assert.Equal(t, uninterrupted.GameState().Rules().ConVars(), resumedTwice.GameState().Rules().ConVars())
+}
+
+func TestParser_Checkpoint_Journal(t *testing.T) {
+	p, _ := parseToCheckpoint(t, checkpointTestDemo(t))
+	journal := p.(*parser).journal
+
+	// File header & the packet of tick 0 before the first keyframe, then the keyframe at tick 5 and the frames after it
+	assert.Len(t, journal.signon, 2)
+	assert.Len(t, journal.sinceKeyframe, 3)
+	assert.Equal(t, msg.EDemoCommands_DEM_FullPacket, journal.sinceKeyframe[0].Cmd&^msg.EDemoCommands_DEM_IsCompressed)
+	assert.Equal(t, uint32(5), journal.sinceKeyframe[0].Tick)
+}
+
+func TestParser_Checkpoint_Disabled(t *testing.T) {
+	p := NewParser(bytes.NewReader(checkpointTestDemo(t)))
+	defer p.Close()
+
+	assert.ErrorIs(t, p.Checkpoint(io.Discard), ErrCheckpointsDisabled)
+}
+
+func TestResumeParser_InvalidCheckpoint(t *testing.T) {
+	_, err := ResumeParser(bytes.NewReader([]byte("not a checkpoint")), bytes.NewReader(checkpointTestDemo(t)))
+
+	assert.ErrorIs(t, err, ErrInvalidCheckpoint)
+}
+
+// matchState returns a comparable description of the entities, participants and teams of the game state.
+func matchState(p Parser) map[string]any {
+	gs := p.GameState()
+
+	entities := make(map[int]map[string]any)
+
+	for id, e := range gs.Entities() {
+		props := make(map[string]any)
+
+		for _, prop := range e.Properties() {
+			if v := prop.Value().Any; v != nil {
+				props[prop.Name()] = v
+			}
+		}
+
+		entities[id] = props
+	}
+
+	var participants, teams []string
+
+	for _, pl := range gs.Participants().All() {
+		participants = append(participants, fmt.Sprintf("%s (%d, user %d, entity %d): team %d, connected %t",
+			pl.Name, pl.SteamID64, pl.UserID, pl.EntityID, pl.Team, pl.IsConnected))
+	}
+
+	sort.Strings(participants)
+
+	for _, team := range []*common.TeamState{gs.TeamTerrorists(), gs.TeamCounterTerrorists()} {
+		teams = append(teams, fmt.Sprintf("%d (%d): %q %d", team.Team(), team.ID(), team.ClanName(), team.Score()))
+	}
+
+	return map[string]any{
+		"entities":     entities,
+		"participants": participants,
+		"teams":        teams,
+		"rounds":       gs.TotalRoundsPlayed(),
+	}
+}
+
+// assertResumed asserts that the resumed parser has the same state as the uninterrupted one
+// and dispatches the same events until the end of the demo.
+func assertResumed(t *testing.T, uninterrupted, resumed Parser) {
+	t.Helper()
+
+	assert.Equal(t, uninterrupted.GameState().IngameTick(), resumed.GameState().IngameTick())
+	assert.Equal(t, uninterrupted.CurrentFrame(), resumed.CurrentFrame())
+	assert.Equal(t, matchState(uninterrupted), matchState(resumed))
+
+	expected, actual := recordEvents(uninterrupted), recordEvents(resumed)
+
+	assert.NoError(t, uninterrupted.ParseToEnd())
+	assert.NoError(t, resumed.ParseToEnd())
+
+	assert.NotEmpty(t, *expected)
+	assert.Equal(t, *expected, *actual)
+	assert.Equal(t, uninterrupted.GameState().IngameTick(), resumed.GameState().IngameTick())
+	assert.Equal(t, matchState(uninterrupted), matchState(resumed))
+}
+
+func TestResumeParser_Entities(t *testing.T) {
+	demo := testMatchDemo(t).Bytes()
+
+	// Before the first round ended, after the keyframe at tick 10 and after the last keyframe
+	for _, tick := range []int{5, 15, 25} {
+		t.Run(fmt.Sprintf("tick %d", tick), func(t *testing.T) {
+			uninterrupted := NewParserWithConfig(bytes.NewReader(demo), checkpointTestConfig())
+			defer uninterrupted.Close()
+
+			for uninterrupted.GameState().IngameTick() < tick {
+				_, err := uninterrupted.ParseNextFrame()
+				assert.NoError(t, err)
+			}
+
+			var cp bytes.Buffer
+
+			assert.NoError(t, uninterrupted.Checkpoint(&cp))
+
+			resumed, err := ResumeParserWithConfig(&cp, bytes.NewReader(demo), checkpointTestConfig())
+			assert.NoError(t, err)
+
+			defer resumed.Close()
+
+			state := matchState(resumed)
+			assert.Len(t, state["entities"], 5)
+			assert.Len(t, state["participants"], 2)
+
+			assertResumed(t, uninterrupted, resumed)
+		})
+	}
+}
+
+func TestResumeParser_Entities_State(t *testing.T) {
+	demo := testMatchDemo(t).Bytes()
+
+	p := NewParserWithConfig(bytes.NewReader(demo), checkpointTestConfig())
+	defer p.Close()
+
+	for p.GameState().IngameTick() < 15 {
+		_, err := p.ParseNextFrame()
+		assert.NoError(t, err)
+	}
+
+	var cp bytes.Buffer
+
+	assert.NoError(t, p.Checkpoint(&cp))
+
+	resumed, err := ResumeParser(&cp, bytes.NewReader(demo))
+	assert.NoError(t, err)
+
+	defer resumed.Close()
+
+	gs := resumed.GameState()
+	assert.Equal(t, 1, gs.TotalRoundsPlayed())
+	assert.Equal(t, 1, gs.TeamCounterTerrorists().Score())
+	assert.Equal(t, "Team Vitality", gs.TeamTerrorists().ClanName())
+	assert.Equal(t, "FaZe", gs.TeamCounterTerrorists().ClanName())
+	assert.Equal(t, map[string]common.Team{
+		"s1mple": common.TeamCounterTerrorists,
+		"ZywOo":  common.TeamTerrorists,
+	}, testPlayerTeams(gs))
+}
+
+// newCSTVTestServer serves the match fixture as a broadcast with a fragment for each keyframe.
+// The frames before the first keyframe are the start data, each keyframe is the full data of a fragment
+// and the delta of the following fragment contains the frames up to the next keyframe.
+func newCSTVTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	broadcast := testMatch(t, demotest.NewBroadcast(t)).Bytes()
+	files := map[string][]byte{"/sync": []byte(`{"tick": 0, "fragment": 1, "signup_fragment": 0}`)}
+	path := "/0/start"
+	fragment := 0
+
+	for len(broadcast) > 0 {
+		cmd, n := binary.Uvarint(broadcast)
+		n += 5 // tick & an unused byte
+
+		// DEM_Stop has no payload size in broadcasts
+		if msg.EDemoCommands(cmd) != msg.EDemoCommands_DEM_Stop {
+			n += 4 + int(binary.LittleEndian.Uint32(broadcast[n:]))
+		}
+
+		if msg.EDemoCommands(cmd) == msg.EDemoCommands_DEM_FullPacket {
+			fragment++
+			files[fmt.Sprintf("/%d/full", fragment)] = broadcast[:n]
+			path = fmt.Sprintf("/%d/delta", fragment+1)
+		} else {
+			files[path] = append(files[path], broadcast[:n]...)
+		}
+
+		broadcast = broadcast[n:]
+	}
+
+	// Padding after DEM_Stop, so reading ahead (up to 512 bytes) doesn't wait for the delta after it
+	files[path] = append(files[path], make([]byte, 512)...)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, ok := files[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+
+			return
+		}
+
+		_, err := w.Write(b)
+		assert.NoError(t, err)
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+func TestResumeCSTVBroadcastParser(t *testing.T) {
+	srv := newCSTVTestServer(t)
+
+	// In the start data, at the end of the start & full data and in the deltas
+	for _, frames := range []int{1, 7, 15, 30} {
+		t.Run(fmt.Sprintf("frame %d", frames), func(t *testing.T) {
+			uninterrupted, err := NewCSTVBroadcastParserWithConfig(srv.URL, checkpointTestConfig())
+			assert.NoError(t, err)
+
+			defer uninterrupted.Close()
+
+			for uninterrupted.(*parser).framesRead < frames {
+				_, err = uninterrupted.ParseNextFrame()
+				assert.NoError(t, err)
+			}
+
+			var cp bytes.Buffer
+
+			assert.NoError(t, uninterrupted.Checkpoint(&cp))
+
+			_, err = ResumeParser(bytes.NewReader(cp.Bytes()), bytes.NewReader(nil))
+			assert.ErrorIs(t, err, ErrInvalidCheckpoint)
+
+			resumed, err := ResumeCSTVBroadcastParserWithConfig(&cp, srv.URL, checkpointTestConfig())
+			assert.NoError(t, err)
+
+			defer resumed.Close()
+
+			assertResumed(t, uninterrupted, resumed)
+		})
+	}
+}
+
+func TestResumeCSTVBroadcastParser_Chained(t *testing.T) {
+	srv := newCSTVTestServer(t)
+
+	p, err := NewCSTVBroadcastParserWithConfig(srv.URL, checkpointTestConfig())
+	assert.NoError(t, err)
+
+	defer p.Close()
+
+	// Resume twice, each time from a checkpoint of the previously resumed parser
+	for _, tick := range []int{8, 18} {
+		for p.GameState().IngameTick() < tick {
+			_, err = p.ParseNextFrame()
+			assert.NoError(t, err)
+		}
+
+		var cp bytes.Buffer
+
+		assert.NoError(t, p.Checkpoint(&cp))
+
+		p, err = ResumeCSTVBroadcastParserWithConfig(&cp, srv.URL, checkpointTestConfig())
+		assert.NoError(t, err)
+
+		defer p.Close()
+	}
+
+	uninterrupted, err := NewCSTVBroadcastParserWithConfig(srv.URL, checkpointTestConfig())
+	assert.NoError(t, err)
+
+	defer uninterrupted.Close()
+
+	for uninterrupted.CurrentFrame() < p.CurrentFrame() {
+		_, err = uninterrupted.ParseNextFrame()
+		assert.NoError(t, err)
+	}
+
+	assertResumed(t, uninterrupted, p)
+}
+
+func TestResumeCSTVBroadcastParser_FileCheckpoint(t *testing.T) {
+	_, cp := parseToCheckpoint(t, checkpointTestDemo(t))
+
+	_, err := ResumeCSTVBroadcastParserWithConfig(cp, "http://localhost:0", checkpointTestConfig())
+	assert.ErrorIs(t, err, ErrInvalidCheckpoint)
+}
@@ -0,0 +1,195 @@
+package demoinfocs
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/pkg/errors"
+)
+
+// compressionFormat describes a compression format that demos are transparently decompressed from.
+type compressionFormat struct {
+	name      string
+	magic     []byte
+	newReader func(r io.Reader) (io.Reader, error)
+}
+
+// maxMagicSize is the length of the longest magic number in compressionFormats.
+const maxMagicSize = 4
+
+var compressionFormats = []compressionFormat{
+	{
+		name:  "gzip",
+		magic: []byte{0x1f, 0x8b},
+		newReader: func(r io.Reader) (io.Reader, error) {
+			return gzip.NewReader(r)
+		},
+	},
+	{
+		name:  "bzip2",
+		magic: []byte("BZh"),
+		newReader: func(r io.Reader) (io.Reader, error) {
+			return bzip2.NewReader(r), nil
+		},
+	},
+	{
+		name:  "zstd",
+		magic: []byte{0x28, 0xb5, 0x2f, 0xfd},
+		newReader: func(r io.Reader) (io.Reader, error) {
+			dec, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+			if err != nil {
+				return nil, err
+			}
+
+			return zstdReader{dec}, nil
+		},
+	},
+}
+
+// zstdReader adds io.Closer to zstd.Decoder, whose Close() doesn't return an error.
+type zstdReader struct {
+	*zstd.Decoder
+}
+
+func (r zstdReader) Close() error {
+	r.Decoder.Close()
+
+	return nil
+}
+
+// decompressedReader reads from a decompressor and closes both the decompressor and the compressed stream.
+type decompressedReader struct {
+	io.Reader
+	compressed io.Reader
+}
+
+// Read delays io.EOF to the next call if data was read, as BitReader expects.
+func (r decompressedReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	if n > 0 && errors.Is(err, io.EOF) {
+		return n, nil
+	}
+
+	return n, err
+}
+
+func (r decompressedReader) Close() error {
+	if c, ok := r.Reader.(io.Closer); ok {
+		err := c.Close()
+		if err != nil {
+			return errors.Wrap(err, "failed to close decompressor")
+		}
+	}
+
+	if c, ok := r.compressed.(io.Closer); ok {
+		return c.Close()
+	}
+
+	return nil
+}
+
+// peekedReader is a bufio.Reader that keeps the io.Closer of the reader it buffers.
+type peekedReader struct {
+	*bufio.Reader
+	underlying io.Reader
+}
+
+func (r peekedReader) Close() error {
+	if c, ok := r.underlying.(io.Closer); ok {
+		return c.Close()
+	}
+
+	return nil
+}
+
+// decompress returns a reader that transparently decompresses r if it's gzip, bzip2 or zstd compressed,
+// which is detected via the magic bytes at the start of r.
+//
+// Uncompressed io.ReadSeekers are returned as they are (so seeking stays possible),
+// other readers may be wrapped as the magic bytes need to be buffered.
+// io.Closers stay io.Closers.
+func decompress(r io.Reader) (io.Reader, error) {
+	magic, r, err := peekMagic(r)
+	if err != nil {
+		return r, err
+	}
+
+	for _, format := range compressionFormats {
+		if !bytes.HasPrefix(magic, format.magic) {
+			continue
+		}
+
+		dec, err := format.newReader(r)
+		if err != nil {
+			return r, fmt.Errorf("failed to decompress %s demo: %w", format.name, err)
+		}
+
+		return decompressedReader{
+			Reader:     dec,
+			compressed: r,
+		}, nil
+	}
+
+	return r, nil
+}
+
+// peekMagic returns the first bytes of r without consuming them.
+// Returns a reader that still contains the returned bytes, which is r itself if it's an io.ReadSeeker.
+func peekMagic(r io.Reader) ([]byte, io.Reader, error) {
+	if seeker, ok := r.(io.ReadSeeker); ok {
+		// Not all io.Seekers can actually seek (e.g. os.Stdin)
+		pos, err := seeker.Seek(0, io.SeekCurrent)
+		if err == nil {
+			magic := make([]byte, maxMagicSize)
+
+			n, err := io.ReadFull(seeker, magic)
+			if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+				return nil, r, errors.Wrap(err, "failed to read magic bytes")
+			}
+
+			_, err = seeker.Seek(pos, io.SeekStart)
+			if err != nil {
+				return nil, r, errors.Wrap(err, "failed to seek back after reading magic bytes")
+			}
+
+			return magic[:n], r, nil
+		}
+	}
+
+	buffered := peekedReader{
+		Reader:     bufio.NewReader(r),
+		underlying: r,
+	}
+
+	// Short demos are detected as uncompressed, reading them fails later on
+	magic, _ := buffered.Peek(maxMagicSize)
+
+	return magic, buffered, nil
+}
+
+// OpenDemo opens the demo file at the given path for use with NewParser() or NewParserWithConfig().
+// gzip, bzip2 and zstd compressed demos (e.g. '.dem.gz', '.dem.bz2' & '.dem.zst') are decompressed transparently.
+// Uncompressed demos are returned as *os.File, so they support seeking (see Parser.SeekToTick()).
+//
+// The returned io.ReadCloser must be closed by the caller (Parser.Close() does this as well).
+func OpenDemo(path string) (io.ReadCloser, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+
+	r, err := decompress(f)
+	if err != nil {
+		_ = f.Close()
+
+		return nil, err
+	}
+
+	return r.(io.ReadCloser), nil
+}
@@ -0,0 +1,161 @@
+package demoinfocs
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/markus-wa/demoinfocs-golang/v5/internal/demotest"
+)
+
+func gzipCompress(t *testing.T, b []byte) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+
+	w := gzip.NewWriter(&buf)
+
+	_, err := w.Write(b)
+	assert.NoError(t, err)
+	assert.NoError(t, w.Close())
+
+	return buf.Bytes()
+}
+
+func zstdCompress(t *testing.T, b []byte) []byte {
+	t.Helper()
+
+	enc, err := zstd.NewWriter(nil)
+	assert.NoError(t, err)
+
+	return enc.EncodeAll(b, nil)
+}
+
+// bzip2Filestamp is "PBDEMS2\x00" compressed with bzip2 (the standard library can't compress bzip2).
+var bzip2Filestamp = []byte{
+	0x42, 0x5a, 0x68, 0x39, 0x31, 0x41, 0x59, 0x26, 0x53, 0x59, 0x2d, 0x5a, 0x5e, 0x08, 0x00, 0x00, 0x03, 0x4e, 0x00, 0x40,
+	0x00, 0x10, 0x00, 0x16, 0x02, 0x48, 0x00, 0x20, 0x00, 0x31, 0x0c, 0x08, 0x21, 0xa6, 0x8d, 0xa8, 0x83, 0x7a, 0x43, 0xc5,
+	0xdc, 0x91, 0x4e, 0x14, 0x24, 0x0b, 0x56, 0x97, 0x82, 0x00,
+}
+
+func TestDecompress(t *testing.T) {
+	demo := demotest.New(t).TickPacket(0).Stop(1).Bytes()
+
+	tests := map[string]struct {
+		input    []byte
+		expected []byte
+	}{
+		"uncompressed": {demo, demo},
+		"gzip":         {gzipCompress(t, demo), demo},
+		"zstd":         {zstdCompress(t, demo), demo},
+		"bzip2":        {bzip2Filestamp, []byte("PBDEMS2\x00")},
+		"short":        {[]byte{0x1f}, []byte{0x1f}},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			for _, input := range []io.Reader{
+				bytes.NewReader(test.input),
+				struct{ io.Reader }{bytes.NewReader(test.input)}, // not seekable
+			} {
+				r, err := decompress(input)
+				assert.NoError(t, err)
+
+				actual, err := io.ReadAll(r)
+				assert.NoError(t, err)
+				assert.Equal(t, test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestDecompress_UncompressedReadSeekerUnchanged(t *testing.T) {
+	input := bytes.NewReader(demotest.New(t).Stop(0).Bytes())
+
+	r, err := decompress(input)
+	assert.NoError(t, err)
+	assert.Same(t, input, r)
+}
+
+func TestDecompress_CorruptGzip(t *testing.T) {
+	_, err := decompress(bytes.NewReader([]byte{0x1f, 0x8b, 0x00}))
+
+	assert.Error(t, err)
+}
+
+func TestNewParser_Compressed(t *testing.T) {
+	demo := demotest.New(t).TickPacket(0).TickPacket(1).Stop(2).Bytes()
+
+	for name, compressed := range map[string][]byte{
+		"gzip": gzipCompress(t, demo),
+		"zstd": zstdCompress(t, demo),
+	} {
+		t.Run(name, func(t *testing.T) {
+			p := NewParser(bytes.NewReader(compressed))
+			defer p.Close()
+
+			assert.NoError(t, p.ParseToEnd())
+			assert.Equal(t, 2, p.GameState().IngameTick())
+			assert.ErrorIs(t, p.SeekToTick(0), ErrSeekNotSupported)
+		})
+	}
+}
+
+func TestNewParser_CorruptCompressed(t *testing.T) {
+	p := NewParser(bytes.NewReader(append([]byte{0x1f, 0x8b, 0x00}, make([]byte, 16)...)))
+	defer p.Close()
+
+	err := p.ParseToEnd()
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, ErrInvalidFileType)
+}
+
+func TestOpenDemo(t *testing.T) {
+	demo := demotest.New(t).TickPacket(0).Stop(1).Bytes()
+	dir := t.TempDir()
+
+	files := map[string][]byte{
+		"demo.dem":     demo,
+		"demo.dem.gz":  gzipCompress(t, demo),
+		"demo.dem.zst": zstdCompress(t, demo),
+	}
+
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		assert.NoError(t, os.WriteFile(path, content, 0o600))
+
+		f, err := OpenDemo(path)
+		assert.NoError(t, err)
+
+		p := NewParser(f)
+
+		assert.NoError(t, p.ParseToEnd(), name)
+		assert.Equal(t, 1, p.GameState().IngameTick(), name)
+		assert.NoError(t, p.Close(), name)
+	}
+
+	_, isFile := mustOpenDemo(t, filepath.Join(dir, "demo.dem")).(*os.File)
+	assert.True(t, isFile, "uncompressed demos should stay seekable")
+
+	_, err := OpenDemo(filepath.Join(dir, "missing.dem"))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func mustOpenDemo(t *testing.T, path string) io.ReadCloser {
+	t.Helper()
+
+	f, err := OpenDemo(path)
+	assert.NoError(t, err)
+
+	t.Cleanup(func() {
+		assert.NoError(t, f.Close())
+	})
+
+	return f
+}
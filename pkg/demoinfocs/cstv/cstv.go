@@ -2,75 +2,290 @@ package cstv
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
+	"math"
 	"net/http"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/pkg/errors"
 )
 
-type sync struct {
-	Tick             int     `json:"tick"`
-	EndTick          int     `json:"endtick"`
-	MaxTick          int     `json:"maxtick"`
-	RtDelay          float64 `json:"rtdelay"`
-	RcvAge           float64 `json:"rcvage"`
-	Fragment         int     `json:"fragment"`
-	SignupFragment   int     `json:"signup_fragment"`
-	Tps              int     `json:"tps"`
-	KeyframeInterval int     `json:"keyframe_interval"`
-	Map              string  `json:"map"`
-	Protocol         int     `json:"protocol"`
+// SyncInfo is the information about a broadcast returned by the /sync endpoint of a CSTV relay.
+type SyncInfo struct {
+	Tick             int     `json:"tick"`              // Ingame tick of the fragment's keyframe
+	EndTick          int     `json:"endtick"`           // Ingame tick at the end of the fragment
+	MaxTick          int     `json:"maxtick"`           // Latest ingame tick received by the relay
+	RtDelay          float64 `json:"rtdelay"`           // Seconds since the fragment was received by the relay, i.e. its delay from real-time
+	RcvAge           float64 `json:"rcvage"`            // Seconds since the relay last received data from the game server
+	Fragment         int     `json:"fragment"`          // Fragment at which clients should start
+	SignupFragment   int     `json:"signup_fragment"`   // Fragment of the start (signon) data, i.e. the start of the broadcast
+	Tps              int     `json:"tps"`               // Ticks per second
+	KeyframeInterval int     `json:"keyframe_interval"` // Seconds between keyframes, i.e. the duration of a fragment
+	Map              string  `json:"map"`               // Map name
+	Protocol         int     `json:"protocol"`          // Broadcast protocol version, 5 for CS2
+}
+
+// Options configures the HTTP requests of a Reader, see NewReaderWithOptions().
+type Options struct {
+	// Client is used for all requests, e.g. to configure TLS, proxies or connection pooling.
+	// Defaults to http.DefaultClient.
+	Client *http.Client
+
+	// Header is added to all requests, e.g. a User-Agent or authorization tokens required by tournament relays.
+	Header http.Header
+
+	// PrepareRequest is called before each request is sent, e.g. to add headers that change over time (refreshed tokens etc.).
+	// Returning an error aborts the request. Optional.
+	PrepareRequest func(req *http.Request) error
+
+	// RequestTimeout is the maximum duration of each request, including reading the response body.
+	// Zero means no timeout other than the one of Client.
+	RequestTimeout time.Duration
+
+	// Backoff decides whether and how long to wait before retrying a fragment that isn't available (yet).
+	// Defaults to DefaultBackoff(10 * time.Second).
+	Backoff Backoff
+
+	// FromStart makes the Reader start at the signup fragment, i.e. the start of the broadcast, and catch up from there.
+	// By default the Reader starts at the fragment returned by /sync, i.e. the live edge.
+	FromStart bool
+
+	// FragmentsBehindLive makes the Reader start the given number of fragments before the live edge,
+	// but not before the signup fragment. Ignored if FromStart is set.
+	FragmentsBehindLive int
+
+	// Resume makes the Reader continue at a position returned by Reader.Position(),
+	// e.g. to resume parsing a broadcast from a checkpoint. The first byte read is the one at that position.
+	// Only the remaining fragments are requested, FromStart & FragmentsBehindLive are ignored.
+	Resume *Position
+}
+
+// Position is the position of a byte in a broadcast, see Reader.Position().
+type Position struct {
+	Fragment int   // Fragment whose data contains the byte
+	Delta    bool  // Whether the byte is part of the fragment's delta, otherwise it's part of the start & full data
+	Offset   int64 // Offset of the byte in the delta or the start & full data
+}
+
+// Backoff returns how long to wait before retrying a failed request for a fragment,
+// attempt is the number of consecutive failures so far (starting at 1).
+// If retry is false the broadcast is considered to have ended and the Reader returns io.EOF.
+type Backoff func(attempt int) (wait time.Duration, retry bool)
+
+// ExponentialBackoff returns a Backoff that waits initial after the first failure and factor times longer after each subsequent one.
+// It gives up after maxRetries retries or once the wait would exceed maxWait.
+func ExponentialBackoff(initial time.Duration, factor float64, maxRetries int, maxWait time.Duration) Backoff {
+	return func(attempt int) (time.Duration, bool) {
+		wait := time.Duration(float64(initial) * math.Pow(factor, float64(attempt-1)))
+
+		return wait, attempt <= maxRetries && wait <= maxWait
+	}
+}
+
+// DefaultBackoff returns the Backoff used by NewReader(): an ExponentialBackoff starting at 1s with a factor of 1.5,
+// which gives up after 4 retries or once the wait would exceed the timeout.
+func DefaultBackoff(timeout time.Duration) Backoff {
+	return ExponentialBackoff(time.Second, 1.5, 4, timeout)
+}
+
+func (o Options) client() *http.Client {
+	if o.Client == nil {
+		return http.DefaultClient
+	}
+
+	return o.Client
+}
+
+// get sends a GET request, the response body must be closed by the caller.
+func (o Options) get(ctx context.Context, url string) (*http.Response, error) {
+	cancel := context.CancelFunc(func() {})
+
+	if o.RequestTimeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, o.RequestTimeout)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		cancel()
+
+		return nil, err
+	}
+
+	for k, v := range o.Header {
+		req.Header[k] = append(req.Header[k], v...)
+	}
+
+	if o.PrepareRequest != nil {
+		err = o.PrepareRequest(req)
+		if err != nil {
+			cancel()
+
+			return nil, fmt.Errorf("failed to prepare request: %w", err)
+		}
+	}
+
+	resp, err := o.client().Do(req)
+	if err != nil {
+		cancel()
+
+		return nil, err
+	}
+
+	resp.Body = cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+
+	return resp, nil
+}
+
+// cancelOnClose cancels the context of a request with RequestTimeout once the response body is closed.
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c cancelOnClose) Close() error {
+	defer c.cancel()
+
+	return c.ReadCloser.Close()
+}
+
+// getAll sends a GET request and returns the response body, non-200 responses are returned as errors.
+func (o Options) getAll(ctx context.Context, url string) ([]byte, error) {
+	resp, err := o.get(ctx, url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %q: %w", url, err)
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to get %q: unexpected status %q", url, resp.Status)
+	}
+
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response from %q: %w", url, err)
+	}
+
+	return b, nil
 }
 
 type Reader struct {
+	ctx     context.Context
 	baseUrl string
-	sync    sync
-	frag    int
+	sync    SyncInfo
+	frag    atomic.Int64 // next fragment to read
 	buf     bytes.Buffer
-	timeout time.Duration
+	opts    Options
+
+	mutex      sync.Mutex
+	startSkip  int64         // Bytes of the start & full data that were skipped, see Options.Resume
+	written    int64         // Stream offset of the end of buf
+	deltaStart []deltaOffset // Stream offsets of the deltas
+}
+
+// deltaOffset is the stream offset at which the delta of a fragment starts.
+type deltaOffset struct {
+	fragment int
+	offset   int64
+}
+
+// SyncInfo returns the sync information of the fragment at which the Reader started.
+// It isn't updated while reading, see Sync() for the current state of the broadcast.
+func (c *Reader) SyncInfo() SyncInfo {
+	return c.sync
+}
+
+// Sync fetches the current sync information of the broadcast from the relay,
+// e.g. to check how far the Reader is behind the live edge via Fragment() or whether the map has changed.
+// May be called concurrently with Read().
+func (c *Reader) Sync(ctx context.Context) (SyncInfo, error) {
+	return getSync(ctx, c.opts, c.baseUrl+"/sync")
+}
+
+// Fragment returns the latest fragment that has been read, e.g. to compare it to the live edge of the broadcast.
+// May be called concurrently with Read().
+func (c *Reader) Fragment() int {
+	return int(c.frag.Load()) - 1
+}
+
+// Position returns the position of the byte at the given offset of the stream,
+// which can be passed to Options.Resume to continue reading at that byte with a new Reader.
+// Offsets at or beyond the end of the data read so far are positions in the next fragment's delta.
+// May be called concurrently with Read().
+func (c *Reader) Position(offset int64) Position {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if offset >= c.written {
+		return Position{Fragment: int(c.frag.Load()), Delta: true, Offset: offset - c.written}
+	}
+
+	for i := len(c.deltaStart) - 1; i >= 0; i-- {
+		if d := c.deltaStart[i]; d.offset <= offset {
+			return Position{Fragment: d.fragment, Delta: true, Offset: offset - d.offset}
+		}
+	}
+
+	return Position{Fragment: c.sync.Fragment, Offset: offset + c.startSkip}
+}
+
+// writeDelta adds the delta of the next fragment to the buffer.
+func (c *Reader) writeDelta(b []byte) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.deltaStart = append(c.deltaStart, deltaOffset{fragment: int(c.frag.Load()), offset: c.written})
+	c.written += int64(len(b))
+	c.buf.Write(b)
+
+	c.frag.Add(1)
 }
 
 func (c *Reader) Read(p []byte) (n int, err error) {
 	n, err = c.buf.Read(p)
 
-	nFails := 0
-	backoff := time.Second
+	attempt := 0
 
 	for n < len(p) && errors.Is(err, io.EOF) {
-		deltaUrl := c.baseUrl + fmt.Sprintf("/%d/delta", c.frag)
+		deltaUrl := c.baseUrl + fmt.Sprintf("/%d/delta", c.frag.Load())
 
-		deltaResp, err := http.Get(deltaUrl)
-		if err != nil {
-			return n, fmt.Errorf("failed to get %q: %w", deltaUrl, err)
-		}
+		var b []byte
 
-		defer deltaResp.Body.Close()
+		b, err = c.opts.getAll(c.ctx, deltaUrl)
+		if err != nil {
+			if c.ctx.Err() != nil {
+				return n, c.ctx.Err()
+			}
 
-		if deltaResp.StatusCode != http.StatusOK {
-			time.Sleep(backoff)
+			attempt++
 
-			backoff = time.Duration(float64(backoff) * 1.5)
-			nFails++
+			wait, retry := c.opts.Backoff(attempt)
+			if !retry {
+				return n, fmt.Errorf("%w: end of CSTV stream (%w)", io.EOF, err)
+			}
 
-			if nFails == 5 || backoff > c.timeout {
-				return n, fmt.Errorf("%w: end of CSTV stream", io.EOF)
+			err = sleep(c.ctx, wait)
+			if err != nil {
+				return n, err
 			}
 
+			err = io.EOF
+
 			continue
 		}
 
-		_, err = io.Copy(&c.buf, deltaResp.Body)
-		if err != nil {
-			return n, fmt.Errorf("failed to read response from %q: %w", deltaUrl, err)
-		}
+		c.writeDelta(b)
 
-		c.frag++
-		backoff = time.Second // reset backoff on success
+		attempt = 0 // reset backoff on success
 
-		n2, err := c.buf.Read(p[n:])
+		var n2 int
+
+		n2, err = c.buf.Read(p[n:])
 		n += n2
 	}
 
@@ -85,63 +300,175 @@ func (c *Reader) Read(p []byte) (n int, err error) {
 // The timeout is the maximum time to retry for a response from the CSTV server,
 // using an exponential backoff mechanism, starting at 1s.
 // If the timeout is exceeded, the reader will return an io.EOF error.
+//
+// See also: NewReaderWithContext() & NewReaderWithOptions()
 func NewReader(baseUrl string, timeout time.Duration) (*Reader, error) {
-	syncUrl := baseUrl + "/sync"
+	return NewReaderWithContext(context.Background(), baseUrl, timeout)
+}
 
-	syncResp, err := http.Get(syncUrl)
+// NewReaderWithContext creates a new CSTV reader that stops when the context is done.
+// The context applies to the initial requests as well as all requests and backoffs of subsequent reads,
+// which will return the context's error once it's done.
+//
+// See also: NewReader() & NewReaderWithOptions()
+func NewReaderWithContext(ctx context.Context, baseUrl string, timeout time.Duration) (*Reader, error) {
+	return NewReaderWithOptions(ctx, baseUrl, Options{
+		Backoff: DefaultBackoff(timeout),
+	})
+}
+
+// NewReaderAtPosition creates a new CSTV reader that continues at a position returned by Reader.Position(), see Options.Resume.
+// The timeout and the context work the same way as for NewReaderWithContext().
+//
+// See also: NewReaderWithOptions()
+func NewReaderAtPosition(ctx context.Context, baseUrl string, timeout time.Duration, pos Position) (*Reader, error) {
+	return NewReaderWithOptions(ctx, baseUrl, Options{
+		Backoff: DefaultBackoff(timeout),
+		Resume:  &pos,
+	})
+}
+
+// NewReaderWithOptions creates a new CSTV reader that sends its requests according to the given options,
+// e.g. with a custom HTTP client or authorization headers.
+// The context works the same way as for NewReaderWithContext().
+//
+// By default the reader starts at the live edge of the broadcast, see Options.FromStart, Options.FragmentsBehindLive & Options.Resume.
+// Failed requests for the sync info and the first fragments aren't retried,
+// failed requests for subsequent fragments are retried according to Options.Backoff.
+func NewReaderWithOptions(ctx context.Context, baseUrl string, opts Options) (*Reader, error) {
+	if opts.Backoff == nil {
+		opts.Backoff = DefaultBackoff(10 * time.Second)
+	}
+
+	s, err := getSync(ctx, opts, baseUrl+"/sync")
 	if err != nil {
-		return nil, fmt.Errorf("failed to get sync from %q: %w", syncUrl, err)
+		return nil, err
+	}
+
+	if opts.Resume != nil && opts.Resume.Delta {
+		return resumeDelta(ctx, baseUrl, opts, s)
 	}
 
-	defer syncResp.Body.Close()
+	start := s.Fragment
 
-	b, err := io.ReadAll(syncResp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response from %q: %w", syncUrl, err)
+	switch {
+	case opts.Resume != nil:
+		start = opts.Resume.Fragment
+
+	case opts.FromStart:
+		start = s.SignupFragment
+
+	case opts.FragmentsBehindLive > 0:
+		start = max(s.Fragment-opts.FragmentsBehindLive, s.SignupFragment)
 	}
 
-	var s sync
+	// Not every fragment has a keyframe, the relay returns the first one with a keyframe at or after the requested one
+	if start != s.Fragment {
+		s, err = getSync(ctx, opts, fmt.Sprintf("%s/sync?fragment=%d", baseUrl, start))
+		if err != nil {
+			return nil, err
+		}
+	}
 
-	err = json.Unmarshal(b, &s)
-	if err != nil {
-		return nil, fmt.Errorf("failed to decode response from %q: %w", syncUrl, err)
+	if opts.Resume != nil && s.Fragment != opts.Resume.Fragment {
+		return nil, fmt.Errorf("failed to resume at fragment %d: relay returned keyframe of fragment %d", opts.Resume.Fragment, s.Fragment)
 	}
 
-	startUrl := fmt.Sprintf(baseUrl+"/%d/start", s.SignupFragment)
+	var buf bytes.Buffer
 
-	startResp, err := http.Get(startUrl)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get %q: %w", startUrl, err)
+	for _, url := range []string{
+		fmt.Sprintf(baseUrl+"/%d/start", s.SignupFragment),
+		fmt.Sprintf(baseUrl+"/%d/full", s.Fragment),
+	} {
+		b, err := opts.getAll(ctx, url)
+		if err != nil {
+			return nil, err
+		}
+
+		buf.Write(b)
 	}
 
-	defer startResp.Body.Close()
+	r := &Reader{
+		ctx:     ctx,
+		baseUrl: baseUrl,
+		sync:    s,
+		buf:     buf,
+		opts:    opts,
+	}
 
-	var buf bytes.Buffer
+	if opts.Resume != nil {
+		if opts.Resume.Offset > int64(r.buf.Len()) {
+			return nil, fmt.Errorf("failed to resume at offset %d: start & full data of fragment %d are only %d bytes", opts.Resume.Offset, s.Fragment, r.buf.Len())
+		}
 
-	_, err = io.Copy(&buf, startResp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response from %q: %w", startUrl, err)
+		r.buf.Next(int(opts.Resume.Offset))
+		r.startSkip = opts.Resume.Offset
 	}
 
-	fullUrl := fmt.Sprintf(baseUrl+"/%d/full", s.Fragment)
+	r.written = int64(r.buf.Len())
+	r.frag.Store(int64(s.Fragment + 1))
+
+	return r, nil
+}
+
+// resumeDelta creates a Reader that continues in the delta of a fragment, see Options.Resume.
+func resumeDelta(ctx context.Context, baseUrl string, opts Options, s SyncInfo) (*Reader, error) {
+	r := &Reader{
+		ctx:     ctx,
+		baseUrl: baseUrl,
+		sync:    s,
+		opts:    opts,
+	}
+
+	r.frag.Store(int64(opts.Resume.Fragment))
+
+	// The delta isn't needed yet (and may not be available) if none of it has been read
+	if opts.Resume.Offset == 0 {
+		return r, nil
+	}
 
-	fullResp, err := http.Get(fullUrl)
+	b, err := opts.getAll(ctx, fmt.Sprintf("%s/%d/delta", baseUrl, opts.Resume.Fragment))
 	if err != nil {
-		return nil, fmt.Errorf("failed to get %q: %w", fullUrl, err)
+		return nil, err
+	}
+
+	if opts.Resume.Offset > int64(len(b)) {
+		return nil, fmt.Errorf("failed to resume at offset %d: delta of fragment %d is only %d bytes", opts.Resume.Offset, opts.Resume.Fragment, len(b))
 	}
 
-	defer fullResp.Body.Close()
+	r.written = -opts.Resume.Offset
+	r.writeDelta(b)
+	r.buf.Next(int(opts.Resume.Offset))
+
+	return r, nil
+}
 
-	_, err = io.Copy(&buf, fullResp.Body)
+func getSync(ctx context.Context, opts Options, syncUrl string) (SyncInfo, error) {
+	var s SyncInfo
+
+	b, err := opts.getAll(ctx, syncUrl)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response from %q: %w", fullUrl, err)
+		return s, fmt.Errorf("failed to get sync: %w", err)
 	}
 
-	return &Reader{
-		baseUrl: baseUrl,
-		sync:    s,
-		buf:     buf,
-		frag:    s.Fragment + 1,
-		timeout: timeout,
-	}, nil
+	err = json.Unmarshal(b, &s)
+	if err != nil {
+		return s, fmt.Errorf("failed to decode response from %q: %w", syncUrl, err)
+	}
+
+	return s, nil
+}
+
+// sleep waits for the given duration or until the context is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+
+	case <-timer.C:
+		return nil
+	}
 }
@@ -0,0 +1,452 @@
+package cstv_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	gosync "sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/markus-wa/demoinfocs-golang/v5/pkg/demoinfocs/cstv"
+)
+
+func newTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/sync", func(w http.ResponseWriter, _ *http.Request) {
+		fmt.Fprint(w, `{"tick": 100, "fragment": 2, "signup_fragment": 1}`)
+	})
+	mux.HandleFunc("/1/start", func(w http.ResponseWriter, _ *http.Request) {
+		fmt.Fprint(w, "start")
+	})
+	mux.HandleFunc("/2/full", func(w http.ResponseWriter, _ *http.Request) {
+		fmt.Fprint(w, "full")
+	})
+	// deltas aren't available yet, so the reader has to back off
+
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+func TestNewReaderWithContext_Cancelled(t *testing.T) {
+	srv := newTestServer(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := cstv.NewReaderWithContext(ctx, srv.URL, time.Minute)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestReader_Read_CancelDuringBackoff(t *testing.T) {
+	srv := newTestServer(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	r, err := cstv.NewReaderWithContext(ctx, srv.URL, time.Minute)
+	assert.NoError(t, err)
+
+	b := make([]byte, 9)
+
+	n, err := io.ReadFull(r, b)
+	assert.NoError(t, err)
+	assert.Equal(t, "startfull", string(b[:n]))
+
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	start := time.Now()
+
+	_, err = r.Read(b)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+// readAll reads until the end of the stream, which is signalled by a wrapped io.EOF.
+func readAll(r io.Reader) ([]byte, error) {
+	var (
+		b   []byte
+		buf = make([]byte, 4)
+	)
+
+	for {
+		n, err := r.Read(buf)
+		b = append(b, buf[:n]...)
+
+		if err != nil {
+			return b, err
+		}
+	}
+}
+
+func TestNewReaderWithOptions(t *testing.T) {
+	var (
+		lock     gosync.Mutex
+		requests []string
+		deltas   = map[string]int{} // fragment -> failures before it's available
+	)
+
+	deltas["3"] = 2
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/sync", func(w http.ResponseWriter, _ *http.Request) {
+		fmt.Fprint(w, `{"tick": 100, "fragment": 2, "signup_fragment": 1}`)
+	})
+	mux.HandleFunc("/1/start", func(w http.ResponseWriter, _ *http.Request) {
+		fmt.Fprint(w, "start")
+	})
+	mux.HandleFunc("/2/full", func(w http.ResponseWriter, _ *http.Request) {
+		fmt.Fprint(w, "full")
+	})
+	mux.HandleFunc("/{frag}/delta", func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+
+		frag := r.PathValue("frag")
+		if frag != "3" || deltas[frag] > 0 {
+			deltas[frag]--
+
+			http.NotFound(w, r)
+
+			return
+		}
+
+		fmt.Fprint(w, "delta"+frag)
+	})
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		requests = append(requests, r.URL.Path)
+		lock.Unlock()
+
+		if r.Header.Get("Authorization") != "Bearer token-"+r.URL.Path || r.Header.Get("User-Agent") != "test" {
+			w.WriteHeader(http.StatusUnauthorized)
+
+			return
+		}
+
+		mux.ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+
+	var attempts []int
+
+	r, err := cstv.NewReaderWithOptions(context.Background(), srv.URL, cstv.Options{
+		Client: srv.Client(),
+		Header: http.Header{"User-Agent": {"test"}},
+		PrepareRequest: func(req *http.Request) error {
+			req.Header.Set("Authorization", "Bearer token-"+req.URL.Path)
+
+			return nil
+		},
+		Backoff: func(attempt int) (time.Duration, bool) {
+			attempts = append(attempts, attempt)
+
+			return 0, attempt <= 2
+		},
+	})
+	assert.NoError(t, err)
+
+	b, err := readAll(r)
+	assert.ErrorIs(t, err, io.EOF)
+	assert.Equal(t, "startfulldelta3", string(b))
+
+	// 2 failures for fragment 3 before it's available, then 3 failures for fragment 4 until the backoff gives up
+	assert.Equal(t, []int{1, 2, 1, 2, 3}, attempts)
+	assert.Equal(t, []string{
+		"/sync", "/1/start", "/2/full",
+		"/3/delta", "/3/delta", "/3/delta",
+		"/4/delta", "/4/delta", "/4/delta",
+	}, requests)
+}
+
+func TestNewReaderWithOptions_Unauthorized(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer srv.Close()
+
+	_, err := cstv.NewReaderWithOptions(context.Background(), srv.URL, cstv.Options{})
+	assert.ErrorContains(t, err, "401 Unauthorized")
+}
+
+func TestNewReaderWithOptions_PrepareRequestError(t *testing.T) {
+	srv := newTestServer(t)
+
+	errPrepare := errors.New("no token")
+
+	_, err := cstv.NewReaderWithOptions(context.Background(), srv.URL, cstv.Options{
+		PrepareRequest: func(*http.Request) error {
+			return errPrepare
+		},
+	})
+	assert.ErrorIs(t, err, errPrepare)
+}
+
+func TestReader_Read_RequestTimeout(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.Handle("/", newTestServer(t).Config.Handler)
+	mux.HandleFunc("/3/delta", func(_ http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	})
+
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	r, err := cstv.NewReaderWithOptions(context.Background(), srv.URL, cstv.Options{
+		RequestTimeout: 50 * time.Millisecond,
+		Backoff: func(attempt int) (time.Duration, bool) {
+			return 0, attempt < 2
+		},
+	})
+	assert.NoError(t, err)
+
+	start := time.Now()
+
+	b, err := readAll(r)
+	assert.ErrorIs(t, err, io.EOF)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, "startfull", string(b))
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestExponentialBackoff(t *testing.T) {
+	backoff := cstv.DefaultBackoff(3 * time.Second)
+
+	var waits []time.Duration
+
+	for attempt := 1; ; attempt++ {
+		wait, retry := backoff(attempt)
+		if !retry {
+			break
+		}
+
+		waits = append(waits, wait)
+	}
+
+	assert.Equal(t, []time.Duration{time.Second, 1500 * time.Millisecond, 2250 * time.Millisecond}, waits)
+
+	_, retry := cstv.DefaultBackoff(time.Minute)(5)
+	assert.False(t, retry)
+}
+
+// newSyncTestServer returns a server with the live edge at fragment 6 and the signup fragment 2,
+// only fragments 3 & 5 have keyframes. Requested paths including queries are appended to requests.
+func newSyncTestServer(t *testing.T, requests *[]string) *httptest.Server {
+	t.Helper()
+
+	var lock gosync.Mutex
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		*requests = append(*requests, r.URL.RequestURI())
+		lock.Unlock()
+
+		switch r.URL.RequestURI() {
+		case "/sync":
+			fmt.Fprint(w, `{"tick": 600, "fragment": 6, "signup_fragment": 2, "tps": 64, "map": "de_test", "keyframe_interval": 3, "rtdelay": 1.5, "rcvage": 0.5, "protocol": 5}`)
+		case "/sync?fragment=2", "/sync?fragment=3":
+			fmt.Fprint(w, `{"tick": 300, "fragment": 3, "signup_fragment": 2, "tps": 64, "map": "de_test"}`)
+		case "/sync?fragment=5":
+			fmt.Fprint(w, `{"tick": 500, "fragment": 5, "signup_fragment": 2, "tps": 64, "map": "de_test"}`)
+		case "/2/start", "/3/full", "/5/full", "/6/full", "/4/delta", "/6/delta":
+			fmt.Fprint(w, r.URL.Path)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+func TestNewReaderWithOptions_StartPosition(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     cstv.Options
+		data     string
+		fragment int
+		requests []string
+	}{{
+		name:     "live",
+		data:     "/2/start/6/full",
+		fragment: 6,
+		requests: []string{"/sync", "/2/start", "/6/full", "/7/delta"},
+	}, {
+		name:     "from start",
+		opts:     cstv.Options{FromStart: true},
+		data:     "/2/start/3/full/4/delta",
+		fragment: 4,
+		requests: []string{"/sync", "/sync?fragment=2", "/2/start", "/3/full", "/4/delta", "/5/delta"},
+	}, {
+		name:     "behind live",
+		opts:     cstv.Options{FragmentsBehindLive: 1},
+		data:     "/2/start/5/full/6/delta",
+		fragment: 6,
+		requests: []string{"/sync", "/sync?fragment=5", "/2/start", "/5/full", "/6/delta", "/7/delta"},
+	}, {
+		name:     "behind live before start",
+		opts:     cstv.Options{FragmentsBehindLive: 10},
+		data:     "/2/start/3/full/4/delta",
+		fragment: 4,
+		requests: []string{"/sync", "/sync?fragment=2", "/2/start", "/3/full", "/4/delta", "/5/delta"},
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var requests []string
+
+			srv := newSyncTestServer(t, &requests)
+
+			test.opts.Backoff = func(int) (time.Duration, bool) {
+				return 0, false
+			}
+
+			r, err := cstv.NewReaderWithOptions(context.Background(), srv.URL, test.opts)
+			assert.NoError(t, err)
+
+			b, err := readAll(r)
+			assert.ErrorIs(t, err, io.EOF)
+			assert.Equal(t, test.data, string(b))
+			assert.Equal(t, test.fragment, r.Fragment())
+			assert.Equal(t, test.requests, requests)
+		})
+	}
+}
+
+func TestReader_SyncInfo(t *testing.T) {
+	var requests []string
+
+	r, err := cstv.NewReaderWithOptions(context.Background(), newSyncTestServer(t, &requests).URL, cstv.Options{})
+	assert.NoError(t, err)
+
+	assert.Equal(t, cstv.SyncInfo{
+		Tick:             600,
+		RtDelay:          1.5,
+		RcvAge:           0.5,
+		Fragment:         6,
+		SignupFragment:   2,
+		Tps:              64,
+		KeyframeInterval: 3,
+		Map:              "de_test",
+		Protocol:         5,
+	}, r.SyncInfo())
+
+	// nothing has been read yet besides the keyframe
+	assert.Equal(t, 6, r.Fragment())
+}
+
+func TestReader_Sync(t *testing.T) {
+	var requests []string
+
+	r, err := cstv.NewReaderWithOptions(context.Background(), newSyncTestServer(t, &requests).URL, cstv.Options{FromStart: true})
+	assert.NoError(t, err)
+
+	requests = nil
+
+	info, err := r.Sync(context.Background())
+	assert.NoError(t, err)
+
+	// the live edge, not the fragment at which the Reader started
+	assert.Equal(t, 6, info.Fragment)
+	assert.Equal(t, 3, r.SyncInfo().Fragment)
+	assert.Equal(t, []string{"/sync"}, requests)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err = r.Sync(ctx)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestNewReaderWithOptions_ResumeMissingKeyframe(t *testing.T) {
+	var requests []string
+
+	_, err := cstv.NewReaderWithOptions(context.Background(), newSyncTestServer(t, &requests).URL, cstv.Options{
+		Resume: &cstv.Position{Fragment: 2},
+	})
+	assert.ErrorContains(t, err, "relay returned keyframe of fragment 3")
+}
+
+// newFragmentTestServer serves a broadcast whose fragments contain their own URL path, starting at fragment 2.
+// The delta of fragment 5 isn't available yet.
+func newFragmentTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.RequestURI() {
+		case "/sync":
+			fmt.Fprint(w, `{"tick": 200, "fragment": 2, "signup_fragment": 1}`)
+
+		case "/sync?fragment=3":
+			fmt.Fprint(w, `{"tick": 300, "fragment": 3, "signup_fragment": 1}`)
+
+		case "/1/start", "/2/full", "/3/full", "/3/delta", "/4/delta":
+			fmt.Fprint(w, r.URL.Path)
+
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+func TestReader_Position_Resume(t *testing.T) {
+	srv := newFragmentTestServer(t)
+
+	r, err := cstv.NewReader(srv.URL, time.Minute)
+	assert.NoError(t, err)
+
+	// Reading exactly the available data doesn't request the missing delta
+	data := make([]byte, len("/1/start/2/full/3/delta/4/delta"))
+
+	_, err = io.ReadFull(r, data)
+	assert.NoError(t, err)
+	assert.Equal(t, "/1/start/2/full/3/delta/4/delta", string(data))
+
+	assert.Equal(t, cstv.Position{Fragment: 2, Offset: 9}, r.Position(9))
+	assert.Equal(t, cstv.Position{Fragment: 3, Delta: true, Offset: 2}, r.Position(17))
+	assert.Equal(t, cstv.Position{Fragment: 5, Delta: true}, r.Position(int64(len(data))))
+
+	for offset := range int64(len(data)) + 1 {
+		pos := r.Position(offset)
+
+		resumed, err := cstv.NewReaderAtPosition(context.Background(), srv.URL, time.Minute, pos)
+		assert.NoError(t, err)
+
+		rest := make([]byte, int64(len(data))-offset)
+
+		_, err = io.ReadFull(resumed, rest)
+		assert.NoError(t, err)
+		assert.Equal(t, string(data[offset:]), string(rest), "resumed at %+v", pos)
+
+		// The resumed Reader returns the same positions, so it can be resumed again
+		for i := range int64(len(rest)) + 1 {
+			assert.Equal(t, r.Position(offset+i), resumed.Position(i))
+		}
+	}
+}
+
+func TestNewReaderAtPosition_Unavailable(t *testing.T) {
+	srv := newFragmentTestServer(t)
+
+	_, err := cstv.NewReaderAtPosition(context.Background(), srv.URL, time.Minute, cstv.Position{Fragment: 4})
+	assert.ErrorContains(t, err, "404 Not Found")
+
+	_, err = cstv.NewReaderAtPosition(context.Background(), srv.URL, time.Minute, cstv.Position{Fragment: 3, Offset: 100})
+	assert.ErrorContains(t, err, "start & full data of fragment 3 are only 15 bytes")
+
+	_, err = cstv.NewReaderAtPosition(context.Background(), srv.URL, time.Minute, cstv.Position{Fragment: 5, Delta: true, Offset: 1})
+	assert.ErrorContains(t, err, "404 Not Found")
+}
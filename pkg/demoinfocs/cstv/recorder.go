@@ -0,0 +1,316 @@
+package cstv
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/golang/snappy"
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/markus-wa/demoinfocs-golang/v5/pkg/demoinfocs/msg"
+)
+
+// demoHeaderSize is the size of the PBDEMS2 filestamp and the file info & spawn groups offsets.
+const demoHeaderSize = 16
+
+// defaultTickRate is used for the playback time of recordings if the tick rate of the broadcast is unknown.
+const defaultTickRate = 64
+
+/*
+Recorder records a CSTV broadcast to a demo file while it's being read, like an io.TeeReader.
+
+The recorded demo is a regular PBDEMS2 demo (header, signon, packets, CDemoFileInfo & DEM_Stop)
+that can be parsed with demoinfocs.NewParser() and played back in the game.
+The demo is finalized once the end of the broadcast (DEM_Stop) has been read or when Close() is called,
+e.g. because the broadcast was cancelled.
+
+Example:
+
+	r, err := cstv.NewReader(url, time.Minute)
+	f, err := os.Create("match.dem")
+	rec := cstv.NewRecorder(r, f)
+	defer rec.Close()
+
+	p := demoinfocs.NewParserWithConfig(rec, demoinfocs.ParserConfig{Format: demoinfocs.DemoFormatCSTVBroadcast})
+*/
+type Recorder struct {
+	r        io.Reader
+	w        io.WriteSeeker
+	tickRate float64
+
+	pending   []byte // broadcast data that doesn't contain a complete frame yet
+	start     int64  // offset of the demo in w
+	offset    int64  // current offset relative to start
+	started   bool
+	finished  bool
+	firstTick int
+	lastTick  int
+	frames    int
+	err       error
+}
+
+// NewRecorder returns a Recorder that writes the broadcast read from r (e.g. a Reader) as demo to w.
+// If r is a Reader, the tick rate of the broadcast is used for the playback time of the demo, otherwise 64 ticks per second are assumed.
+func NewRecorder(r io.Reader, w io.WriteSeeker) *Recorder {
+	tickRate := float64(defaultTickRate)
+
+	if reader, ok := r.(*Reader); ok && reader.sync.Tps > 0 {
+		tickRate = float64(reader.sync.Tps)
+	}
+
+	return &Recorder{
+		r:         r,
+		w:         w,
+		tickRate:  tickRate,
+		firstTick: -1,
+	}
+}
+
+// Read reads from the underlying reader and records all complete frames.
+// Errors that occur while recording are returned as well.
+func (rec *Recorder) Read(p []byte) (int, error) {
+	n, err := rec.r.Read(p)
+
+	if rec.err == nil && n > 0 {
+		rec.err = rec.record(p[:n])
+	}
+
+	if rec.err != nil {
+		return n, rec.err
+	}
+
+	return n, err
+}
+
+// Close finalizes the demo by writing the CDemoFileInfo & DEM_Stop frames if the end of the broadcast hasn't been reached yet.
+// Incomplete frames are discarded. It doesn't close the underlying reader or writer.
+func (rec *Recorder) Close() error {
+	if rec.err != nil {
+		return rec.err
+	}
+
+	if rec.finished {
+		return nil
+	}
+
+	rec.err = rec.finish(uint32(max(rec.lastTick, 0)))
+
+	return rec.err
+}
+
+// record appends broadcast data and writes all frames that are complete.
+func (rec *Recorder) record(b []byte) error {
+	rec.pending = append(rec.pending, b...)
+
+	for !rec.finished {
+		n, err := rec.recordFrame(rec.pending)
+		if err != nil {
+			return err
+		}
+
+		if n == 0 {
+			break
+		}
+
+		rec.pending = rec.pending[n:]
+	}
+
+	// avoid holding on to the whole broadcast
+	rec.pending = append([]byte(nil), rec.pending...)
+
+	return nil
+}
+
+// recordFrame writes the first frame of b if it's complete and returns its size, or 0 if it isn't.
+// See the broadcast frame format in demoinfocs.parser.readFrameHeader().
+func (rec *Recorder) recordFrame(b []byte) (int, error) {
+	cmdVal, n := binary.Uvarint(b)
+	if n == 0 {
+		return 0, nil
+	}
+
+	if n < 0 {
+		return 0, errors.New("failed to record demo: corrupt frame command")
+	}
+
+	const headerSizeWithoutCmd = 4 + 1 + 4 // tick, unused byte & size
+
+	cmd := msg.EDemoCommands(cmdVal)
+
+	if cmd == msg.EDemoCommands_DEM_Stop {
+		if len(b) < n+4+1 {
+			return 0, nil
+		}
+
+		return n + 4 + 1, rec.finish(binary.LittleEndian.Uint32(b[n:]))
+	}
+
+	if len(b) < n+headerSizeWithoutCmd {
+		return 0, nil
+	}
+
+	tick := binary.LittleEndian.Uint32(b[n:])
+	size := int(binary.LittleEndian.Uint32(b[n+5:]))
+	frameSize := n + headerSizeWithoutCmd + size
+
+	if len(b) < frameSize {
+		return 0, nil
+	}
+
+	payload := b[n+headerSizeWithoutCmd : frameSize]
+
+	payload, cmd, err := demoFramePayload(cmd, payload)
+	if err != nil {
+		return 0, fmt.Errorf("failed to record demo: %w", err)
+	}
+
+	err = rec.writeFrame(cmd, tick, payload)
+	if err != nil {
+		return 0, err
+	}
+
+	return frameSize, nil
+}
+
+// demoFramePayload converts the payload of a broadcast frame to the one of a demo frame.
+// Broadcasts contain packets as raw net-message data and spawn groups as raw message with a one byte prefix.
+func demoFramePayload(cmd msg.EDemoCommands, payload []byte) ([]byte, msg.EDemoCommands, error) {
+	if cmd&msg.EDemoCommands_DEM_IsCompressed != 0 {
+		cmd &^= msg.EDemoCommands_DEM_IsCompressed
+
+		var err error
+
+		payload, err = snappy.Decode(nil, payload)
+		if err != nil {
+			return nil, cmd, fmt.Errorf("failed to decompress frame: %w", err)
+		}
+	}
+
+	var m proto.Message
+
+	switch cmd {
+	case msg.EDemoCommands_DEM_Packet, msg.EDemoCommands_DEM_SignonPacket:
+		m = &msg.CDemoPacket{Data: payload}
+
+	case msg.EDemoCommands_DEM_SpawnGroups:
+		if len(payload) == 0 {
+			return nil, cmd, errors.New("empty spawn groups frame")
+		}
+
+		m = &msg.CDemoSpawnGroups{Msgs: [][]byte{payload[1:]}}
+
+	default:
+		return bytes.Clone(payload), cmd, nil
+	}
+
+	b, err := proto.Marshal(m)
+
+	return b, cmd, err
+}
+
+func (rec *Recorder) write(b []byte) error {
+	if !rec.started {
+		start, err := rec.w.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return fmt.Errorf("failed to record demo: %w", err)
+		}
+
+		rec.start = start
+		rec.started = true
+
+		header := make([]byte, demoHeaderSize)
+		copy(header, "PBDEMS2\x00") // offsets are set once the demo is finished
+
+		err = rec.write(header)
+		if err != nil {
+			return err
+		}
+	}
+
+	n, err := rec.w.Write(b)
+	rec.offset += int64(n)
+
+	if err != nil {
+		return fmt.Errorf("failed to record demo: %w", err)
+	}
+
+	return nil
+}
+
+// writeFrame writes a frame in the demo format: varint command, tick, size & payload.
+func (rec *Recorder) writeFrame(cmd msg.EDemoCommands, tick uint32, payload []byte) error {
+	b := binary.AppendUvarint(nil, uint64(cmd))
+	b = binary.AppendUvarint(b, uint64(tick))
+	b = binary.AppendUvarint(b, uint64(len(payload)))
+	b = append(b, payload...)
+
+	// signon frames have tick -1
+	if int32(tick) >= 0 {
+		if rec.firstTick < 0 {
+			rec.firstTick = int(tick)
+		}
+
+		rec.lastTick = int(tick)
+	}
+
+	rec.frames++
+
+	return rec.write(b)
+}
+
+// finish writes the CDemoFileInfo & DEM_Stop frames and sets the file info offset in the demo header.
+func (rec *Recorder) finish(tick uint32) error {
+	rec.finished = true
+
+	ticks := 0
+	if rec.firstTick >= 0 {
+		ticks = rec.lastTick - rec.firstTick
+	}
+
+	fileInfo, err := proto.Marshal(&msg.CDemoFileInfo{
+		PlaybackTime:   proto.Float32(float32(float64(ticks) / rec.tickRate)),
+		PlaybackTicks:  proto.Int32(int32(ticks)),
+		PlaybackFrames: proto.Int32(int32(rec.frames)),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record demo: %w", err)
+	}
+
+	// make sure the header has been written
+	err = rec.write(nil)
+	if err != nil {
+		return err
+	}
+
+	fileInfoOffset := rec.offset
+
+	err = rec.writeFrame(msg.EDemoCommands_DEM_FileInfo, tick, fileInfo)
+	if err != nil {
+		return err
+	}
+
+	err = rec.writeFrame(msg.EDemoCommands_DEM_Stop, tick, nil)
+	if err != nil {
+		return err
+	}
+
+	_, err = rec.w.Seek(rec.start+8, io.SeekStart)
+	if err != nil {
+		return fmt.Errorf("failed to record demo: %w", err)
+	}
+
+	_, err = rec.w.Write(binary.LittleEndian.AppendUint32(nil, uint32(fileInfoOffset)))
+	if err != nil {
+		return fmt.Errorf("failed to record demo: %w", err)
+	}
+
+	_, err = rec.w.Seek(rec.start+rec.offset, io.SeekStart)
+	if err != nil {
+		return fmt.Errorf("failed to record demo: %w", err)
+	}
+
+	return nil
+}
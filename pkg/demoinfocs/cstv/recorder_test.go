@@ -0,0 +1,133 @@
+package cstv_test
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/markus-wa/demoinfocs-golang/v5/internal/demotest"
+	demoinfocs "github.com/markus-wa/demoinfocs-golang/v5/pkg/demoinfocs"
+	"github.com/markus-wa/demoinfocs-golang/v5/pkg/demoinfocs/cstv"
+	events "github.com/markus-wa/demoinfocs-golang/v5/pkg/demoinfocs/events"
+	"github.com/markus-wa/demoinfocs-golang/v5/pkg/demoinfocs/msg"
+)
+
+// newTestBroadcast returns a broadcast up to and including the DEM_SyncTick frame.
+func newTestBroadcast(t *testing.T) *demotest.Demo {
+	t.Helper()
+
+	return demotest.NewBroadcast(t).Signon()
+}
+
+// parseTicks parses a demo and returns the ingame ticks of all TickDone events.
+func parseTicks(t *testing.T, r io.Reader, format demoinfocs.DemoFormat) []int {
+	t.Helper()
+
+	cfg := demoinfocs.DefaultParserConfig
+	cfg.Format = format
+
+	p := demoinfocs.NewParserWithConfig(r, cfg)
+	defer p.Close()
+
+	var ticks []int
+
+	p.RegisterEventHandler(func(e events.TickDone) {
+		ticks = append(ticks, e.Tick)
+	})
+
+	assert.NoError(t, p.ParseToEnd())
+
+	return ticks
+}
+
+func record(t *testing.T, broadcast []byte) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "recorded.dem")
+
+	f, err := os.Create(path)
+	assert.NoError(t, err)
+
+	defer f.Close()
+
+	// one byte at a time, so frames are split across reads
+	rec := cstv.NewRecorder(iotest.OneByteReader(bytes.NewReader(broadcast)), f)
+
+	_, err = io.Copy(io.Discard, rec)
+	assert.NoError(t, err)
+	assert.NoError(t, rec.Close())
+
+	return path
+}
+
+func TestRecorder(t *testing.T) {
+	broadcast := newTestBroadcast(t).
+		Packets(0, 32).
+		FullPacket(64).
+		Packets(64, 96).
+		RawFrame(msg.EDemoCommands_DEM_SpawnGroups, 96, []byte{0, 1, 2}).
+		Stop(96).
+		Bytes()
+
+	path := record(t, broadcast)
+
+	f, err := os.Open(path)
+	assert.NoError(t, err)
+
+	defer f.Close()
+
+	expected := parseTicks(t, bytes.NewReader(broadcast), demoinfocs.DemoFormatCSTVBroadcast)
+	assert.Equal(t, []int{-1, 0, 32, 64, 96}, expected)
+	// demos have tick 0 instead of -1 before the first packet
+	assert.Equal(t, expected[1:], parseTicks(t, f, demoinfocs.DemoFormatFile))
+
+	// the parser closes the file
+	f, err = os.Open(path)
+	assert.NoError(t, err)
+
+	defer f.Close()
+
+	md, err := demoinfocs.ScanMetadata(f)
+	assert.NoError(t, err)
+
+	assert.True(t, md.HasFileInfo)
+	assert.Equal(t, "de_test", md.MapName)
+	assert.Equal(t, 96, md.PlaybackTicks)
+	assert.Equal(t, 9, md.PlaybackFrames)
+	assert.Equal(t, 1500*time.Millisecond, md.PlaybackTime)
+}
+
+func TestRecorder_Close(t *testing.T) {
+	broadcast := newTestBroadcast(t).Packets(0, 32).Stop(32).Bytes()
+
+	// the broadcast is cut off in the middle of the DEM_Stop frame
+	path := record(t, broadcast[:len(broadcast)-2])
+
+	f, err := os.Open(path)
+	assert.NoError(t, err)
+
+	defer f.Close()
+
+	assert.Equal(t, []int{0, 32}, parseTicks(t, f, demoinfocs.DemoFormatFile))
+}
+
+func TestRecorder_Corrupt(t *testing.T) {
+	broadcast := newTestBroadcast(t).
+		RawFrame(msg.EDemoCommands_DEM_Packet|msg.EDemoCommands_DEM_IsCompressed, 0, []byte{0xff}).
+		Stop(0).
+		Bytes()
+
+	f, err := os.Create(filepath.Join(t.TempDir(), "recorded.dem"))
+	assert.NoError(t, err)
+
+	defer f.Close()
+
+	_, err = io.Copy(io.Discard, cstv.NewRecorder(bytes.NewReader(broadcast), f))
+	assert.ErrorContains(t, err, "failed to record demo")
+}
@@ -0,0 +1,247 @@
+package server
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/golang/snappy"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/markus-wa/demoinfocs-golang/v5/pkg/demoinfocs/msg"
+)
+
+// ErrInvalidDemo signals that the demo isn't a valid CS2 demo (PBDEMS2).
+var ErrInvalidDemo = errors.New("invalid demo, only CS2 demos (PBDEMS2) are supported")
+
+// frame is a demo frame with its payload in the broadcast format.
+type frame struct {
+	cmd     msg.EDemoCommands
+	tick    uint32
+	payload []byte
+}
+
+// appendFrame encodes a frame the way CSTV broadcasts do:
+// varint command, uint32 tick, one unused byte, uint32 size & payload.
+// DEM_Stop frames have no size.
+func appendFrame(b []byte, f frame) []byte {
+	b = binary.AppendUvarint(b, uint64(f.cmd))
+	b = binary.LittleEndian.AppendUint32(b, f.tick)
+	b = append(b, 0)
+
+	if f.cmd == msg.EDemoCommands_DEM_Stop {
+		return b
+	}
+
+	b = binary.LittleEndian.AppendUint32(b, uint32(len(f.payload)))
+
+	return append(b, f.payload...)
+}
+
+// readDemo reads all frames of a demo until DEM_Stop and converts them to the broadcast format.
+// DEM_FileInfo is dropped as broadcasts don't contain it, the DEM_Stop frame isn't returned.
+func readDemo(r io.Reader) ([]frame, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read demo: %w", err)
+	}
+
+	// filestamp + file info & spawn groups offsets
+	if len(b) < 16 || string(b[:8]) != "PBDEMS2\x00" {
+		return nil, ErrInvalidDemo
+	}
+
+	b = b[16:]
+
+	var frames []frame
+
+	for len(b) > 0 {
+		var (
+			header [3]uint64
+			n      int
+		)
+
+		for i := range header {
+			header[i], n = binary.Uvarint(b)
+			if n <= 0 {
+				return nil, fmt.Errorf("%w: corrupt frame header", ErrInvalidDemo)
+			}
+
+			b = b[n:]
+		}
+
+		cmd := msg.EDemoCommands(header[0])
+		size := header[2]
+
+		if size > uint64(len(b)) {
+			return nil, fmt.Errorf("%w: frame exceeds end of demo", ErrInvalidDemo)
+		}
+
+		payload := b[:size]
+		b = b[size:]
+
+		if cmd&msg.EDemoCommands_DEM_IsCompressed != 0 {
+			cmd &^= msg.EDemoCommands_DEM_IsCompressed
+
+			payload, err = snappy.Decode(nil, payload)
+			if err != nil {
+				return nil, fmt.Errorf("failed to decompress frame: %w", err)
+			}
+		}
+
+		if cmd == msg.EDemoCommands_DEM_Stop {
+			return frames, nil
+		}
+
+		converted, err := convertFrame(frame{cmd: cmd, tick: uint32(header[1]), payload: payload})
+		if err != nil {
+			return nil, err
+		}
+
+		frames = append(frames, converted...)
+	}
+
+	return frames, nil
+}
+
+// convertFrame converts a demo frame's payload to the broadcast format.
+// Packets are sent as raw net-message data, spawn groups as raw messages with a one byte prefix.
+func convertFrame(f frame) ([]frame, error) {
+	switch f.cmd {
+	case msg.EDemoCommands_DEM_FileInfo:
+		return nil, nil
+
+	case msg.EDemoCommands_DEM_Packet, msg.EDemoCommands_DEM_SignonPacket:
+		var m msg.CDemoPacket
+
+		err := proto.Unmarshal(f.payload, &m)
+		if err != nil {
+			return nil, fmt.Errorf("failed to unmarshal packet: %w", err)
+		}
+
+		f.payload = m.GetData()
+
+		return []frame{f}, nil
+
+	case msg.EDemoCommands_DEM_SpawnGroups:
+		var m msg.CDemoSpawnGroups
+
+		err := proto.Unmarshal(f.payload, &m)
+		if err != nil {
+			return nil, fmt.Errorf("failed to unmarshal spawn groups: %w", err)
+		}
+
+		frames := make([]frame, 0, len(m.GetMsgs()))
+
+		for _, sg := range m.GetMsgs() {
+			frames = append(frames, frame{cmd: f.cmd, tick: f.tick, payload: append([]byte{0}, sg...)})
+		}
+
+		return frames, nil
+	}
+
+	return []frame{f}, nil
+}
+
+// fragment is a part of the broadcast as served by /<n>/delta & /<n>/full.
+type fragment struct {
+	tick  int    // ingame tick at the end of the fragment
+	delta []byte // frames since the previous fragment
+	full  []byte // keyframe with the state at the end of the fragment, nil if there is none
+}
+
+// broadcast is a demo sliced into fragments.
+type broadcast struct {
+	mapName   string
+	start     []byte
+	fragments []fragment
+}
+
+func isSignon(cmd msg.EDemoCommands) bool {
+	return cmd != msg.EDemoCommands_DEM_Packet && cmd != msg.EDemoCommands_DEM_FullPacket
+}
+
+/*
+newBroadcast slices the frames of a demo into fragments.
+
+The signon frames (everything up to the first packet) are served as /0/start.
+The remaining frames are split into fragments of roughly keyframeInterval ticks and at each DEM_FullPacket.
+Full packets aren't part of the deltas but are served as the keyframe (/<n>/full) of the fragment preceding them,
+so that a client starting at fragment n with /<n>/full can continue with /<n+1>/delta.
+
+Fragment 0 contains no deltas and its keyframe is empty, unless the demo starts with a full packet,
+since the first packets of a demo contain a full update anyway.
+*/
+func newBroadcast(frames []frame, keyframeInterval int) *broadcast {
+	bc := new(broadcast)
+
+	i := 0
+
+	for ; i < len(frames) && isSignon(frames[i].cmd); i++ {
+		if frames[i].cmd == msg.EDemoCommands_DEM_FileHeader {
+			var header msg.CDemoFileHeader
+
+			if proto.Unmarshal(frames[i].payload, &header) == nil {
+				bc.mapName = header.GetMapName()
+			}
+		}
+
+		bc.start = appendFrame(bc.start, frames[i])
+	}
+
+	frames = frames[i:]
+
+	firstTick := 0
+	if len(frames) > 0 {
+		firstTick = int(int32(frames[0].tick))
+	}
+
+	bc.fragments = []fragment{{tick: firstTick, full: []byte{}}}
+
+	var (
+		cur       fragment
+		startTick int
+		lastTick  = firstTick
+	)
+
+	closeFragment := func() {
+		cur.tick = lastTick
+		bc.fragments = append(bc.fragments, cur)
+		cur = fragment{}
+	}
+
+	for _, f := range frames {
+		tick := int(int32(f.tick))
+
+		if f.cmd == msg.EDemoCommands_DEM_FullPacket {
+			if len(cur.delta) > 0 {
+				closeFragment()
+			}
+
+			last := &bc.fragments[len(bc.fragments)-1]
+			last.full = appendFrame(nil, f)
+			last.tick = tick
+			lastTick = tick
+
+			continue
+		}
+
+		if len(cur.delta) == 0 {
+			startTick = tick
+		} else if tick != lastTick && tick-startTick >= keyframeInterval {
+			closeFragment()
+
+			startTick = tick
+		}
+
+		cur.delta = appendFrame(cur.delta, f)
+		lastTick = tick
+	}
+
+	cur.delta = appendFrame(cur.delta, frame{cmd: msg.EDemoCommands_DEM_Stop, tick: uint32(lastTick)})
+
+	closeFragment()
+
+	return bc
+}
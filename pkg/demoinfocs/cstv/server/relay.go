@@ -0,0 +1,568 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/markus-wa/demoinfocs-golang/v5/pkg/demoinfocs/cstv"
+)
+
+// RelayOptions configures a Relay.
+type RelayOptions struct {
+	// Storage stores the received fragments.
+	// Defaults to NewMemoryStorage().
+	Storage Storage
+
+	// OriginAuth is the value of the X-Origin-Auth header that game servers must send with their fragments,
+	// i.e. the value of tv_broadcast_origin_auth. If empty all uploads are accepted,
+	// so it should be set whenever the Relay is reachable by anyone but the game servers.
+	OriginAuth string
+
+	// MaxFragmentSize is the maximum size of an uploaded fragment in bytes, larger uploads are rejected.
+	// Defaults to 64 MiB.
+	MaxFragmentSize int64
+
+	// BroadcastTimeout is how long a broadcast is kept after its last fragment has been received.
+	// Expired broadcasts, including their data in the Storage, are removed when the next fragment of any broadcast is received.
+	// Defaults to 1 hour.
+	BroadcastTimeout time.Duration
+}
+
+const (
+	defaultMaxFragmentSize  = 64 << 20
+	defaultBroadcastTimeout = time.Hour
+)
+
+// relayFragment is the metadata of a received fragment, the data itself is kept in the Storage.
+type relayFragment struct {
+	tick     int
+	endTick  int
+	full     bool
+	delta    bool
+	final    bool
+	received time.Time
+}
+
+// relayBroadcast is the metadata of a broadcast received by a Relay.
+type relayBroadcast struct {
+	started          bool
+	signupFragment   int
+	tps              int
+	keyframeInterval int
+	mapName          string
+	protocol         int
+	fragments        map[int]*relayFragment
+	latest           int
+	received         time.Time
+}
+
+func (bc *relayBroadcast) isReady(fragment int) bool {
+	f := bc.fragments[fragment]
+
+	return f != nil && f.full && f.delta
+}
+
+/*
+Relay receives CSTV broadcasts from game servers and serves them to clients like a CSTV relay, e.g. to cstv.Reader.
+
+Game servers upload fragments via POST to <tv_broadcast_url>/<token>/<fragment>/<start|full|delta>,
+so tv_broadcast_url must be set to the URL at which the Relay is served.
+Clients can then consume the broadcast at <url>/<token>:
+
+	/<token>/sync              - JSON info about the broadcast (see cstv.SyncInfo), 'fragment' is the latest fragment that has been received completely
+	                             or the first one at or after the 'fragment' query parameter, if given
+	/<token>/<n>/start         - signon data, only for the signup fragment
+	/<token>/<n>/full          - keyframe of fragment n, 404 if it hasn't been received (yet)
+	/<token>/<n>/delta         - frames of fragment n, 404 if it hasn't been received (yet)
+
+Uploads of keyframes & deltas are answered with 205 (Reset Content) until the start of the broadcast has been received,
+which makes the game server send it again.
+The metadata of broadcasts (ticks, map, etc.) is always kept in memory, only the fragments themselves are kept in the Storage.
+Broadcasts that haven't received any fragments for RelayOptions.BroadcastTimeout are removed.
+
+Broadcasts can also be read in-process via NewReader(), without going through HTTP.
+*/
+type Relay struct {
+	opts RelayOptions
+	mux  *http.ServeMux
+	now  func() time.Time
+
+	mutex      sync.Mutex
+	broadcasts map[string]*relayBroadcast
+	changed    chan struct{} // closed and replaced whenever a fragment is received
+
+	// uploads is held for reading while a fragment is stored & registered and for writing while expired broadcasts are deleted,
+	// so the deletion can't remove fragments of a broadcast that is restarted with the same token at the same time
+	uploads sync.RWMutex
+}
+
+// NewRelay creates a new Relay, see Relay.
+func NewRelay(opts RelayOptions) *Relay {
+	if opts.Storage == nil {
+		opts.Storage = NewMemoryStorage()
+	}
+
+	if opts.MaxFragmentSize <= 0 {
+		opts.MaxFragmentSize = defaultMaxFragmentSize
+	}
+
+	if opts.BroadcastTimeout <= 0 {
+		opts.BroadcastTimeout = defaultBroadcastTimeout
+	}
+
+	r := &Relay{
+		opts:       opts,
+		now:        time.Now,
+		broadcasts: make(map[string]*relayBroadcast),
+		changed:    make(chan struct{}),
+	}
+
+	r.mux = http.NewServeMux()
+	r.mux.HandleFunc("POST /{token}/{fragment}/{field}", r.handlePost)
+	r.mux.HandleFunc("GET /{token}/sync", r.handleSync)
+	r.mux.HandleFunc("GET /{token}/{fragment}/{field}", r.handleGet)
+
+	return r
+}
+
+// ServeHTTP implements http.Handler.
+func (r *Relay) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mux.ServeHTTP(w, req)
+}
+
+func isValidToken(token string) bool {
+	if token == "" {
+		return false
+	}
+
+	for _, c := range token {
+		isValid := c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_'
+		if !isValid {
+			return false
+		}
+	}
+
+	return true
+}
+
+// fragmentFromPath returns the token, fragment & field of a request, writes an error response if they're invalid.
+func fragmentFromPath(w http.ResponseWriter, req *http.Request) (token string, fragment int, field string, ok bool) {
+	token = req.PathValue("token")
+	if !isValidToken(token) {
+		http.Error(w, "invalid token", http.StatusBadRequest)
+
+		return "", 0, "", false
+	}
+
+	fragment, err := strconv.Atoi(req.PathValue("fragment"))
+	if err != nil || fragment < 0 {
+		http.Error(w, "invalid fragment", http.StatusBadRequest)
+
+		return "", 0, "", false
+	}
+
+	field = req.PathValue("field")
+	if field != "start" && field != "full" && field != "delta" {
+		http.Error(w, "invalid field, must be start, full or delta", http.StatusBadRequest)
+
+		return "", 0, "", false
+	}
+
+	return token, fragment, field, true
+}
+
+// queryInt returns the query parameter with the given key as int, or 0.
+func queryInt(req *http.Request, key string) int {
+	n, _ := strconv.Atoi(req.URL.Query().Get(key))
+
+	return n
+}
+
+// queryBool returns whether the query parameter with the given key is set and not false, e.g. "?final" or "?final=1".
+func queryBool(req *http.Request, key string) bool {
+	if !req.URL.Query().Has(key) {
+		return false
+	}
+
+	v := req.URL.Query().Get(key)
+	b, err := strconv.ParseBool(v)
+
+	return v == "" || err != nil || b
+}
+
+func (r *Relay) handlePost(w http.ResponseWriter, req *http.Request) {
+	if r.opts.OriginAuth != "" && !isAuthorized(req.Header.Get("X-Origin-Auth"), r.opts.OriginAuth) {
+		http.Error(w, "not authorized", http.StatusForbidden)
+
+		return
+	}
+
+	token, fragment, field, ok := fragmentFromPath(w, req)
+	if !ok {
+		return
+	}
+
+	b, err := io.ReadAll(http.MaxBytesReader(w, req.Body, r.opts.MaxFragmentSize))
+
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		http.Error(w, fmt.Sprintf("fragment exceeds %d bytes", maxBytesErr.Limit), http.StatusRequestEntityTooLarge)
+
+		return
+	}
+
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to read fragment: %v", err), http.StatusBadRequest)
+
+		return
+	}
+
+	r.evictExpired()
+
+	r.uploads.RLock()
+	defer r.uploads.RUnlock()
+
+	err = r.opts.Storage.Put(token, fragment, field, b)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
+	if !r.received(token, fragment, field, req) {
+		// makes the game server send the start again
+		w.WriteHeader(http.StatusResetContent)
+
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// isAuthorized compares the X-Origin-Auth header in constant time, so the secret can't be guessed via response times.
+func isAuthorized(header, originAuth string) bool {
+	return subtle.ConstantTimeCompare([]byte(header), []byte(originAuth)) == 1
+}
+
+// evictExpired removes all broadcasts that haven't received any fragments for RelayOptions.BroadcastTimeout.
+func (r *Relay) evictExpired() {
+	if len(r.expired(false)) == 0 {
+		return
+	}
+
+	// wait for uploads in progress, they may restart an expired broadcast
+	r.uploads.Lock()
+	defer r.uploads.Unlock()
+
+	// errors are ignored, the next upload shouldn't fail because of an old broadcast
+	for _, token := range r.expired(true) {
+		_ = r.opts.Storage.Delete(token)
+	}
+}
+
+// expired returns the tokens of all expired broadcasts and optionally removes their metadata.
+func (r *Relay) expired(remove bool) []string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	var tokens []string
+
+	now := r.now()
+
+	for token, bc := range r.broadcasts {
+		if now.Sub(bc.received) > r.opts.BroadcastTimeout {
+			tokens = append(tokens, token)
+
+			if remove {
+				delete(r.broadcasts, token)
+			}
+		}
+	}
+
+	return tokens
+}
+
+// received updates the metadata of a broadcast after a fragment has been stored.
+// Returns false if the start of the broadcast hasn't been received yet.
+func (r *Relay) received(token string, fragment int, field string, req *http.Request) bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	defer func() {
+		close(r.changed)
+		r.changed = make(chan struct{})
+	}()
+
+	now := r.now()
+
+	bc := r.broadcasts[token]
+	if bc == nil {
+		bc = &relayBroadcast{
+			fragments: make(map[int]*relayFragment),
+		}
+
+		r.broadcasts[token] = bc
+	}
+
+	bc.received = now
+
+	if field == "start" {
+		bc.started = true
+		bc.signupFragment = fragment
+		bc.tps = queryInt(req, "tps")
+		bc.keyframeInterval = queryInt(req, "keyframe_interval")
+		bc.mapName = req.URL.Query().Get("map")
+		bc.protocol = queryInt(req, "protocol")
+
+		if bc.protocol == 0 {
+			bc.protocol = broadcastProtocol
+		}
+
+		return true
+	}
+
+	f := bc.fragments[fragment]
+	if f == nil {
+		f = new(relayFragment)
+		bc.fragments[fragment] = f
+	}
+
+	f.received = now
+
+	switch field {
+	case "full":
+		f.full = true
+		f.tick = queryInt(req, "tick")
+
+	case "delta":
+		f.delta = true
+		f.endTick = queryInt(req, "endtick")
+		f.final = queryBool(req, "final")
+	}
+
+	bc.latest = max(bc.latest, fragment)
+
+	return bc.started
+}
+
+func (r *Relay) handleSync(w http.ResponseWriter, req *http.Request) {
+	token := req.PathValue("token")
+
+	r.mutex.Lock()
+
+	bc := r.broadcasts[token]
+	if bc == nil || !bc.started {
+		r.mutex.Unlock()
+
+		http.Error(w, "broadcast has not started yet", http.StatusNotFound)
+
+		return
+	}
+
+	fragment := -1
+
+	if req.URL.Query().Has("fragment") {
+		for i := max(queryInt(req, "fragment"), bc.signupFragment); i <= bc.latest; i++ {
+			if bc.isReady(i) {
+				fragment = i
+
+				break
+			}
+		}
+	} else {
+		for i := bc.latest; i >= bc.signupFragment; i-- {
+			if bc.isReady(i) {
+				fragment = i
+
+				break
+			}
+		}
+	}
+
+	if fragment < 0 {
+		r.mutex.Unlock()
+
+		http.Error(w, "fragment not found, please check back soon", http.StatusNotFound)
+
+		return
+	}
+
+	now := r.now()
+	f := bc.fragments[fragment]
+
+	info := cstv.SyncInfo{
+		Tick:             f.tick,
+		EndTick:          f.endTick,
+		MaxTick:          bc.fragments[bc.latest].endTick,
+		RtDelay:          now.Sub(f.received).Seconds(),
+		RcvAge:           now.Sub(bc.received).Seconds(),
+		Fragment:         fragment,
+		SignupFragment:   bc.signupFragment,
+		Tps:              bc.tps,
+		KeyframeInterval: bc.keyframeInterval,
+		Map:              bc.mapName,
+		Protocol:         bc.protocol,
+	}
+
+	r.mutex.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+
+	err := json.NewEncoder(w).Encode(info)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func (r *Relay) handleGet(w http.ResponseWriter, req *http.Request) {
+	token, fragment, field, ok := fragmentFromPath(w, req)
+	if !ok {
+		return
+	}
+
+	if field == "start" {
+		r.mutex.Lock()
+		bc := r.broadcasts[token]
+		isSignup := bc != nil && bc.started && bc.signupFragment == fragment
+		r.mutex.Unlock()
+
+		if !isSignup {
+			http.Error(w, "invalid or expired start fragment, please re-sync", http.StatusNotFound)
+
+			return
+		}
+	}
+
+	b, err := r.opts.Storage.Get(token, fragment, field)
+	if errors.Is(err, ErrFragmentNotFound) {
+		http.NotFound(w, req)
+
+		return
+	}
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
+	writeFragment(w, b)
+}
+
+// relayReader reads a broadcast from a Relay, see Relay.NewReader().
+type relayReader struct {
+	ctx      context.Context
+	relay    *Relay
+	token    string
+	fragment int // next delta to read, -1 before the start has been read
+	buf      bytes.Buffer
+	done     bool
+}
+
+/*
+NewReader returns a reader for the broadcast with the given token in the format of demoinfocs.DemoFormatCSTVBroadcast,
+starting at the signup fragment. This allows parsing a broadcast in-process without a network hop, e.g.:
+
+	cfg := demoinfocs.DefaultParserConfig
+	cfg.Format = demoinfocs.DemoFormatCSTVBroadcast
+
+	p := demoinfocs.NewParserWithConfig(relay.NewReader(ctx, token), cfg)
+
+Reads block until the next fragment has been received.
+The reader returns io.EOF after the final delta (the game server sets the 'final' query parameter) has been read,
+or the error of the context once it's done.
+*/
+func (r *Relay) NewReader(ctx context.Context, token string) io.Reader {
+	return &relayReader{
+		ctx:      ctx,
+		relay:    r,
+		token:    token,
+		fragment: -1,
+	}
+}
+
+func (rr *relayReader) Read(p []byte) (int, error) {
+	for rr.buf.Len() == 0 {
+		if rr.done {
+			return 0, io.EOF
+		}
+
+		err := rr.next()
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return rr.buf.Read(p)
+}
+
+type storageKey struct {
+	fragment int
+	field    string
+}
+
+// next waits until the next fragments are available and reads them into the buffer.
+func (rr *relayReader) next() error {
+	for {
+		keys, next, final, changed := rr.available()
+
+		if keys != nil {
+			for _, k := range keys {
+				b, err := rr.relay.opts.Storage.Get(rr.token, k.fragment, k.field)
+				if err != nil {
+					return fmt.Errorf("failed to get %s of fragment %d: %w", k.field, k.fragment, err)
+				}
+
+				rr.buf.Write(b)
+			}
+
+			rr.fragment = next
+			rr.done = final
+
+			return nil
+		}
+
+		select {
+		case <-changed:
+		case <-rr.ctx.Done():
+			return rr.ctx.Err()
+		}
+	}
+}
+
+// available returns the keys of the next fragments to read if they have been received,
+// otherwise a channel that is closed once the next fragment has been received.
+func (rr *relayReader) available() (keys []storageKey, next int, final bool, changed <-chan struct{}) {
+	rr.relay.mutex.Lock()
+	defer rr.relay.mutex.Unlock()
+
+	changed = rr.relay.changed
+
+	bc := rr.relay.broadcasts[rr.token]
+	if bc == nil || !bc.started {
+		return nil, 0, false, changed
+	}
+
+	if rr.fragment < 0 {
+		if f := bc.fragments[bc.signupFragment]; f != nil && f.full {
+			return []storageKey{{bc.signupFragment, "start"}, {bc.signupFragment, "full"}}, bc.signupFragment + 1, false, changed
+		}
+
+		return nil, 0, false, changed
+	}
+
+	if f := bc.fragments[rr.fragment]; f != nil && f.delta {
+		return []storageKey{{rr.fragment, "delta"}}, rr.fragment + 1, f.final, changed
+	}
+
+	return nil, 0, false, changed
+}
@@ -0,0 +1,269 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	demoinfocs "github.com/markus-wa/demoinfocs-golang/v5/pkg/demoinfocs"
+	"github.com/markus-wa/demoinfocs-golang/v5/pkg/demoinfocs/cstv"
+)
+
+func post(t *testing.T, h http.Handler, path string, body []byte, header http.Header) int {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(body))
+
+	for k, v := range header {
+		req.Header[k] = v
+	}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+
+	return w.Code
+}
+
+// postBroadcast uploads the fragments of the test demo the way a game server does.
+func postBroadcast(t *testing.T, h http.Handler, token string) {
+	t.Helper()
+
+	frames, err := readDemo(bytes.NewReader(testDemoBytes(t)))
+	assert.NoError(t, err)
+
+	bc := newBroadcast(frames, 64)
+
+	status := post(t, h, fmt.Sprintf("/%s/0/start?tick=0&tps=64&map=de_test&protocol=5&keyframe_interval=1", token), bc.start, nil)
+	assert.Equal(t, http.StatusOK, status)
+
+	for i, f := range bc.fragments {
+		if f.full != nil {
+			status = post(t, h, fmt.Sprintf("/%s/%d/full?tick=%d", token, i, f.tick), f.full, nil)
+			assert.Equal(t, http.StatusOK, status)
+		}
+
+		if f.delta != nil {
+			path := fmt.Sprintf("/%s/%d/delta?endtick=%d", token, i, f.tick)
+			if i == len(bc.fragments)-1 {
+				path += "&final=1"
+			}
+
+			status = post(t, h, path, f.delta, nil)
+			assert.Equal(t, http.StatusOK, status)
+		}
+	}
+}
+
+func TestRelay_Post(t *testing.T) {
+	r := NewRelay(RelayOptions{OriginAuth: "secret"})
+
+	auth := http.Header{"X-Origin-Auth": {"secret"}}
+
+	assert.Equal(t, http.StatusForbidden, post(t, r, "/tok/0/start", []byte("start"), nil))
+	assert.Equal(t, http.StatusForbidden, post(t, r, "/tok/0/start", []byte("start"), http.Header{"X-Origin-Auth": {"secre"}}))
+	assert.Equal(t, http.StatusResetContent, post(t, r, "/tok/1/full", []byte("full"), auth))
+	assert.Equal(t, http.StatusOK, post(t, r, "/tok/1/start?tps=64", []byte("start"), auth))
+	assert.Equal(t, http.StatusOK, post(t, r, "/tok/1/full", []byte("full"), auth))
+
+	assert.Equal(t, http.StatusBadRequest, post(t, r, "/t.k/1/full", nil, auth))
+	assert.Equal(t, http.StatusBadRequest, post(t, r, "/tok/x/full", nil, auth))
+	assert.Equal(t, http.StatusBadRequest, post(t, r, "/tok/1/other", nil, auth))
+
+	assert.Equal(t, "start", get(t, r, "/tok/1/start").Body.String())
+	assert.Equal(t, http.StatusNotFound, get(t, r, "/tok/0/start").Code)
+	assert.Equal(t, "full", get(t, r, "/tok/1/full").Body.String())
+	assert.Equal(t, http.StatusNotFound, get(t, r, "/tok/1/delta").Code)
+	assert.Equal(t, http.StatusNotFound, get(t, r, "/other/1/full").Code)
+
+	// fragment 1 is incomplete without delta
+	assert.Equal(t, http.StatusNotFound, get(t, r, "/tok/sync").Code)
+	assert.Equal(t, http.StatusNotFound, get(t, r, "/other/sync").Code)
+}
+
+func TestRelay_Post_MaxFragmentSize(t *testing.T) {
+	r := NewRelay(RelayOptions{MaxFragmentSize: 4})
+
+	assert.Equal(t, http.StatusOK, post(t, r, "/tok/1/start", []byte("star"), nil))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, post(t, r, "/tok/1/full", []byte("full!"), nil))
+	assert.Equal(t, http.StatusNotFound, get(t, r, "/tok/1/full").Code)
+}
+
+func TestRelay_BroadcastTimeout(t *testing.T) {
+	s := NewMemoryStorage()
+	r := NewRelay(RelayOptions{Storage: s, BroadcastTimeout: time.Minute})
+
+	now := time.Now()
+	r.now = func() time.Time { return now }
+
+	postBroadcast(t, r, "old")
+
+	now = now.Add(time.Minute)
+
+	postBroadcast(t, r, "new")
+
+	// not expired yet
+	assert.Equal(t, http.StatusOK, get(t, r, "/old/sync").Code)
+
+	now = now.Add(time.Second)
+
+	assert.Equal(t, http.StatusOK, post(t, r, "/new/1/full?tick=32", []byte("full"), nil))
+
+	assert.Equal(t, http.StatusNotFound, get(t, r, "/old/sync").Code)
+	assert.Equal(t, http.StatusNotFound, get(t, r, "/old/2/full").Code)
+	assert.Equal(t, http.StatusOK, get(t, r, "/new/sync").Code)
+
+	_, err := s.Get("old", 0, "start")
+	assert.ErrorIs(t, err, ErrFragmentNotFound)
+
+	// uploads to an expired broadcast must start over
+	assert.Equal(t, http.StatusResetContent, post(t, r, "/old/3/full?tick=128", []byte("full"), nil))
+}
+
+// blockingDeleteStorage blocks Delete() until release is closed.
+type blockingDeleteStorage struct {
+	Storage
+
+	deleting chan string
+	release  chan struct{}
+}
+
+func (s blockingDeleteStorage) Delete(token string) error {
+	s.deleting <- token
+	<-s.release
+
+	return s.Storage.Delete(token)
+}
+
+func TestRelay_BroadcastTimeout_Restart(t *testing.T) {
+	s := blockingDeleteStorage{
+		Storage:  NewMemoryStorage(),
+		deleting: make(chan string, 1),
+		release:  make(chan struct{}),
+	}
+	r := NewRelay(RelayOptions{Storage: s, BroadcastTimeout: time.Minute})
+
+	now := time.Now()
+	r.now = func() time.Time { return now }
+
+	assert.Equal(t, http.StatusOK, post(t, r, "/tok/0/start", []byte("old"), nil))
+
+	now = now.Add(2 * time.Minute)
+
+	evicted := make(chan struct{})
+
+	go func() {
+		defer close(evicted)
+
+		post(t, r, "/other/0/start", []byte("other"), nil)
+	}()
+
+	assert.Equal(t, "tok", <-s.deleting)
+
+	// the game server restarts the broadcast while the old one is being deleted
+	restarted := make(chan int)
+
+	go func() {
+		restarted <- post(t, r, "/tok/1/start", []byte("new"), nil)
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	close(s.release)
+
+	<-evicted
+	assert.Equal(t, http.StatusOK, <-restarted)
+
+	assert.Equal(t, "new", get(t, r, "/tok/1/start").Body.String())
+}
+
+func TestRelay_Sync(t *testing.T) {
+	r := NewRelay(RelayOptions{})
+
+	now := time.Now()
+	r.now = func() time.Time { return now }
+
+	postBroadcast(t, r, "tok")
+
+	now = now.Add(time.Second)
+
+	w := get(t, r, "/tok/sync")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"tick":128,"endtick":128,"maxtick":224,"rtdelay":1,"rcvage":1,"fragment":2,"signup_fragment":0,"tps":64,"keyframe_interval":1,"map":"de_test","protocol":5}`, w.Body.String())
+
+	assert.Equal(t, http.StatusOK, get(t, r, "/tok/sync?fragment=1").Code)
+	assert.Equal(t, http.StatusNotFound, get(t, r, "/tok/sync?fragment=3").Code)
+}
+
+func TestRelay_CSTVReader(t *testing.T) {
+	r := NewRelay(RelayOptions{})
+
+	postBroadcast(t, r, "tok")
+
+	// Starts at the latest complete fragment
+	assert.Equal(t, []int{-1, 128, 160, 192, 224}, parseBroadcast(t, r, "/tok", cstv.Options{}))
+}
+
+func TestRelay_NewReader(t *testing.T) {
+	r := NewRelay(RelayOptions{Storage: NewDiskStorage(t.TempDir())})
+
+	// The reader waits for the fragments
+	time.AfterFunc(10*time.Millisecond, func() {
+		postBroadcast(t, r, "tok")
+	})
+
+	cfg := demoinfocs.DefaultParserConfig
+	cfg.Format = demoinfocs.DemoFormatCSTVBroadcast
+
+	p := demoinfocs.NewParserWithConfig(r.NewReader(context.Background(), "tok"), cfg)
+	defer p.Close()
+
+	assert.Equal(t, []int{-1, 0, 32, 64, 96, 128, 160, 192, 224}, parse(t, p))
+}
+
+func TestRelay_NewReader_Cancelled(t *testing.T) {
+	r := NewRelay(RelayOptions{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+
+	_, err := r.NewReader(ctx, "tok").Read(make([]byte, 1))
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestStorage(t *testing.T) {
+	for name, s := range map[string]Storage{
+		"memory": NewMemoryStorage(),
+		"disk":   NewDiskStorage(t.TempDir()),
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := s.Get("tok", 1, "full")
+			assert.ErrorIs(t, err, ErrFragmentNotFound)
+
+			assert.NoError(t, s.Put("tok", 1, "full", []byte("a")))
+			assert.NoError(t, s.Put("tok", 1, "full", []byte("b")))
+
+			b, err := s.Get("tok", 1, "full")
+			assert.NoError(t, err)
+			assert.Equal(t, []byte("b"), b)
+
+			_, err = s.Get("tok", 1, "delta")
+			assert.ErrorIs(t, err, ErrFragmentNotFound)
+
+			assert.NoError(t, s.Put("other", 1, "full", []byte("c")))
+			assert.NoError(t, s.Delete("tok"))
+			assert.NoError(t, s.Delete("unknown"))
+
+			_, err = s.Get("tok", 1, "full")
+			assert.ErrorIs(t, err, ErrFragmentNotFound)
+
+			b, err = s.Get("other", 1, "full")
+			assert.NoError(t, err)
+			assert.Equal(t, []byte("c"), b)
+		})
+	}
+}
@@ -0,0 +1,242 @@
+// Package server provides CSTV broadcast servers.
+// Server replays a recorded demo as a broadcast, e.g. as an offline stand-in when testing code that uses
+// demoinfocs.NewCSTVBroadcastParser() or cstv.Reader.
+// Relay receives broadcasts from game servers (tv_broadcast_url) and serves them to clients.
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/markus-wa/demoinfocs-golang/v5/pkg/demoinfocs/cstv"
+)
+
+// Options configures the pacing and fragments of a Server.
+type Options struct {
+	// Speed is the playback speed relative to real-time, e.g. 2 makes fragments available twice as fast.
+	// math.Inf(1) makes all fragments available immediately.
+	// Defaults to 1 (real-time).
+	Speed float64
+
+	// TickRate is the number of ticks per second of the demo, CS2 demos are recorded with 64 ticks per second.
+	// Defaults to 64.
+	TickRate float64
+
+	// KeyframeInterval is the maximum duration of a fragment, fragments also end at each keyframe (DEM_FullPacket) of the demo.
+	// Defaults to 3s, the default of tv_broadcast.
+	KeyframeInterval time.Duration
+}
+
+// broadcastProtocol is the protocol version of CS2 broadcasts as reported by /sync.
+const broadcastProtocol = 5
+
+/*
+Server serves a demo in the format of a CSTV broadcast relay, as consumed by cstv.Reader.
+
+The demo is sliced into fragments that become available over time as if the match was live,
+starting when the Server is created:
+
+	/sync         - JSON info about the broadcast (see cstv.SyncInfo), 'fragment' is the latest fragment with a keyframe
+	                or the first one at or after the 'fragment' query parameter, if given
+	/0/start      - signon data (server info, send tables, string tables etc.)
+	/<n>/full     - keyframe of fragment n, 404 if the fragment has none or isn't available yet
+	/<n>/delta    - frames of fragment n, i.e. since fragment n-1 - 404 if it isn't available yet
+
+A client should request /sync, /<signup_fragment>/start and /<fragment>/full and then continue with /<fragment+1>/delta.
+
+The Server can be mounted under a path (e.g. a broadcast token) with http.StripPrefix().
+*/
+type Server struct {
+	opts      Options
+	broadcast *broadcast
+	mux       *http.ServeMux
+
+	mutex   sync.Mutex
+	started time.Time
+	now     func() time.Time
+}
+
+// New creates a Server that replays the given demo, the playback starts immediately.
+// Returns ErrInvalidDemo if the demo isn't a CS2 demo.
+func New(demo io.Reader, opts Options) (*Server, error) {
+	if opts.Speed == 0 {
+		opts.Speed = 1
+	}
+
+	if opts.TickRate == 0 {
+		opts.TickRate = 64
+	}
+
+	if opts.KeyframeInterval == 0 {
+		opts.KeyframeInterval = 3 * time.Second
+	}
+
+	frames, err := readDemo(demo)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Server{
+		opts:      opts,
+		broadcast: newBroadcast(frames, int(opts.KeyframeInterval.Seconds()*opts.TickRate)),
+		now:       time.Now,
+	}
+
+	s.started = s.now()
+
+	s.mux = http.NewServeMux()
+	s.mux.HandleFunc("GET /sync", s.handleSync)
+	s.mux.HandleFunc("GET /{fragment}/start", s.handleStart)
+	s.mux.HandleFunc("GET /{fragment}/full", s.handleFragment(func(f fragment) []byte { return f.full }))
+	s.mux.HandleFunc("GET /{fragment}/delta", s.handleFragment(func(f fragment) []byte { return f.delta }))
+
+	return s, nil
+}
+
+// Restart restarts the playback, i.e. only the first fragment is available again.
+func (s *Server) Restart() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.started = s.now()
+}
+
+// Fragments returns the number of fragments of the broadcast.
+func (s *Server) Fragments() int {
+	return len(s.broadcast.fragments)
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// availableAt returns the time at which the given fragment becomes available.
+func (s *Server) availableAt(started time.Time, fragment int) time.Time {
+	ticks := s.broadcast.fragments[fragment].tick - s.broadcast.fragments[0].tick
+	if math.IsInf(s.opts.Speed, 1) || ticks <= 0 {
+		return started
+	}
+
+	return started.Add(time.Duration(float64(ticks) / s.opts.TickRate / s.opts.Speed * float64(time.Second)))
+}
+
+// clock returns the start of the playback and the current time.
+func (s *Server) clock() (started, now time.Time) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.started, s.now()
+}
+
+// latestFragment returns the index of the latest available fragment.
+func (s *Server) latestFragment(started, now time.Time) int {
+	latest := 0
+
+	for i := range s.broadcast.fragments {
+		if s.availableAt(started, i).After(now) {
+			break
+		}
+
+		latest = i
+	}
+
+	return latest
+}
+
+func (s *Server) handleSync(w http.ResponseWriter, r *http.Request) {
+	started, now := s.clock()
+	latest := s.latestFragment(started, now)
+
+	keyframe := -1
+
+	if r.URL.Query().Has("fragment") {
+		n, _ := strconv.Atoi(r.URL.Query().Get("fragment"))
+
+		for i := max(n, 0); i <= latest; i++ {
+			if s.broadcast.fragments[i].full != nil {
+				keyframe = i
+
+				break
+			}
+		}
+	} else {
+		keyframe = latest
+
+		// fragment 0 always has a keyframe
+		for s.broadcast.fragments[keyframe].full == nil {
+			keyframe--
+		}
+	}
+
+	if keyframe < 0 {
+		http.Error(w, "fragment not found, please check back soon", http.StatusNotFound)
+
+		return
+	}
+
+	info := cstv.SyncInfo{
+		Tick:             s.broadcast.fragments[keyframe].tick,
+		EndTick:          s.broadcast.fragments[latest].tick,
+		MaxTick:          s.broadcast.fragments[latest].tick,
+		RtDelay:          now.Sub(s.availableAt(started, keyframe)).Seconds(),
+		RcvAge:           now.Sub(s.availableAt(started, latest)).Seconds(),
+		Fragment:         keyframe,
+		SignupFragment:   0,
+		Tps:              int(s.opts.TickRate),
+		KeyframeInterval: int(s.opts.KeyframeInterval.Seconds()),
+		Map:              s.broadcast.mapName,
+		Protocol:         broadcastProtocol,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	err := json.NewEncoder(w).Encode(info)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func (s *Server) handleStart(w http.ResponseWriter, r *http.Request) {
+	if r.PathValue("fragment") != "0" {
+		http.NotFound(w, r)
+
+		return
+	}
+
+	writeFragment(w, s.broadcast.start)
+}
+
+func (s *Server) handleFragment(data func(fragment) []byte) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		started, now := s.clock()
+
+		n, err := strconv.Atoi(r.PathValue("fragment"))
+		if err != nil || n < 0 || n > s.latestFragment(started, now) {
+			http.NotFound(w, r)
+
+			return
+		}
+
+		b := data(s.broadcast.fragments[n])
+		if b == nil {
+			http.NotFound(w, r)
+
+			return
+		}
+
+		writeFragment(w, b)
+	}
+}
+
+func writeFragment(w http.ResponseWriter, b []byte) {
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Length", strconv.Itoa(len(b)))
+
+	_, _ = w.Write(b)
+}
@@ -0,0 +1,211 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/markus-wa/demoinfocs-golang/v5/internal/demotest"
+	demoinfocs "github.com/markus-wa/demoinfocs-golang/v5/pkg/demoinfocs"
+	"github.com/markus-wa/demoinfocs-golang/v5/pkg/demoinfocs/cstv"
+	events "github.com/markus-wa/demoinfocs-golang/v5/pkg/demoinfocs/events"
+	"github.com/markus-wa/demoinfocs-golang/v5/pkg/demoinfocs/msg"
+)
+
+// testDemoBytes returns a demo with packets every 32 ticks and a keyframe at tick 128.
+// With a keyframe interval of 64 ticks this results in the fragments
+// 0 (tick 0, empty keyframe), 1 (ticks 0-32), 2 (ticks 64-96, keyframe at 128), 3 (ticks 128-160) & 4 (ticks 192-224).
+func testDemoBytes(t *testing.T) []byte {
+	t.Helper()
+
+	return demotest.New(t).
+		Signon().
+		Packets(0, 32, 64, 96).
+		FullPacket(128).
+		Packets(128, 160, 192, 224).
+		FileInfo(224, &msg.CDemoFileInfo{}).
+		Stop(224).
+		Bytes()
+}
+
+func get(t *testing.T, h http.Handler, path string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+
+	return w
+}
+
+func getSyncFragment(t *testing.T, s *Server, path string) int {
+	t.Helper()
+
+	return getSyncPath(t, s, path).Fragment
+}
+
+func getSync(t *testing.T, s *Server) cstv.SyncInfo {
+	t.Helper()
+
+	return getSyncPath(t, s, "/sync")
+}
+
+func getSyncPath(t *testing.T, s *Server, path string) cstv.SyncInfo {
+	t.Helper()
+
+	w := get(t, s, path)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var info cstv.SyncInfo
+
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &info))
+
+	return info
+}
+
+func TestNew_InvalidDemo(t *testing.T) {
+	_, err := New(strings.NewReader("HL2DEMO\x00"), Options{})
+	assert.ErrorIs(t, err, ErrInvalidDemo)
+
+	_, err = New(bytes.NewReader(testDemoBytes(t)[:100]), Options{})
+	assert.ErrorIs(t, err, ErrInvalidDemo)
+}
+
+func TestServer_Pacing(t *testing.T) {
+	s, err := New(bytes.NewReader(testDemoBytes(t)), Options{
+		Speed:            2,
+		KeyframeInterval: time.Second,
+	})
+	assert.NoError(t, err)
+
+	now := time.Now()
+	s.now = func() time.Time { return now }
+	s.Restart()
+
+	assert.Equal(t, 5, s.Fragments())
+
+	assert.Equal(t, cstv.SyncInfo{
+		Fragment:         0,
+		Tps:              64,
+		KeyframeInterval: 1,
+		Map:              "de_test",
+		Protocol:         5,
+	}, getSync(t, s))
+
+	assert.Equal(t, http.StatusOK, get(t, s, "/0/start").Code)
+	assert.Equal(t, http.StatusNotFound, get(t, s, "/1/start").Code)
+	assert.Equal(t, http.StatusOK, get(t, s, "/0/full").Code)
+	assert.Equal(t, http.StatusNotFound, get(t, s, "/1/delta").Code)
+	assert.Equal(t, http.StatusNotFound, get(t, s, "/x/delta").Code)
+
+	// 32 ticks at twice the speed
+	now = now.Add(250 * time.Millisecond)
+
+	assert.Equal(t, http.StatusOK, get(t, s, "/1/delta").Code)
+	assert.Equal(t, http.StatusNotFound, get(t, s, "/1/full").Code)
+	assert.Equal(t, http.StatusNotFound, get(t, s, "/2/delta").Code)
+
+	now = now.Add(750 * time.Millisecond)
+
+	assert.Equal(t, cstv.SyncInfo{
+		Tick:             128,
+		EndTick:          128,
+		MaxTick:          128,
+		Fragment:         2,
+		Tps:              64,
+		KeyframeInterval: 1,
+		Map:              "de_test",
+		Protocol:         5,
+	}, getSync(t, s))
+
+	assert.Equal(t, http.StatusOK, get(t, s, "/2/full").Code)
+	assert.Equal(t, http.StatusNotFound, get(t, s, "/3/delta").Code)
+
+	now = now.Add(time.Second)
+
+	assert.Equal(t, 0, getSyncFragment(t, s, "/sync?fragment=0"))
+	assert.Equal(t, 2, getSyncFragment(t, s, "/sync?fragment=1"))
+	assert.Equal(t, http.StatusNotFound, get(t, s, "/sync?fragment=3").Code)
+
+	info := getSync(t, s)
+	assert.Equal(t, 2, info.Fragment)
+	assert.Equal(t, 224, info.EndTick)
+	assert.Equal(t, 1.0, info.RtDelay)
+	assert.Equal(t, 0.25, info.RcvAge)
+
+	s.Restart()
+
+	assert.Equal(t, 0, getSync(t, s).Fragment)
+}
+
+// parseBroadcast parses the broadcast served by h at the given path and returns the ingame ticks of all TickDone events.
+func parseBroadcast(t *testing.T, h http.Handler, path string, opts cstv.Options) []int {
+	t.Helper()
+
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	opts.Backoff = cstv.ExponentialBackoff(10*time.Millisecond, 1, 10, time.Second)
+
+	cfg := demoinfocs.DefaultParserConfig
+	cfg.CSTVOptions = opts
+
+	p, err := demoinfocs.NewCSTVBroadcastParserWithConfig(srv.URL+path, cfg)
+	assert.NoError(t, err)
+
+	defer p.Close()
+
+	return parse(t, p)
+}
+
+// parse parses a broadcast and returns the ingame ticks of all TickDone events.
+func parse(t *testing.T, p demoinfocs.Parser) []int {
+	t.Helper()
+
+	var (
+		mapName string
+		ticks   []int
+	)
+
+	p.RegisterNetMessageHandler(func(m *msg.CDemoFileHeader) {
+		mapName = m.GetMapName()
+	})
+
+	p.RegisterEventHandler(func(e events.TickDone) {
+		ticks = append(ticks, e.Tick)
+	})
+
+	assert.NoError(t, p.ParseToEnd())
+	assert.Equal(t, "de_test", mapName)
+
+	return ticks
+}
+
+func TestServer_Parser(t *testing.T) {
+	s, err := New(bytes.NewReader(testDemoBytes(t)), Options{
+		Speed:            64,
+		KeyframeInterval: time.Second,
+	})
+	assert.NoError(t, err)
+
+	assert.Equal(t, []int{-1, 0, 32, 64, 96, 128, 160, 192, 224}, parseBroadcast(t, s, "", cstv.Options{}))
+}
+
+func TestServer_Parser_Keyframe(t *testing.T) {
+	s, err := New(bytes.NewReader(testDemoBytes(t)), Options{
+		Speed:            math.Inf(1),
+		KeyframeInterval: time.Second,
+	})
+	assert.NoError(t, err)
+
+	// Starts at the latest keyframe
+	assert.Equal(t, []int{-1, 128, 160, 192, 224}, parseBroadcast(t, s, "", cstv.Options{}))
+
+	assert.Equal(t, []int{-1, 0, 32, 64, 96, 128, 160, 192, 224}, parseBroadcast(t, s, "", cstv.Options{FromStart: true}))
+}
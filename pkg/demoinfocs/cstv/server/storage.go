@@ -0,0 +1,134 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+)
+
+// ErrFragmentNotFound is returned by Storage.Get() if the requested data hasn't been stored.
+var ErrFragmentNotFound = errors.New("fragment not found")
+
+// Storage stores the fragment data received by a Relay.
+// Field is "start", "full" or "delta". Implementations must be safe for concurrent use.
+// Delete removes all data of a broadcast, it's called when the broadcast expires.
+type Storage interface {
+	Put(token string, fragment int, field string, data []byte) error
+	Get(token string, fragment int, field string) ([]byte, error)
+	Delete(token string) error
+}
+
+type fragmentKey struct {
+	token    string
+	fragment int
+	field    string
+}
+
+type memoryStorage struct {
+	mutex sync.RWMutex
+	data  map[fragmentKey][]byte
+}
+
+// NewMemoryStorage returns a Storage that keeps all fragments in memory.
+func NewMemoryStorage() Storage {
+	return &memoryStorage{
+		data: make(map[fragmentKey][]byte),
+	}
+}
+
+func (s *memoryStorage) Put(token string, fragment int, field string, data []byte) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.data[fragmentKey{token, fragment, field}] = data
+
+	return nil
+}
+
+func (s *memoryStorage) Get(token string, fragment int, field string) ([]byte, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	b, ok := s.data[fragmentKey{token, fragment, field}]
+	if !ok {
+		return nil, ErrFragmentNotFound
+	}
+
+	return b, nil
+}
+
+func (s *memoryStorage) Delete(token string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for k := range s.data {
+		if k.token == token {
+			delete(s.data, k)
+		}
+	}
+
+	return nil
+}
+
+type diskStorage struct {
+	dir string
+}
+
+// NewDiskStorage returns a Storage that writes fragments to files in the given directory,
+// as <dir>/<token>/<fragment>/<field>.
+func NewDiskStorage(dir string) Storage {
+	return diskStorage{dir: dir}
+}
+
+func (s diskStorage) path(token string, fragment int, field string) string {
+	return filepath.Join(s.dir, token, strconv.Itoa(fragment), field)
+}
+
+func (s diskStorage) Put(token string, fragment int, field string, data []byte) error {
+	path := s.path(token, fragment, field)
+
+	err := os.MkdirAll(filepath.Dir(path), 0o755)
+	if err != nil {
+		return fmt.Errorf("failed to create fragment directory: %w", err)
+	}
+
+	// write to a temporary file first so readers never see partial fragments
+	tmp := path + ".tmp"
+
+	err = os.WriteFile(tmp, data, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to write fragment: %w", err)
+	}
+
+	err = os.Rename(tmp, path)
+	if err != nil {
+		return fmt.Errorf("failed to write fragment: %w", err)
+	}
+
+	return nil
+}
+
+func (s diskStorage) Get(token string, fragment int, field string) ([]byte, error) {
+	b, err := os.ReadFile(s.path(token, fragment, field))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrFragmentNotFound
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fragment: %w", err)
+	}
+
+	return b, nil
+}
+
+func (s diskStorage) Delete(token string) error {
+	err := os.RemoveAll(filepath.Join(s.dir, token))
+	if err != nil {
+		return fmt.Errorf("failed to delete broadcast: %w", err)
+	}
+
+	return nil
+}
@@ -30,7 +30,7 @@ func (p *parser) bindBomb() {
 	bomb := &p.gameState.bomb
 
 	// Track bomb when it is dropped on the ground or being held by a player
-	scC4 := p.stParser.ServerClasses().FindByName("CC4")
+	scC4 := p.serverClass("CC4", "the bomb")
 	scC4.OnEntityCreated(func(bombEntity st.Entity) {
 		bombEntity.OnPositionUpdate(func(pos r3.Vector) {
 			bomb.LastOnGroundPosition = pos
@@ -96,7 +96,7 @@ func (p *parser) bindBomb() {
 	})
 
 	// Track bomb when it has been planted
-	scPlantedC4 := p.stParser.ServerClasses().FindByName("CPlantedC4")
+	scPlantedC4 := p.serverClass("CPlantedC4", "the planted bomb")
 	scPlantedC4.OnEntityCreated(func(bombEntity st.Entity) {
 		// Player can't hold the bomb when it has been planted
 		p.gameState.bomb.Carrier = nil
@@ -235,7 +235,7 @@ func (p *parser) bindBomb() {
 }
 
 func (p *parser) bindTeamStates() {
-	p.stParser.ServerClasses().FindByName("CCSTeam").OnEntityCreated(func(entity st.Entity) {
+	p.serverClass("CCSTeam", "team states").OnEntityCreated(func(entity st.Entity) {
 		teamVal := entity.PropertyValueMust("m_szTeamname")
 		team := teamVal.String()
 
@@ -294,7 +294,7 @@ func (p *parser) bindTeamStates() {
 }
 
 func (p *parser) bindBombSites() {
-	p.stParser.ServerClasses().FindByName("CCSPlayerResource").OnEntityCreated(func(playerResource st.Entity) {
+	p.serverClass("CCSPlayerResource", "bombsite positions").OnEntityCreated(func(playerResource st.Entity) {
 		playerResource.BindProperty("m_bombsiteCenterA", &p.bombsiteA.center, st.ValTypeVector)
 		playerResource.BindProperty("m_bombsiteCenterB", &p.bombsiteB.center, st.ValTypeVector)
 	})
@@ -308,20 +308,20 @@ func (p *parser) bindBombSites() {
 	}
 
 	// CBombTarget is not available with CS2 demos created in the early days of the limited test.
-	bombTargetClass := p.stParser.ServerClasses().FindByName("CBombTarget")
+	bombTargetClass := p.serverClass("CBombTarget", "bombsite areas")
 	if bombTargetClass != nil {
 		bombTargetClass.OnEntityCreated(onBombTargetEntityCreated)
 		return
 	}
 
-	p.stParser.ServerClasses().FindByName("CBaseTrigger").OnEntityCreated(onBombTargetEntityCreated)
+	p.serverClass("CBaseTrigger", "bombsite areas").OnEntityCreated(onBombTargetEntityCreated)
 }
 
 func (p *parser) bindPlayers() {
-	p.stParser.ServerClasses().FindByName("CCSPlayerController").OnEntityCreated(func(player st.Entity) {
+	p.serverClass("CCSPlayerController", "players").OnEntityCreated(func(player st.Entity) {
 		p.bindNewPlayerController(player)
 	})
-	p.stParser.ServerClasses().FindByName("CCSPlayerPawn").OnEntityCreated(func(player st.Entity) {
+	p.serverClass("CCSPlayerPawn", "player positions, health etc.").OnEntityCreated(func(player st.Entity) {
 		p.bindNewPlayerPawn(player)
 	})
 }
@@ -625,15 +625,17 @@ func (p *parser) bindWeapons() {
 		isEquipmentClass := hasClipProp && hasIndexProp
 
 		if isEquipmentClass {
+			p.dependOnServerClass(sc, "equipment")
 			sc.OnEntityCreated(p.bindWeaponS2)
 		}
 
 		if hasThrower {
+			p.dependOnServerClass(sc, "grenade projectiles")
 			sc.OnEntityCreated(p.bindGrenadeProjectiles)
 		}
 	}
 
-	p.stParser.ServerClasses().FindByName("CInferno").OnEntityCreated(p.bindNewInferno)
+	p.serverClass("CInferno", "infernos").OnEntityCreated(p.bindNewInferno)
 }
 
 // bindGrenadeProjectiles keeps track of the location of live grenades (parser.gameState.grenadeProjectiles), actively thrown by players.
@@ -1019,7 +1021,7 @@ func (p *parser) infernoExpired(inf *common.Inferno) {
 
 //nolint:funlen
 func (p *parser) bindGameRules() {
-	gameRules := p.ServerClasses().FindByName("CCSGameRulesProxy")
+	gameRules := p.serverClass("CCSGameRulesProxy", "game rules")
 	gameRules.OnEntityCreated(func(entity st.Entity) {
 		grPrefix := func(s string) string {
 			return fmt.Sprintf("%s.%s", gameRulesPrefixS2, s)
@@ -1251,7 +1253,7 @@ func (p *parser) bindGameRules() {
 }
 
 func (p *parser) bindHostages() {
-	p.stParser.ServerClasses().FindByName("CHostage").OnEntityCreated(func(entity st.Entity) {
+	p.serverClass("CHostage", "hostages").OnEntityCreated(func(entity st.Entity) {
 		entityID := entity.ID()
 		p.gameState.hostages[entityID] = common.NewHostage(p.demoInfoProvider, entity)
 
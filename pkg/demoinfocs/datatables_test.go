@@ -1,13 +1,19 @@
 package demoinfocs
 
 import (
+	"maps"
+	"slices"
 	"testing"
 
 	"github.com/golang/geo/r3"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"google.golang.org/protobuf/proto"
 
+	"github.com/markus-wa/demoinfocs-golang/v5/internal/demotest"
+	common "github.com/markus-wa/demoinfocs-golang/v5/pkg/demoinfocs/common"
 	events "github.com/markus-wa/demoinfocs-golang/v5/pkg/demoinfocs/events"
+	"github.com/markus-wa/demoinfocs-golang/v5/pkg/demoinfocs/msg"
 	stfake "github.com/markus-wa/demoinfocs-golang/v5/pkg/demoinfocs/sendtables/fake"
 )
 
@@ -67,3 +73,188 @@ func configurePlayerEntityMock(id int, entity *stfake.Entity) {
 		destroyCallback()
 	})
 }
+
+// Server class IDs of testMatchEntities().
+const (
+	testClassC4 = iota + 1
+	testClassPlantedC4
+	testClassTeam
+	testClassPlayerResource
+	testClassBombTarget
+	testClassPlayerController
+	testClassPlayerPawn
+	testClassInferno
+	testClassGameRulesProxy
+	testClassHostage
+)
+
+// Entity indices of testMatchDemo(), controllers are at their player slot + 1.
+const (
+	testEntityController1 = iota + 1
+	testEntityController2
+	testEntityTeamT
+	testEntityTeamCT
+	testEntityGameRules
+)
+
+// testMatchEntities returns an encoder for the server classes that bindEntities() needs.
+// Only controllers, teams and the game rules have the properties required to create entities of their class.
+func testMatchEntities(t *testing.T) *demotest.Entities {
+	t.Helper()
+
+	return demotest.NewEntities(t,
+		&demotest.Class{ID: testClassC4, Name: "CC4"},
+		&demotest.Class{ID: testClassPlantedC4, Name: "CPlantedC4"},
+		&demotest.Class{ID: testClassTeam, Name: "CCSTeam", Fields: []demotest.Field{
+			{Name: "m_iTeamNum", Type: "uint8"},
+			{Name: "m_iScore", Type: "int32"},
+			{Name: "m_szTeamname", Type: "char[129]"},
+			{Name: "m_szClanTeamname", Type: "char[129]"},
+		}},
+		&demotest.Class{ID: testClassPlayerResource, Name: "CCSPlayerResource"},
+		&demotest.Class{ID: testClassBombTarget, Name: "CBombTarget"},
+		&demotest.Class{ID: testClassPlayerController, Name: "CCSPlayerController", Fields: []demotest.Field{
+			{Name: "m_iTeamNum", Type: "uint8"},
+			{Name: "m_iConnected", Type: "PlayerConnectedState"},
+			{Name: "m_iszPlayerName", Type: "char[128]"},
+			{Name: "m_steamID", Type: "uint64"},
+		}},
+		&demotest.Class{ID: testClassPlayerPawn, Name: "CCSPlayerPawn"},
+		&demotest.Class{ID: testClassInferno, Name: "CInferno"},
+		&demotest.Class{ID: testClassGameRulesProxy, Name: "CCSGameRulesProxy", Fields: []demotest.Field{
+			{Name: "m_pGameRules", Type: "CCSGameRules*", Fields: []demotest.Field{
+				{Name: "m_bFreezePeriod", Type: "bool"},
+				{Name: "m_bWarmupPeriod", Type: "bool"},
+				{Name: "m_bHasMatchStarted", Type: "bool"},
+				{Name: "m_gamePhase", Type: "int32"},
+				{Name: "m_totalRoundsPlayed", Type: "int32"},
+				{Name: "m_nOvertimePlaying", Type: "int32"},
+				{Name: "m_iRoundTime", Type: "int32"},
+				{Name: "m_eRoundWinReason", Type: "int32"},
+				{Name: "m_bMapHasBombTarget", Type: "bool"},
+				{Name: "m_bMapHasRescueZone", Type: "bool"},
+			}},
+		}},
+		&demotest.Class{ID: testClassHostage, Name: "CHostage"},
+	)
+}
+
+var testMatchEntityClasses = map[int32]int32{
+	testEntityController1: testClassPlayerController,
+	testEntityController2: testClassPlayerController,
+	testEntityTeamT:       testClassTeam,
+	testEntityTeamCT:      testClassTeam,
+	testEntityGameRules:   testClassGameRulesProxy,
+}
+
+// testMatchChanges contains the property changes of testMatchDemo() by tick and entity index.
+// Round 1 starts at tick 0 and is won by CT at tick 8, round 2 starts at tick 12 and is won by T at tick 18,
+// round 3 starts at tick 22. There are no changes at keyframe ticks.
+var testMatchChanges = map[int32]map[int32]map[string]any{
+	5: {
+		testEntityController2: {"m_iTeamNum": int(common.TeamSpectators)},
+	},
+	8: {
+		testEntityGameRules: {"m_pGameRules.m_eRoundWinReason": int(events.RoundEndReasonCTWin), "m_pGameRules.m_totalRoundsPlayed": 1},
+		testEntityTeamCT:    {"m_iScore": 1},
+	},
+	12: {
+		testEntityGameRules: {"m_pGameRules.m_eRoundWinReason": int(events.RoundEndReasonStillInProgress)},
+		testEntityTeamT:     {"m_szClanTeamname": "Team Vitality"},
+	},
+	15: {
+		testEntityController2: {"m_iTeamNum": int(common.TeamTerrorists)},
+	},
+	18: {
+		testEntityGameRules: {"m_pGameRules.m_eRoundWinReason": int(events.RoundEndReasonTerroristsWin), "m_pGameRules.m_totalRoundsPlayed": 2},
+		testEntityTeamT:     {"m_iScore": 1},
+	},
+	22: {
+		testEntityGameRules: {"m_pGameRules.m_eRoundWinReason": int(events.RoundEndReasonStillInProgress)},
+	},
+	25: {
+		testEntityController1: {"m_iConnected": 8}, // disconnected
+	},
+}
+
+// testMatchDemo returns a demo with two players, ticks 0-29, keyframes at ticks 0, 10 & 20 and the rounds of testMatchChanges.
+func testMatchDemo(t *testing.T) *demotest.Demo {
+	t.Helper()
+
+	return testMatch(t, demotest.New(t))
+}
+
+// testMatch adds the frames of testMatchDemo() to a demo or broadcast.
+func testMatch(t *testing.T, d *demotest.Demo) *demotest.Demo {
+	t.Helper()
+
+	ents := testMatchEntities(t)
+	d = ents.Signon(d)
+
+	players := demotest.UserInfoTable(t, map[int]*msg.CMsgPlayerInfo{
+		0: {Name: proto.String("s1mple"), Xuid: proto.Uint64(76561198034202275), Userid: proto.Int32(2)},
+		1: {Name: proto.String("ZywOo"), Xuid: proto.Uint64(76561198113666193), Userid: proto.Int32(3)},
+	})
+
+	state := map[int32]map[string]any{
+		testEntityController1: {"m_iTeamNum": int(common.TeamCounterTerrorists), "m_iConnected": 0, "m_iszPlayerName": "s1mple", "m_steamID": 76561198034202275},
+		testEntityController2: {"m_iTeamNum": int(common.TeamTerrorists), "m_iConnected": 0, "m_iszPlayerName": "ZywOo", "m_steamID": 76561198113666193},
+		testEntityTeamT:       {"m_iTeamNum": int(common.TeamTerrorists), "m_iScore": 0, "m_szTeamname": "TERRORIST", "m_szClanTeamname": "NAVI"},
+		testEntityTeamCT:      {"m_iTeamNum": int(common.TeamCounterTerrorists), "m_iScore": 0, "m_szTeamname": "CT", "m_szClanTeamname": "FaZe"},
+		testEntityGameRules: {
+			"m_pGameRules.m_bFreezePeriod":     false,
+			"m_pGameRules.m_bWarmupPeriod":     false,
+			"m_pGameRules.m_bHasMatchStarted":  true,
+			"m_pGameRules.m_gamePhase":         int(common.GamePhaseStartGamePhase),
+			"m_pGameRules.m_totalRoundsPlayed": 0,
+			"m_pGameRules.m_nOvertimePlaying":  0,
+			"m_pGameRules.m_iRoundTime":        115,
+			"m_pGameRules.m_eRoundWinReason":   int(events.RoundEndReasonStillInProgress),
+			"m_pGameRules.m_bMapHasBombTarget": true,
+			"m_pGameRules.m_bMapHasRescueZone": false,
+		},
+	}
+
+	indices := slices.Sorted(maps.Keys(state))
+
+	for tick := int32(0); tick < 30; tick++ {
+		var updates []demotest.EntityUpdate
+
+		for _, index := range indices {
+			props := testMatchChanges[tick][index]
+			if props != nil {
+				maps.Copy(state[index], props)
+				updates = append(updates, demotest.Update(index, props))
+			}
+		}
+
+		if tick%10 != 0 {
+			d.Packet(tick, demotest.TickMsg(tick), ents.PacketEntities(true, updates...))
+
+			continue
+		}
+
+		assert.Empty(t, updates, "no changes allowed at keyframes")
+
+		creates := make([]demotest.EntityUpdate, 0, len(indices))
+
+		for _, index := range indices {
+			creates = append(creates, demotest.Create(index, testMatchEntityClasses[index], state[index]))
+		}
+
+		d.FullPacketWithTables(tick, []*msg.CDemoStringTablesTableT{players}, demotest.TickMsg(tick), ents.PacketEntities(false, creates...))
+	}
+
+	return d.Stop(30)
+}
+
+// testPlayerTeams returns the team of each participant by name.
+func testPlayerTeams(gs GameState) map[string]common.Team {
+	teams := make(map[string]common.Team)
+
+	for _, pl := range gs.Participants().All() {
+		teams[pl.Name] = pl.Team
+	}
+
+	return teams
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"compress/gzip"
 	"crypto/rand"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
@@ -495,6 +496,13 @@ func assertGolden(tb testing.TB, assertions *assert.Assertions, testCase string,
 		assertions.NoError(err, "error closing gzip writer for %q", goldenFile)
 	} else {
 		f, err := os.Open(goldenFile)
+		if errors.Is(err, os.ErrNotExist) {
+			tb.Logf("golden file %q doesn't exist, skipping golden file verification", goldenFile)
+			tb.Logf("run with -update to create it")
+
+			return
+		}
+
 		assertions.NoError(err, "error opening %q", goldenFile)
 
 		gzipReader, err := gzip.NewReader(f)
@@ -0,0 +1,98 @@
+package demoinfocs
+
+import (
+	"fmt"
+	"path"
+	"sort"
+
+	"github.com/markus-wa/demoinfocs-golang/v5/pkg/demoinfocs/events"
+	st "github.com/markus-wa/demoinfocs-golang/v5/pkg/demoinfocs/sendtables"
+)
+
+// serverClass returns the server class with the given name, or nil if it doesn't exist.
+// Records that the given game state feature depends on entities of the class, see ParserConfig.EntityClasses.
+func (p *parser) serverClass(name, feature string) st.ServerClass {
+	sc := p.stParser.ServerClasses().FindByName(name)
+	if sc != nil {
+		p.dependOnServerClass(sc, feature)
+	}
+
+	return sc
+}
+
+func (p *parser) dependOnServerClass(sc st.ServerClass, feature string) {
+	p.entityClassDependencies[sc.Name()] = feature
+}
+
+// entityClassProperties returns whether entities of the given server class should be decoded according to
+// ParserConfig.EntityClasses and which properties (nil means all).
+func (p *parser) entityClassProperties(sc st.ServerClass) (decode bool, properties []string) {
+	allProperties := false
+
+	for pattern, props := range p.config.EntityClasses {
+		match, _ := path.Match(pattern, sc.Name()) // patterns are validated in applyEntityClassFilter()
+		if !match {
+			continue
+		}
+
+		decode = true
+
+		if props == nil {
+			allProperties = true
+		} else {
+			properties = append(properties, props...)
+		}
+	}
+
+	if !decode {
+		return false, nil
+	}
+
+	// The game state needs all properties of the classes it depends on
+	if _, required := p.entityClassDependencies[sc.Name()]; required || allProperties {
+		return true, nil
+	}
+
+	return true, properties
+}
+
+// applyEntityClassFilter passes ParserConfig.EntityClasses on to the sendtables parser
+// and warns about game state features that won't be available because of it.
+func (p *parser) applyEntityClassFilter() {
+	if p.config.EntityClasses == nil {
+		return
+	}
+
+	for pattern := range p.config.EntityClasses {
+		_, err := path.Match(pattern, "")
+		if err != nil {
+			p.setError(fmt.Errorf("invalid server class pattern %q in ParserConfig.EntityClasses: %w", pattern, err))
+
+			return
+		}
+	}
+
+	p.stParser.SetEntityFilter(p.entityClassProperties)
+
+	skipped := make([]string, 0)
+
+	for _, sc := range p.stParser.ServerClasses().All() {
+		if _, required := p.entityClassDependencies[sc.Name()]; !required {
+			continue
+		}
+
+		if decode, _ := p.entityClassProperties(sc); !decode {
+			skipped = append(skipped, sc.Name())
+		}
+	}
+
+	sort.Strings(skipped)
+
+	for _, name := range skipped {
+		p.eventDispatcher.Dispatch(events.ParserWarn{
+			Message: fmt.Sprintf("entities of server class %s are skipped because of ParserConfig.EntityClasses, %s won't be available",
+				name, p.entityClassDependencies[name]),
+			Type: events.WarnTypeEntityClassSkipped,
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package demoinfocs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/markus-wa/demoinfocs-golang/v5/pkg/demoinfocs/events"
+	"github.com/markus-wa/demoinfocs-golang/v5/pkg/demoinfocs/msg"
+	"github.com/markus-wa/demoinfocs-golang/v5/pkg/demoinfocs/sendtables/sendtablescs2"
+)
+
+func newEntityFilterTestParser(t *testing.T, classes map[string][]string) *parser {
+	t.Helper()
+
+	p := newParserWithoutInput(ParserConfig{
+		MsgQueueBufferSize: -1,
+		EntityClasses:      classes,
+	})
+	p.stParser = sendtablescs2.NewParser(nil)
+
+	var info msg.CDemoClassInfo
+
+	for i, name := range []string{"CCSPlayerPawn", "CCSPlayerController", "CCSTeam", "CWeaponAK47", "CWeaponM4A1", "CChicken"} {
+		info.Classes = append(info.Classes, &msg.CDemoClassInfoClassT{
+			ClassId:     proto.Int32(int32(i)),
+			NetworkName: proto.String(name),
+		})
+	}
+
+	assert.NoError(t, p.stParser.OnDemoClassInfo(&info))
+
+	// Usually done by bindEntities()
+	p.serverClass("CCSPlayerPawn", "player positions")
+	p.serverClass("CCSPlayerController", "players")
+	p.serverClass("CCSTeam", "team states")
+	p.serverClass("CWeaponAK47", "equipment")
+	p.serverClass("CWeaponM4A1", "equipment")
+
+	return p
+}
+
+func TestParser_EntityClassProperties(t *testing.T) {
+	p := newEntityFilterTestParser(t, map[string][]string{
+		"CCSPlayerPawn": {"m_iHealth"},
+		"CWeapon*":      nil,
+		"CChicken":      {"m_vecOrigin"},
+		"C*Chicken":     {"m_iHealth"},
+	})
+
+	classes := p.stParser.ServerClasses()
+
+	decode, props := p.entityClassProperties(classes.FindByName("CCSPlayerPawn"))
+	assert.True(t, decode)
+	assert.Nil(t, props, "classes the game state depends on should be decoded completely")
+
+	decode, _ = p.entityClassProperties(classes.FindByName("CCSTeam"))
+	assert.False(t, decode)
+
+	decode, props = p.entityClassProperties(classes.FindByName("CWeaponM4A1"))
+	assert.True(t, decode)
+	assert.Nil(t, props)
+
+	decode, props = p.entityClassProperties(classes.FindByName("CChicken"))
+	assert.True(t, decode)
+	assert.ElementsMatch(t, []string{"m_vecOrigin", "m_iHealth"}, props)
+}
+
+func TestParser_ApplyEntityClassFilter_Warnings(t *testing.T) {
+	p := newEntityFilterTestParser(t, map[string][]string{
+		"CCSPlayer*": nil,
+	})
+
+	var warns []events.ParserWarn
+
+	p.RegisterEventHandler(func(e events.ParserWarn) {
+		warns = append(warns, e)
+	})
+
+	p.applyEntityClassFilter()
+
+	assert.NoError(t, p.error())
+	assert.Len(t, warns, 3)
+
+	for _, w := range warns {
+		assert.Equal(t, events.WarnType(events.WarnTypeEntityClassSkipped), w.Type)
+	}
+
+	assert.Contains(t, warns[0].Message, "CCSTeam")
+	assert.Contains(t, warns[0].Message, "team states")
+	assert.Contains(t, warns[1].Message, "CWeaponAK47")
+	assert.Contains(t, warns[2].Message, "CWeaponM4A1")
+}
+
+func TestParser_ApplyEntityClassFilter_InvalidPattern(t *testing.T) {
+	p := newEntityFilterTestParser(t, map[string][]string{
+		"CWeapon[": nil,
+	})
+
+	p.applyEntityClassFilter()
+
+	assert.Error(t, p.error())
+}
+
+func TestParser_ApplyEntityClassFilter_Disabled(t *testing.T) {
+	p := newEntityFilterTestParser(t, nil)
+
+	warns := 0
+
+	p.RegisterEventHandler(func(events.ParserWarn) {
+		warns++
+	})
+
+	p.applyEntityClassFilter()
+
+	assert.Zero(t, warns)
+}
@@ -0,0 +1,70 @@
+package demoinfocs
+
+import (
+	"time"
+
+	dp "github.com/markus-wa/godispatch"
+
+	"github.com/markus-wa/demoinfocs-golang/v5/pkg/demoinfocs/events"
+)
+
+// eventOrigin is the position in the demo at which an event originated, see events.Envelope.
+type eventOrigin struct {
+	ingameTick int
+	frame      int
+	round      int
+	time       time.Duration
+}
+
+func (p *parser) currentEventOrigin() eventOrigin {
+	return eventOrigin{
+		ingameTick: p.gameState.ingameTick,
+		frame:      p.currentFrame,
+		round:      p.gameState.totalRoundsPlayed + 1,
+		time:       p.CurrentTime(),
+	}
+}
+
+// withEventOrigin calls f with the origin of dispatched events set to the given one, used for delayed game events.
+func (p *parser) withEventOrigin(origin eventOrigin, f func()) {
+	previous := p.delayedEventOrigin
+	p.delayedEventOrigin = &origin
+
+	defer func() {
+		p.delayedEventOrigin = previous
+	}()
+
+	f()
+}
+
+func (p *parser) envelope(event any) events.Envelope {
+	origin := p.currentEventOrigin()
+	if p.delayedEventOrigin != nil {
+		origin = *p.delayedEventOrigin
+	}
+
+	return events.Envelope{
+		Event:      event,
+		IngameTick: origin.ingameTick,
+		Frame:      origin.frame,
+		Round:      origin.round,
+		Time:       origin.time,
+	}
+}
+
+/*
+RegisterEnvelopeHandler registers a handler that receives all game events wrapped in an events.Envelope,
+which contains the ingame tick, frame, round & time at which the event originated.
+
+Unlike calling GameState().IngameTick() etc. from within a handler,
+this also returns the correct values for game events that are delayed until the end of the frame.
+
+The handler has priority 0, see RegisterEventHandlerWithPriority() for the order in which handlers are called.
+
+Returns an identifier with which the handler can be removed via UnregisterEventHandler().
+*/
+func (p *parser) RegisterEnvelopeHandler(handler func(events.Envelope)) dp.HandlerIdentifier {
+	return p.RegisterEventHandler(func(event any) {
+		handler(p.envelope(event))
+	})
+}
@@ -0,0 +1,123 @@
+package demoinfocs
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/markus-wa/demoinfocs-golang/v5/internal/demotest"
+	common "github.com/markus-wa/demoinfocs-golang/v5/pkg/demoinfocs/common"
+	"github.com/markus-wa/demoinfocs-golang/v5/pkg/demoinfocs/events"
+	"github.com/markus-wa/demoinfocs-golang/v5/pkg/demoinfocs/msg"
+)
+
+func TestParser_RegisterEnvelopeHandler(t *testing.T) {
+	d := demotest.New(t).TickPacket(0).Packet(64, testSayTextMsg("hello")).Packet(128, testSayTextMsg("world"))
+
+	p := NewParser(bytes.NewReader(d.Stop(129).Bytes()))
+	defer p.Close()
+
+	var envelopes []events.Envelope
+
+	p.RegisterEnvelopeHandler(func(e events.Envelope) {
+		if _, ok := e.Event.(events.SayText); ok {
+			envelopes = append(envelopes, e)
+		}
+	})
+
+	assert.NoError(t, p.ParseToEnd())
+
+	// Synthetic demos don't contain the tick-rate, so the time is always 0
+	assert.Equal(t, []events.Envelope{{
+		Event:      events.SayText{EntIdx: -1, Text: "hello"},
+		IngameTick: 64,
+		Frame:      2,
+		Round:      1,
+	}, {
+		Event:      events.SayText{EntIdx: -1, Text: "world"},
+		IngameTick: 128,
+		Frame:      3,
+		Round:      1,
+	}}, envelopes)
+}
+
+func TestParser_RegisterEnvelopeHandler_DelayedEvent(t *testing.T) {
+	p := newParserWithoutInput(ParserConfig{})
+	p.tickInterval = 1.0 / 64
+
+	pl := &common.Player{Name: "s1mple", UserID: 2}
+	p.gameState.playersByUserID[2] = pl
+
+	p.gameEventDescs = map[int32]*msg.CMsgSource1LegacyGameEventListDescriptorT{
+		1: gameEventDescriptor("item_pickup", "userid", "item"),
+	}
+
+	var envelopes []events.Envelope
+
+	p.RegisterEnvelopeHandler(func(e events.Envelope) {
+		if _, ok := e.Event.(events.GenericGameEvent); !ok {
+			envelopes = append(envelopes, e)
+		}
+	})
+
+	p.gameState.ingameTick = 64
+	p.gameState.totalRoundsPlayed = 3
+
+	// item_pickup is delayed until the end of the frame
+	p.handleGameEvent(gameEvent(1,
+		&msg.CMsgSource1LegacyGameEventKeyT{ValShort: proto.Int32(2)},
+		&msg.CMsgSource1LegacyGameEventKeyT{ValString: proto.String("ak47")},
+	))
+
+	assert.Empty(t, envelopes)
+
+	// E.g. the round ended and the tick changed before the delayed event was dispatched
+	p.gameState.ingameTick = 96
+	p.gameState.totalRoundsPlayed = 4
+
+	p.handleFrameParsed(frameParsedToken)
+
+	assert.Len(t, envelopes, 2)
+
+	pickup, ok := envelopes[0].Event.(events.ItemPickup)
+	assert.True(t, ok)
+	assert.Same(t, pl, pickup.Player)
+	assert.Equal(t, common.EqAK47, pickup.Weapon.Type)
+
+	envelopes[0].Event = nil
+
+	assert.Equal(t, events.Envelope{
+		IngameTick: 64,
+		Round:      4,
+		Time:       time.Second,
+	}, envelopes[0])
+	assert.Equal(t, events.Envelope{
+		Event:      events.FrameDone{},
+		IngameTick: 96,
+		Frame:      1,
+		Round:      5,
+		Time:       1500 * time.Millisecond,
+	}, envelopes[1])
+}
+
+func gameEventDescriptor(name string, keys ...string) *msg.CMsgSource1LegacyGameEventListDescriptorT {
+	desc := &msg.CMsgSource1LegacyGameEventListDescriptorT{
+		Name: proto.String(name),
+	}
+
+	for _, k := range keys {
+		desc.Keys = append(desc.Keys, &msg.CMsgSource1LegacyGameEventListKeyT{Name: proto.String(k)})
+	}
+
+	return desc
+}
+
+func gameEvent(id int32, keys ...*msg.CMsgSource1LegacyGameEventKeyT) *msg.CMsgSource1LegacyGameEvent {
+	return &msg.CMsgSource1LegacyGameEvent{
+		Eventid: proto.Int32(id),
+		Keys:    keys,
+	}
+}
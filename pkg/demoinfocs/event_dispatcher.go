@@ -0,0 +1,165 @@
+package demoinfocs
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"sync"
+
+	dp "github.com/markus-wa/godispatch"
+)
+
+// eventHandler is a handler registered via Parser.RegisterEventHandlerWithPriority().
+type eventHandler struct {
+	identifier dp.HandlerIdentifier
+	priority   int
+	eventType  reflect.Type // Parameter type of the handler
+	fn         reflect.Value
+}
+
+// eventDispatcher dispatches game events synchronously to handlers in a deterministic order:
+// by descending priority, then in the order of registration.
+// godispatch's Dispatcher orders handlers by map iteration, which is random.
+type eventDispatcher struct {
+	lock     sync.Mutex
+	handlers []eventHandler                   // Sorted in dispatch order, replaced on (un-)registration so dispatching isn't affected
+	cache    map[reflect.Type][]reflect.Value // Handlers per event type, reset on (un-)registration
+}
+
+func newEventDispatcher() *eventDispatcher {
+	return new(eventDispatcher)
+}
+
+// Dispatch calls all handlers that accept the given event, see Parser.RegisterEventHandlerWithPriority().
+// Panics in handlers are re-panicked as dp.ConsumerCodePanic.
+func (d *eventDispatcher) Dispatch(event any) {
+	d.lock.Lock()
+
+	t := reflect.TypeOf(event)
+
+	handlers, ok := d.cache[t]
+	if !ok {
+		handlers = d.handlersFor(t)
+	}
+
+	// Handlers may (un-)register handlers
+	d.lock.Unlock()
+
+	args := []reflect.Value{reflect.ValueOf(event)}
+
+	for _, h := range handlers {
+		callEventHandler(h, args)
+	}
+}
+
+// hasHandlersOfType returns true if at least one handler's parameter is exactly of the given type,
+// i.e. ignoring handlers of interfaces like func(any) that accept all events.
+func (d *eventDispatcher) hasHandlersOfType(t reflect.Type) bool {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	return slices.ContainsFunc(d.handlers, func(h eventHandler) bool {
+		return h.eventType == t
+	})
+}
+
+// handlersFor returns the handlers for an event type in dispatch order and caches them, the caller must hold the lock.
+func (d *eventDispatcher) handlersFor(t reflect.Type) []reflect.Value {
+	var handlers []reflect.Value
+
+	for _, h := range d.handlers {
+		if t.AssignableTo(h.eventType) {
+			handlers = append(handlers, h.fn)
+		}
+	}
+
+	if d.cache == nil {
+		d.cache = make(map[reflect.Type][]reflect.Value)
+	}
+
+	d.cache[t] = handlers
+
+	return handlers
+}
+
+func callEventHandler(h reflect.Value, args []reflect.Value) {
+	defer func() {
+		r := recover()
+		if r != nil {
+			panic(eventHandlerPanic{value: r})
+		}
+	}()
+
+	h.Call(args)
+}
+
+// eventHandlerPanic is a panic in an event handler, see recoverFromUnexpectedEOF().
+type eventHandlerPanic struct {
+	value any
+}
+
+func (p eventHandlerPanic) String() string {
+	return fmt.Sprint(p.value)
+}
+
+func (p eventHandlerPanic) Value() any {
+	return p.value
+}
+
+// RegisterHandler registers a handler with priority 0.
+func (d *eventDispatcher) RegisterHandler(handler any) dp.HandlerIdentifier {
+	return d.RegisterHandlerWithPriority(handler, 0)
+}
+
+// RegisterHandlerWithPriority registers a handler that is called before all handlers with a lower priority
+// and after all handlers that have a higher or the same priority and were registered earlier.
+// Panics if the handler isn't a func with a single parameter, like dp.Dispatcher.
+func (d *eventDispatcher) RegisterHandlerWithPriority(handler any, priority int) dp.HandlerIdentifier {
+	fn := reflect.ValueOf(handler)
+
+	if fn.Kind() != reflect.Func {
+		panic("Handler isn't a function")
+	} else if fn.Type().NumIn() != 1 {
+		panic("Handler function has more than one input parameter")
+	}
+
+	h := eventHandler{
+		identifier: new(int),
+		priority:   priority,
+		eventType:  fn.Type().In(0),
+		fn:         fn,
+	}
+
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	i := slices.IndexFunc(d.handlers, func(other eventHandler) bool {
+		return other.priority < priority
+	})
+	if i < 0 {
+		i = len(d.handlers)
+	}
+
+	d.handlers = slices.Insert(slices.Clip(d.handlers), i, h)
+	d.cache = nil
+
+	return h.identifier
+}
+
+func (d *eventDispatcher) UnregisterHandler(identifier dp.HandlerIdentifier) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	d.handlers = slices.DeleteFunc(slices.Clone(d.handlers), func(h eventHandler) bool {
+		return h.identifier == identifier
+	})
+	d.cache = nil
+}
+
+func (d *eventDispatcher) UnregisterAllHandlers() {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	d.handlers = nil
+	d.cache = nil
+}
@@ -0,0 +1,169 @@
+package demoinfocs
+
+import (
+	"fmt"
+	"testing"
+
+	dp "github.com/markus-wa/godispatch"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/markus-wa/demoinfocs-golang/v5/pkg/demoinfocs/events"
+)
+
+func TestEventDispatcher_Order(t *testing.T) {
+	d := newEventDispatcher()
+
+	var calls []string
+
+	record := func(name string) func(any) {
+		return func(any) {
+			calls = append(calls, name)
+		}
+	}
+
+	d.RegisterHandlerWithPriority(record("low"), -1)
+	d.RegisterHandler(func(events.Kill) {
+		calls = append(calls, "default kill")
+	})
+	d.RegisterHandlerWithPriority(record("high"), 10)
+	d.RegisterHandler(record("default any"))
+	d.RegisterHandlerWithPriority(func(events.Kill) {
+		calls = append(calls, "high kill")
+	}, 10)
+
+	// Enough handlers with the same priority that map ordering would show
+	var expectedSamePriority []string
+
+	for i := 0; i < 20; i++ {
+		name := fmt.Sprintf("same %d", i)
+		expectedSamePriority = append(expectedSamePriority, name)
+		d.RegisterHandlerWithPriority(record(name), 5)
+	}
+
+	d.Dispatch(events.Kill{})
+
+	expected := append([]string{"high", "high kill"}, expectedSamePriority...)
+	expected = append(expected, "default kill", "default any", "low")
+
+	assert.Equal(t, expected, calls)
+
+	calls = nil
+
+	d.Dispatch(events.FrameDone{})
+
+	assert.Equal(t, append(append([]string{"high"}, expectedSamePriority...), "default any", "low"), calls)
+}
+
+func TestEventDispatcher_Unregister(t *testing.T) {
+	d := newEventDispatcher()
+
+	var (
+		calls       []int
+		identifiers []dp.HandlerIdentifier
+	)
+
+	for i := 0; i < 3; i++ {
+		identifiers = append(identifiers, d.RegisterHandler(func(events.Kill) {
+			calls = append(calls, i)
+		}))
+	}
+
+	d.Dispatch(events.Kill{})
+	d.UnregisterHandler(identifiers[1])
+	d.Dispatch(events.Kill{})
+	d.UnregisterAllHandlers()
+	d.Dispatch(events.Kill{})
+
+	assert.Equal(t, []int{0, 1, 2, 0, 2}, calls)
+}
+
+func TestEventDispatcher_RegisterWhileDispatching(t *testing.T) {
+	d := newEventDispatcher()
+
+	var calls []string
+
+	d.RegisterHandler(func(events.Kill) {
+		calls = append(calls, "first")
+
+		d.RegisterHandlerWithPriority(func(events.Kill) {
+			calls = append(calls, "added")
+		}, 1)
+	})
+
+	d.Dispatch(events.Kill{})
+
+	assert.Equal(t, []string{"first"}, calls)
+
+	calls = nil
+
+	d.Dispatch(events.Kill{})
+
+	assert.Equal(t, []string{"added", "first"}, calls)
+}
+
+func TestEventDispatcher_Panic(t *testing.T) {
+	d := newEventDispatcher()
+
+	d.RegisterHandler(func(events.Kill) {
+		panic("test")
+	})
+
+	assert.PanicsWithValue(t, eventHandlerPanic{value: "test"}, func() {
+		d.Dispatch(events.Kill{})
+	})
+	assert.EqualError(t, recoverFromUnexpectedEOF(eventHandlerPanic{value: "test"}), "panic in handler: test")
+
+	assert.PanicsWithValue(t, "Handler isn't a function", func() {
+		d.RegisterHandler(1)
+	})
+}
+
+func TestParser_RegisterEventHandlerWithPriority_DelayedEvents(t *testing.T) {
+	p := newParserWithoutInput(ParserConfig{Metrics: true})
+	p.disableMimicSource1GameEvents = true
+
+	var calls []string
+
+	for _, prio := range []int{-1, 1} {
+		p.RegisterEventHandlerWithPriority(func(e any) {
+			calls = append(calls, fmt.Sprintf("%T %d", e, prio))
+		}, prio)
+	}
+
+	// A delayed game event, e.g. item_pickup, followed by one that is dispatched immediately
+	p.delayedEventHandlers = append(p.delayedEventHandlers, func() {
+		p.eventDispatcher.Dispatch(events.ItemPickup{})
+	})
+	p.eventDispatcher.Dispatch(events.WeaponFire{})
+
+	p.handleFrameParsed(frameParsedToken)
+
+	assert.Equal(t, []string{
+		"events.WeaponFire 1",
+		"events.WeaponFire -1",
+		"events.ItemPickup 1",
+		"events.ItemPickup -1",
+		"events.FrameDone 1",
+		"events.FrameDone -1",
+	}, calls)
+}
+
+func TestOnWithPriority(t *testing.T) {
+	p := newParserWithoutInput(ParserConfig{})
+
+	var calls []string
+
+	On(p, func(events.Kill) {
+		calls = append(calls, "default")
+	})
+	OnWithPriority(p, func(events.Kill) {
+		calls = append(calls, "high")
+	}, 1)
+
+	p.eventDispatcher.Dispatch(events.Kill{})
+
+	assert.Equal(t, []string{"high", "default"}, calls)
+	assert.Panics(t, func() {
+		OnWithPriority(p, func(*events.Kill) {}, 1)
+	})
+}
@@ -0,0 +1,17 @@
+package events
+
+import "time"
+
+// Envelope wraps an event with information about where in the demo it originated.
+// Envelopes are only passed to handlers registered via demoinfocs.Parser.RegisterEnvelopeHandler(),
+// they aren't dispatched as events themselves.
+//
+// For game events that are delayed until the end of the frame (e.g. PlayerFlashed or ItemPickup)
+// the fields describe the state of the parser when the game event was received, not when it was dispatched.
+type Envelope struct {
+	Event      any           // The event, e.g. events.Kill
+	IngameTick int           // Ingame tick, see GameState.IngameTick()
+	Frame      int           // Demo frame, see Parser.CurrentFrame()
+	Round      int           // Number of the round, starting at 1 - see GameState.TotalRoundsPlayed()
+	Time       time.Duration // Ingame time, see Parser.CurrentTime()
+}
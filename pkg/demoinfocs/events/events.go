@@ -12,12 +12,27 @@ import (
 
 	common "github.com/markus-wa/demoinfocs-golang/v5/pkg/demoinfocs/common"
 	msg "github.com/markus-wa/demoinfocs-golang/v5/pkg/demoinfocs/msg"
+	st "github.com/markus-wa/demoinfocs-golang/v5/pkg/demoinfocs/sendtables"
 )
 
 // FrameDone signals that a demo-frame has been processed.
 // A frame can contain multiple ticks (usually 2 or 4) if the tv_snapshotrate differs from the tick-rate the game was played at.
 type FrameDone struct{}
 
+// TickDone signals that all frames of an ingame tick have been processed,
+// including entity updates and game events that are delayed until the end of a frame.
+// It's dispatched exactly once per ingame tick of the demo, before any events of the next tick (or at the end of the demo).
+// Ticks without frames (e.g. if tv_snapshotrate is lower than the tick-rate) are skipped.
+type TickDone struct {
+	Tick int           // Ingame tick, see GameState.IngameTick()
+	Time time.Duration // Ingame time of the tick, see Parser.CurrentTime()
+}
+
+// DemoStopped signals that the recording of a demo that is still being written has been stopped (DEM_Stop),
+// no more events are dispatched afterwards.
+// Only dispatched for demos parsed with demoinfocs.DemoFormatGrowingFile.
+type DemoStopped struct{}
+
 // POVRecordingPlayerDetected signals that a player started recording the demo locally.
 // If this event is dispatched, it means it's a client-side (POV) demo.
 type POVRecordingPlayerDetected struct {
@@ -596,6 +611,10 @@ const (
 	WarnTypeMissingItemDefinitionIndex
 	WarnTypeStringTableParsingFailure // Should happen only with CS2 POV demos
 	WarnTypePacketEntitiesPanic
+	WarnTypeCorruptFrameSkipped // only in ParserConfig.RecoveryMode, frames are skipped until the next full packet
+	WarnTypeEntityClassSkipped  // a server class the game state depends on is excluded by ParserConfig.EntityClasses
+	WarnTypeUnknownNetMessage   // once per unknown net-message ID, see UnknownNetMessage
+	WarnTypeCorruptUserCommand  // a user command couldn't be decoded, see PlayerInput
 )
 
 // ParserWarn signals that a non-fatal problem occurred during parsing.
@@ -611,6 +630,15 @@ type GenericGameEvent struct {
 	Data map[string]*msg.CMsgSource1LegacyGameEventKeyT
 }
 
+// UnknownNetMessage signals a net-message with an ID that the parser doesn't know about,
+// e.g. because the protobuf messages haven't been updated after a game update yet.
+// It contains the raw, protobuf encoded payload.
+// Not dispatched for IDs that have handlers registered via Parser.RegisterRawNetMessageHandler().
+type UnknownNetMessage struct {
+	ID      int32
+	Payload []byte
+}
+
 // InfernoStart signals that the fire of a incendiary or Molotov is starting.
 // This is different from the FireGrenadeStart because it's sent out when the inferno entity is created instead of on the game-event.
 type InfernoStart struct {
@@ -702,3 +730,101 @@ type TeamClanNameUpdated struct {
 	NewName   string
 	TeamState *common.TeamState
 }
+
+// BuyZoneEntered signals that a player entered a buy zone.
+// Only available in locally recorded (POV) demos, usually only for the recording player.
+type BuyZoneEntered struct {
+	Player *common.Player
+	CanBuy bool
+}
+
+// BuyZoneLeft signals that a player left a buy zone.
+// Only available in locally recorded (POV) demos, usually only for the recording player.
+type BuyZoneLeft struct {
+	Player *common.Player
+	CanBuy bool
+}
+
+// BombZoneEntered signals that a player entered a bombsite.
+// Only available in locally recorded (POV) demos, usually only for the recording player.
+type BombZoneEntered struct {
+	Player    *common.Player
+	HasBomb   bool
+	IsPlanted bool // Whether the bomb has already been planted
+}
+
+// BombZoneLeft signals that a player left a bombsite.
+// Only available in locally recorded (POV) demos, usually only for the recording player.
+type BombZoneLeft struct {
+	Player    *common.Player
+	HasBomb   bool
+	IsPlanted bool // Whether the bomb has already been planted
+}
+
+// WeaponInspect signals that a player started inspecting their weapon.
+// Only available in locally recorded (POV) demos.
+type WeaponInspect struct {
+	Player *common.Player
+	Weapon *common.Equipment // The player's active weapon, may be nil
+}
+
+// ItemPickupSlerp signals the animation of an item flying towards a player that picked it up.
+// Only available in locally recorded (POV) demos.
+type ItemPickupSlerp struct {
+	Player   *common.Player
+	Weapon   *common.Equipment // May be nil if the item isn't a weapon
+	Behavior int
+}
+
+// PlayerPing signals that a player used the ping system to mark a position.
+// Only available in locally recorded (POV) demos, only for pings that are visible to the recording player.
+type PlayerPing struct {
+	Player   *common.Player
+	Position r3.Vector
+	IsUrgent bool
+}
+
+// PlayerPingStop signals that a player's ping expired or was removed.
+// Only available in locally recorded (POV) demos.
+type PlayerPingStop struct {
+	Player *common.Player // May be nil if the corresponding PlayerPing wasn't part of the demo
+}
+
+// EntityEnteredPVS signals that an entity that has left the recording player's PVS (potentially visible set) is being updated again.
+// Only dispatched for locally recorded (POV) demos.
+type EntityEnteredPVS struct {
+	Entity st.Entity
+}
+
+// EntityLeftPVS signals that an entity left the recording player's PVS (potentially visible set).
+// Its properties (position, health etc.) aren't updated anymore and are stale until EntityEnteredPVS is dispatched.
+// Only dispatched for locally recorded (POV) demos.
+type EntityLeftPVS struct {
+	Entity st.Entity
+}
+
+// PlayerInput signals a user command of a player, i.e. the buttons, movement & view angles of one tick of their input.
+// Only available if the demo contains user commands, e.g. for the recording player of locally recorded (POV) demos.
+// User commands are only decoded if there is at least one handler of exactly this type, e.g. via demoinfocs.On(),
+// handlers that accept all events like func(any) don't receive PlayerInput otherwise.
+type PlayerInput struct {
+	Player         *common.Player // May be nil if the player isn't known (yet)
+	CommandNumber  int
+	ClientTick     int
+	Buttons        Button  // Buttons that are held down
+	ButtonsChanged Button  // Buttons that have been pressed or released since the previous command
+	ForwardMove    float32 // -1 (backwards) to 1 (forwards)
+	LeftMove       float32 // -1 (right) to 1 (left)
+	UpMove         float32
+	Pitch          float32 // View angle in degrees
+	Yaw            float32 // View angle in degrees
+	MouseDX        int
+	MouseDY        int
+	WeaponSelect   int           // Entity-ID of the weapon the player switched to, 0 if none
+	SubtickMoves   []SubtickMove // Inputs that happened between the previous and this tick, in chronological order
+}
+
+// IsPressed returns true if the given button is held down.
+func (e PlayerInput) IsPressed(b Button) bool {
+	return e.Buttons&b != 0
+}
@@ -0,0 +1,33 @@
+package events
+
+// Button is a bitmask of the buttons of a player's input, see PlayerInput.
+type Button uint64
+
+// Buttons as defined by the game (IN_* flags).
+const (
+	ButtonAttack    Button = 1 << 0
+	ButtonJump      Button = 1 << 1
+	ButtonDuck      Button = 1 << 2
+	ButtonForward   Button = 1 << 3
+	ButtonBack      Button = 1 << 4
+	ButtonUse       Button = 1 << 5
+	ButtonTurnLeft  Button = 1 << 7
+	ButtonTurnRight Button = 1 << 8
+	ButtonMoveLeft  Button = 1 << 9
+	ButtonMoveRight Button = 1 << 10
+	ButtonAttack2   Button = 1 << 11
+	ButtonReload    Button = 1 << 13
+	ButtonWalk      Button = 1 << 16 // IN_SPEED
+	ButtonScore     Button = 1 << 33 // Scoreboard
+	ButtonZoom      Button = 1 << 34
+	ButtonInspect   Button = 1 << 35 // IN_LOOK_AT_WEAPON
+)
+
+// SubtickMove is an input that happened between two ticks, either a button press / release or a change of the analog movement.
+type SubtickMove struct {
+	Button             Button  // The pressed or released button, 0 for analog movement
+	Pressed            bool    // Whether Button was pressed or released
+	When               float32 // Point in time between the previous (0) and the current tick (1)
+	AnalogForwardDelta float32
+	AnalogLeftDelta    float32
+}
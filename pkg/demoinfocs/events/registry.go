@@ -0,0 +1,139 @@
+package events
+
+import (
+	"reflect"
+)
+
+// eventTypes contains the types of all events that are dispatched by the parser, in the order of their declaration.
+// New events have to be added here, otherwise they can't be handled via demoinfocs.On().
+var eventTypes = typesOf(
+	FrameDone{},
+	TickDone{},
+	DemoStopped{},
+	POVRecordingPlayerDetected{},
+	MatchStart{},
+	RoundStart{},
+	RoundFreezetimeEnd{},
+	RoundFreezetimeChanged{},
+	RoundEnd{},
+	RoundEndOfficial{},
+	RoundMVPAnnouncement{},
+	AnnouncementMatchStarted{},
+	AnnouncementLastRoundHalf{},
+	AnnouncementFinalRound{},
+	AnnouncementWinPanelMatch{},
+	Footstep{},
+	PlayerTeamChange{},
+	PlayerJump{},
+	PlayerSound{},
+	Kill{},
+	BotTakenOver{},
+	WeaponFire{},
+	WeaponReload{},
+	HeExplode{},
+	FlashExplode{},
+	DecoyStart{},
+	DecoyExpired{},
+	SmokeStart{},
+	SmokeExpired{},
+	FireGrenadeStart{},
+	FireGrenadeExpired{},
+	GrenadeProjectileBounce{},
+	GrenadeProjectileThrow{},
+	GrenadeProjectileDestroy{},
+	PlayerFlashed{},
+	BombPlantBegin{},
+	BombPlantAborted{},
+	BombPlanted{},
+	BombDefused{},
+	BombExplode{},
+	BombDefuseStart{},
+	BombDefuseAborted{},
+	BombDropped{},
+	BombPickup{},
+	HostageRescued{},
+	HostageRescuedAll{},
+	HostageHurt{},
+	HostageKilled{},
+	HostageStateChanged{},
+	BulletDamage{},
+	PlayerHurt{},
+	PlayerConnect{},
+	BotConnect{},
+	PlayerDisconnected{},
+	PlayerNameChange{},
+	StringTablePlayerUpdateApplied{},
+	SayText{},
+	SayText2{},
+	TickRateInfoAvailable{},
+	ChatMessage{},
+	RankUpdate{},
+	OtherDeath{},
+	ItemEquip{},
+	ItemPickup{},
+	ItemDrop{},
+	DataTablesParsed{},
+	StringTableCreated{},
+	ParserWarn{},
+	GenericGameEvent{},
+	UnknownNetMessage{},
+	InfernoStart{},
+	InfernoExpired{},
+	ScoreUpdated{},
+	GamePhaseChanged{},
+	TeamSideSwitch{},
+	GameHalfEnded{},
+	MatchStartedChanged{},
+	IsWarmupPeriodChanged{},
+	PlayerSpottersChanged{},
+	ConVarsUpdated{},
+	PlayerInfo{},
+	OvertimeNumberChanged{},
+	ItemRefund{},
+	TeamClanNameUpdated{},
+	BuyZoneEntered{},
+	BuyZoneLeft{},
+	BombZoneEntered{},
+	BombZoneLeft{},
+	WeaponInspect{},
+	ItemPickupSlerp{},
+	PlayerPing{},
+	PlayerPingStop{},
+	EntityEnteredPVS{},
+	EntityLeftPVS{},
+	PlayerInput{},
+)
+
+func typesOf(events ...any) []reflect.Type {
+	types := make([]reflect.Type, len(events))
+
+	for i, e := range events {
+		types[i] = reflect.TypeOf(e)
+	}
+
+	return types
+}
+
+// Types returns the types of all events that may be dispatched by the parser.
+func Types() []reflect.Type {
+	types := make([]reflect.Type, len(eventTypes))
+	copy(types, eventTypes)
+
+	return types
+}
+
+// IsHandlerType returns true if handlers of type func(t) can receive events,
+// i.e. if t is one of Types() or an interface that is implemented by at least one of them (e.g. GrenadeEventIf or any).
+func IsHandlerType(t reflect.Type) bool {
+	if t == nil {
+		return false
+	}
+
+	for _, et := range eventTypes {
+		if et == t || (t.Kind() == reflect.Interface && et.Implements(t)) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,62 @@
+package events
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestTypes_Complete makes sure every event struct declared in events.go is part of the registry.
+func TestTypes_Complete(t *testing.T) {
+	f, err := parser.ParseFile(token.NewFileSet(), "events.go", nil, 0)
+	assert.NoError(t, err)
+
+	// Base types that are embedded in events but never dispatched themselves
+	notDispatched := map[string]bool{
+		"GrenadeEvent": true,
+		"BombEvent":    true,
+	}
+
+	var declared []string
+
+	for _, decl := range f.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.TYPE {
+			continue
+		}
+
+		for _, spec := range gen.Specs {
+			ts := spec.(*ast.TypeSpec)
+
+			if _, isStruct := ts.Type.(*ast.StructType); isStruct && ts.Name.IsExported() && !notDispatched[ts.Name.Name] {
+				declared = append(declared, ts.Name.Name)
+			}
+		}
+	}
+
+	registered := make([]string, 0, len(eventTypes))
+
+	for _, et := range Types() {
+		registered = append(registered, et.Name())
+	}
+
+	assert.Equal(t, declared, registered)
+}
+
+func TestIsHandlerType(t *testing.T) {
+	assert.True(t, IsHandlerType(reflect.TypeFor[Kill]()))
+	assert.True(t, IsHandlerType(reflect.TypeFor[FrameDone]()))
+	assert.True(t, IsHandlerType(reflect.TypeFor[GrenadeEventIf]()))
+	assert.True(t, IsHandlerType(reflect.TypeFor[BombEventIf]()))
+	assert.True(t, IsHandlerType(reflect.TypeFor[any]()))
+
+	assert.False(t, IsHandlerType(reflect.TypeFor[*Kill]()))
+	assert.False(t, IsHandlerType(reflect.TypeFor[GrenadeEvent]()))
+	assert.False(t, IsHandlerType(reflect.TypeFor[error]()))
+	assert.False(t, IsHandlerType(reflect.TypeFor[string]()))
+	assert.False(t, IsHandlerType(nil))
+}
@@ -3,6 +3,8 @@
 package fake
 
 import (
+	"context"
+	"io"
 	"time"
 
 	dp "github.com/markus-wa/godispatch"
@@ -10,6 +12,8 @@ import (
 	"golang.org/x/exp/constraints"
 
 	demoinfocs "github.com/markus-wa/demoinfocs-golang/v5/pkg/demoinfocs"
+	common "github.com/markus-wa/demoinfocs-golang/v5/pkg/demoinfocs/common"
+	events "github.com/markus-wa/demoinfocs-golang/v5/pkg/demoinfocs/events"
 	st "github.com/markus-wa/demoinfocs-golang/v5/pkg/demoinfocs/sendtables"
 )
 
@@ -120,6 +124,16 @@ func (p *Parser) Progress() float32 {
 	return p.Called().Get(0).(float32)
 }
 
+// Header is a mock-implementation of Parser.Header().
+func (p *Parser) Header() *demoinfocs.Header {
+	return p.Called().Get(0).(*demoinfocs.Header)
+}
+
+// ServerInfo is a mock-implementation of Parser.ServerInfo().
+func (p *Parser) ServerInfo() *demoinfocs.ServerInfo {
+	return p.Called().Get(0).(*demoinfocs.ServerInfo)
+}
+
 // RegisterEventHandler is a mock-implementation of Parser.RegisterEventHandler().
 // Return HandlerIdentifier cannot be mocked (for now).
 func (p *Parser) RegisterEventHandler(handler any) dp.HandlerIdentifier {
@@ -127,6 +141,13 @@ func (p *Parser) RegisterEventHandler(handler any) dp.HandlerIdentifier {
 	return p.eventDispatcher.RegisterHandler(handler)
 }
 
+// RegisterEventHandlerWithPriority is a mock-implementation of Parser.RegisterEventHandlerWithPriority().
+// Return HandlerIdentifier cannot be mocked (for now), the priority isn't applied to the order in which handlers are called.
+func (p *Parser) RegisterEventHandlerWithPriority(handler any, priority int) dp.HandlerIdentifier {
+	p.Called(priority)
+	return p.eventDispatcher.RegisterHandler(handler)
+}
+
 // UnregisterEventHandler is a mock-implementation of Parser.UnregisterEventHandler().
 func (p *Parser) UnregisterEventHandler(identifier dp.HandlerIdentifier) {
 	p.Called()
@@ -146,6 +167,18 @@ func (p *Parser) UnregisterNetMessageHandler(identifier dp.HandlerIdentifier) {
 	p.msgDispatcher.UnregisterHandler(identifier)
 }
 
+// RegisterRawNetMessageHandler is a mock-implementation of Parser.RegisterRawNetMessageHandler().
+// The handler is never called and the returned HandlerIdentifier cannot be mocked (for now).
+func (p *Parser) RegisterRawNetMessageHandler(id int32, handler func(tick int, payload []byte)) dp.HandlerIdentifier {
+	p.Called(id)
+	return dp.HandlerIdentifier(new(int))
+}
+
+// NetMessageCounts is a mock-implementation of Parser.NetMessageCounts().
+func (p *Parser) NetMessageCounts() map[int32]demoinfocs.NetMessageCount {
+	return p.Called().Get(0).(map[int32]demoinfocs.NetMessageCount)
+}
+
 // ParseToEnd is a mock-implementation of Parser.ParseToEnd().
 //
 // Dispatches Parser.Events and Parser.NetMessages in the specified order.
@@ -168,6 +201,28 @@ func (p *Parser) ParseToEnd() (err error) {
 	return args.Error(0)
 }
 
+// ParseToEndContext is a mock-implementation of Parser.ParseToEndContext().
+//
+// Dispatches Parser.Events and Parser.NetMessages in the specified order until the context is done.
+//
+// Returns the mocked error value.
+func (p *Parser) ParseToEndContext(ctx context.Context) (err error) {
+	args := p.Called(ctx)
+
+	maxFrame := maxKey(p.Events)
+	maxNetMessageFrame := maxKey(p.NetMessages)
+
+	if maxFrame < maxNetMessageFrame {
+		maxFrame = maxNetMessageFrame
+	}
+
+	for p.currentFrame <= maxFrame && ctx.Err() == nil {
+		p.parseNextFrame()
+	}
+
+	return args.Error(0)
+}
+
 func (p *Parser) parseNextFrame() {
 	events, ok := p.Events[p.currentFrame]
 	if ok {
@@ -209,6 +264,56 @@ func maxKey[T any, N constraints.Ordered](numbers map[N]T) (maxNumber N) {
 	return
 }
 
+// RecoveryStats is a mock-implementation of Parser.RecoveryStats().
+func (p *Parser) RecoveryStats() demoinfocs.RecoveryStats {
+	return p.Called().Get(0).(demoinfocs.RecoveryStats)
+}
+
+// SeekToTick is a mock-implementation of Parser.SeekToTick().
+// Does not change the mock's current frame, mock the return value instead.
+func (p *Parser) SeekToTick(tick int) error {
+	return p.Called(tick).Error(0)
+}
+
+// SeekToRound is a mock-implementation of Parser.SeekToRound().
+// Does not change the mock's current frame, mock the return value instead.
+func (p *Parser) SeekToRound(round int) error {
+	return p.Called(round).Error(0)
+}
+
+// Checkpoint is a mock-implementation of Parser.Checkpoint().
+// Nothing is written to w, mock the return value instead.
+func (p *Parser) Checkpoint(w io.Writer) error {
+	return p.Called(w).Error(0)
+}
+
+// Stats is a mock-implementation of Parser.Stats().
+func (p *Parser) Stats() demoinfocs.Stats {
+	return p.Called().Get(0).(demoinfocs.Stats)
+}
+
+// RegisterEnvelopeHandler is a mock-implementation of Parser.RegisterEnvelopeHandler().
+// Return HandlerIdentifier cannot be mocked (for now), envelopes only contain the event and the frame.
+func (p *Parser) RegisterEnvelopeHandler(handler func(events.Envelope)) dp.HandlerIdentifier {
+	p.Called()
+	return p.eventDispatcher.RegisterHandler(func(e any) {
+		handler(events.Envelope{
+			Event: e,
+			Frame: p.currentFrame,
+		})
+	})
+}
+
+// RecordingPlayer is a mock-implementation of Parser.RecordingPlayer().
+func (p *Parser) RecordingPlayer() *common.Player {
+	return p.Called().Get(0).(*common.Player)
+}
+
+// IsEntityInPVS is a mock-implementation of Parser.IsEntityInPVS().
+func (p *Parser) IsEntityInPVS(entityID int) bool {
+	return p.Called(entityID).Bool(0)
+}
+
 // Cancel is a mock-implementation of Parser.Cancel().
 // Does not cancel the mock's ParseToEnd() function,
 // mock the return value of ParseToEnd() to be ErrCancelled instead.
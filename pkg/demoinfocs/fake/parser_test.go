@@ -1,11 +1,13 @@
 package fake_test
 
 import (
+	"context"
 	"testing"
 
 	assert "github.com/stretchr/testify/assert"
 	"google.golang.org/protobuf/proto"
 
+	demoinfocs "github.com/markus-wa/demoinfocs-golang/v5/pkg/demoinfocs"
 	common "github.com/markus-wa/demoinfocs-golang/v5/pkg/demoinfocs/common"
 	events "github.com/markus-wa/demoinfocs-golang/v5/pkg/demoinfocs/events"
 	fake "github.com/markus-wa/demoinfocs-golang/v5/pkg/demoinfocs/fake"
@@ -61,6 +63,68 @@ func TestParseToEndEvents(t *testing.T) {
 	assert.Equal(t, expected, actual)
 }
 
+func TestParseToEndContextEvents(t *testing.T) {
+	p := fake.NewParser()
+	ctx, cancel := context.WithCancel(context.Background())
+	p.On("ParseToEndContext", ctx).Return(context.Canceled)
+	expected := []any{kill(common.EqAK47), kill(common.EqScout)}
+	p.MockEvents(expected[:1]...)
+	p.MockEvents(expected[1:]...)
+
+	var actual []any
+	p.RegisterEventHandler(func(e events.Kill) {
+		actual = append(actual, e)
+		cancel()
+	})
+
+	err := p.ParseToEndContext(ctx)
+
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, expected[:1], actual)
+}
+
+func TestEvents(t *testing.T) {
+	p := fake.NewParser()
+	p.On("ParseNextFrame").Return(true, nil).Once()
+	p.On("ParseNextFrame").Return(false, nil).Once()
+	p.On("UnregisterEventHandler").Return()
+	expected := []any{kill(common.EqAK47), kill(common.EqScout), kill(common.EqAUG)}
+	p.MockEvents(expected[:1]...)
+	p.MockEvents(expected[1:]...)
+
+	var actual []any
+
+	for e, err := range demoinfocs.Events(p) {
+		assert.NoError(t, err)
+
+		actual = append(actual, e)
+	}
+
+	assert.Equal(t, expected, actual)
+}
+
+func TestRegisterEnvelopeHandler(t *testing.T) {
+	p := fake.NewParser()
+	p.On("RegisterEnvelopeHandler").Return()
+	p.On("ParseToEnd").Return(nil)
+	expected := []any{kill(common.EqAK47), kill(common.EqScout)}
+	p.MockEvents(expected[:1]...)
+	p.MockEvents(expected[1:]...)
+
+	var actual []events.Envelope
+	p.RegisterEnvelopeHandler(func(e events.Envelope) {
+		actual = append(actual, e)
+	})
+
+	err := p.ParseToEnd()
+
+	assert.Nil(t, err)
+	assert.Equal(t, []events.Envelope{
+		{Event: expected[0], Frame: 0},
+		{Event: expected[1], Frame: 1},
+	}, actual)
+}
+
 func TestParseNextFrameNetMessages(t *testing.T) {
 	p := fake.NewParser()
 	p.On("ParseNextFrame").Return(true, nil)
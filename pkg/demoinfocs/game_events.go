@@ -69,7 +69,8 @@ type gameEventHandler struct {
 	gameEventNameToHandler      map[string]gameEventHandlerFunc
 	userIDToFallDamageFrame     map[int32]int
 	frameToRoundEndReason       map[int]events.RoundEndReason
-	ignoreBombsiteIndexNotFound bool // see https://github.com/markus-wa/demoinfocs-golang/issues/314
+	playerByPingEntityID        map[int]*common.Player // Needed for player_ping_stop, which only contains the entity-ID of the ping
+	ignoreBombsiteIndexNotFound bool                   // see https://github.com/markus-wa/demoinfocs-golang/issues/314
 }
 
 func (geh gameEventHandler) dispatch(event any) {
@@ -110,6 +111,7 @@ func newGameEventHandler(parser *parser, ignoreBombsiteIndexNotFound bool) gameE
 		parser:                      parser,
 		userIDToFallDamageFrame:     make(map[int32]int),
 		frameToRoundEndReason:       make(map[int]events.RoundEndReason),
+		playerByPingEntityID:        make(map[int]*common.Player),
 		ignoreBombsiteIndexNotFound: ignoreBombsiteIndexNotFound,
 	}
 
@@ -118,8 +120,12 @@ func newGameEventHandler(parser *parser, ignoreBombsiteIndexNotFound bool) gameE
 	// TODO: maybe we're supposed to delay all of them and store the data we need until the end of the tick
 	delay := func(f gameEventHandlerFunc) gameEventHandlerFunc {
 		return func(data map[string]*msg.CMsgSource1LegacyGameEventKeyT) {
+			origin := parser.currentEventOrigin()
+
 			parser.delayedEventHandlers = append(parser.delayedEventHandlers, func() {
-				f(data)
+				parser.withEventOrigin(origin, func() {
+					f(data)
+				})
 			})
 		}
 	}
@@ -163,10 +169,10 @@ func newGameEventHandler(parser *parser, ignoreBombsiteIndexNotFound bool) gameE
 		"decoy_started":                   delay(geh.decoyStarted),               // Decoy started. Delayed because projectile entity is not yet created
 		"endmatch_cmm_start_reveal_items": nil,                                   // Drops
 		"entity_visible":                  nil,                                   // Dunno, only in locally recorded (POV) demo
-		"enter_bombzone":                  nil,                                   // Dunno, only in locally recorded (POV) demo
-		"exit_bombzone":                   nil,                                   // Dunno, only in locally recorded (POV) demo
-		"enter_buyzone":                   nil,                                   // Dunno, only in locally recorded (POV) demo
-		"exit_buyzone":                    nil,                                   // Dunno, only in locally recorded (POV) demo
+		"enter_bombzone":                  geh.enterBombZone,                     // Player entered a bombsite, only in locally recorded (POV) demo
+		"exit_bombzone":                   geh.exitBombZone,                      // Player left a bombsite, only in locally recorded (POV) demo
+		"enter_buyzone":                   geh.enterBuyZone,                      // Player entered a buy zone, only in locally recorded (POV) demo
+		"exit_buyzone":                    geh.exitBuyZone,                       // Player left a buy zone, only in locally recorded (POV) demo
 		"flashbang_detonate":              geh.flashBangDetonate,                 // Flash exploded
 		"firstbombs_incoming_warning":     nil,                                   // First wave artillery incoming (Danger zone mode)
 		"grenade_thrown":                  nil,                                   // CS2 only, not reliable as it's not always present in demos and always fired. You should use "weapon_fire".
@@ -183,10 +189,10 @@ func newGameEventHandler(parser *parser, ignoreBombsiteIndexNotFound bool) gameE
 		"hostname_changed":                nil,                                   // Only present in locally recorded (POV) demos
 		"inferno_expire":                  geh.infernoExpire,                     // Incendiary expired
 		"inferno_startburn":               delay(geh.infernoStartBurn),           // Incendiary exploded/started. Delayed because inferno entity is not yet created
-		"inspect_weapon":                  nil,                                   // Dunno, only in locally recorded (POV) demos
+		"inspect_weapon":                  geh.inspectWeapon,                     // Player inspects their weapon, only in locally recorded (POV) demos
 		"item_equip":                      delay(geh.itemEquip),                  // Equipped / weapon swap, I think. Delayed because of #142 - Bot entity possibly not yet created
 		"item_pickup":                     delay(geh.itemPickup),                 // Picked up or bought? Delayed because of #119 - Equipment.UniqueID()
-		"item_pickup_slerp":               nil,                                   // Not sure, only in locally recorded (POV) demos
+		"item_pickup_slerp":               geh.itemPickupSlerp,                   // Item pickup animation, only in locally recorded (POV) demos
 		"item_remove":                     geh.itemRemove,                        // Dropped?
 		"jointeam_failed":                 nil,                                   // Dunno, only in locally recorded (POV) demos
 		"other_death":                     geh.otherDeath,                        // Other deaths, like chickens.
@@ -204,8 +210,8 @@ func newGameEventHandler(parser *parser, ignoreBombsiteIndexNotFound bool) gameE
 		"player_spawn":                    nil,                                   // Player spawn
 		"player_spawned":                  nil,                                   // Only present in locally recorded (POV) demos
 		"player_given_c4":                 nil,                                   // Dunno, only present in locally recorded (POV) demos
-		"player_ping":                     nil,                                   // When a player uses the "ping system" added with the operation Broken Fang, only present in locally recorded (POV) demos
-		"player_ping_stop":                nil,                                   // When a player's ping expired, only present in locally recorded (POV) demos
+		"player_ping":                     geh.playerPing,                        // When a player uses the "ping system" added with the operation Broken Fang, only present in locally recorded (POV) demos
+		"player_ping_stop":                geh.playerPingStop,                    // When a player's ping expired, only present in locally recorded (POV) demos
 		"player_sound":                    delayIfNoPlayers(geh.playerSound),     // When a player makes a sound
 
 		// Player changed team. Delayed for two reasons
@@ -275,6 +281,7 @@ func (geh gameEventHandler) roundStart(data map[string]*msg.CMsgSource1LegacyGam
 	}
 
 	geh.clearGrenadeProjectiles()
+	geh.parser.recordRoundStart()
 
 	geh.dispatch(events.RoundStart{
 		TimeLimit: int(data["timelimit"].GetValLong()),
@@ -381,6 +388,83 @@ func (geh gameEventHandler) playerSound(data map[string]*msg.CMsgSource1LegacyGa
 	})
 }
 
+func (geh gameEventHandler) enterBuyZone(data map[string]*msg.CMsgSource1LegacyGameEventKeyT) {
+	geh.dispatch(events.BuyZoneEntered{
+		Player: geh.playerByUserID32(data["userid"].GetValShort()),
+		CanBuy: data["canbuy"].GetValBool(),
+	})
+}
+
+func (geh gameEventHandler) exitBuyZone(data map[string]*msg.CMsgSource1LegacyGameEventKeyT) {
+	geh.dispatch(events.BuyZoneLeft{
+		Player: geh.playerByUserID32(data["userid"].GetValShort()),
+		CanBuy: data["canbuy"].GetValBool(),
+	})
+}
+
+func (geh gameEventHandler) enterBombZone(data map[string]*msg.CMsgSource1LegacyGameEventKeyT) {
+	geh.dispatch(events.BombZoneEntered{
+		Player:    geh.playerByUserID32(data["userid"].GetValShort()),
+		HasBomb:   data["hasbomb"].GetValBool(),
+		IsPlanted: data["isplanted"].GetValBool(),
+	})
+}
+
+func (geh gameEventHandler) exitBombZone(data map[string]*msg.CMsgSource1LegacyGameEventKeyT) {
+	geh.dispatch(events.BombZoneLeft{
+		Player:    geh.playerByUserID32(data["userid"].GetValShort()),
+		HasBomb:   data["hasbomb"].GetValBool(),
+		IsPlanted: data["isplanted"].GetValBool(),
+	})
+}
+
+func (geh gameEventHandler) inspectWeapon(data map[string]*msg.CMsgSource1LegacyGameEventKeyT) {
+	pl := geh.playerByUserID32(data["userid"].GetValShort())
+
+	var weapon *common.Equipment
+	if pl != nil {
+		weapon = pl.ActiveWeapon()
+	}
+
+	geh.dispatch(events.WeaponInspect{
+		Player: pl,
+		Weapon: weapon,
+	})
+}
+
+func (geh gameEventHandler) itemPickupSlerp(data map[string]*msg.CMsgSource1LegacyGameEventKeyT) {
+	geh.dispatch(events.ItemPickupSlerp{
+		Player:   geh.playerByUserID32(data["userid"].GetValShort()),
+		Weapon:   geh.gameState().weapons[int(data["index"].GetValShort())],
+		Behavior: int(data["behavior"].GetValShort()),
+	})
+}
+
+func (geh gameEventHandler) playerPing(data map[string]*msg.CMsgSource1LegacyGameEventKeyT) {
+	pl := geh.playerByUserID32(data["userid"].GetValShort())
+	geh.playerByPingEntityID[int(data["entityid"].GetValShort())] = pl
+
+	geh.dispatch(events.PlayerPing{
+		Player: pl,
+		Position: r3.Vector{
+			X: float64(data["x"].GetValFloat()),
+			Y: float64(data["y"].GetValFloat()),
+			Z: float64(data["z"].GetValFloat()),
+		},
+		IsUrgent: data["urgent"].GetValBool(),
+	})
+}
+
+func (geh gameEventHandler) playerPingStop(data map[string]*msg.CMsgSource1LegacyGameEventKeyT) {
+	entityID := int(data["entityid"].GetValShort())
+	pl := geh.playerByPingEntityID[entityID]
+	delete(geh.playerByPingEntityID, entityID)
+
+	geh.dispatch(events.PlayerPingStop{
+		Player: pl,
+	})
+}
+
 func (geh gameEventHandler) weaponFire(data map[string]*msg.CMsgSource1LegacyGameEventKeyT) {
 	if !geh.parser.disableMimicSource1GameEvents {
 		return
@@ -1106,6 +1190,7 @@ func (p *parser) dispatchMatchStartedEventIfNecessary() {
 func (p *parser) processRoundProgressEvents() {
 	if p.gameState.lastRoundStartEvent != nil {
 		p.dispatchMatchStartedEventIfNecessary()
+		p.recordRoundStart()
 		p.gameEventHandler.dispatch(*p.gameState.lastRoundStartEvent)
 		p.gameState.lastRoundStartEvent = nil
 	}
@@ -83,6 +83,22 @@ func (gs *gameState) handleIngameTickNumber(n ingameTickNumber) {
 	debugIngameTick(gs.ingameTick)
 }
 
+// resetTransientState clears state that only makes sense in the context of the previous frames,
+// e.g. pending events or grenades in flight. Used when jumping to a different position in the demo.
+func (gs *gameState) resetTransientState() {
+	gs.lastFlash = lastFlash{
+		projectileByPlayer: make(map[*common.Player]*common.GrenadeProjectile),
+	}
+	gs.currentDefuser = nil
+	gs.currentPlanter = nil
+	gs.thrownGrenades = make(map[*common.Player]map[common.EquipmentType][]*common.Equipment)
+	gs.flyingFlashbangs = make([]*FlyingFlashbang, 0)
+	gs.lastRoundStartEvent = nil
+	gs.lastFreezeTimeChangedEvent = nil
+	gs.lastRoundEndEvent = nil
+	gs.lastMatchStartedChangedEvent = nil
+}
+
 func (gs *gameState) indexPlayerBySteamID(pl *common.Player) {
 	if !pl.IsBot && pl.SteamID64 > 0 {
 		gs.playersBySteamID32[common.ConvertSteamID64To32(pl.SteamID64)] = pl
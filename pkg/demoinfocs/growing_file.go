@@ -0,0 +1,194 @@
+package demoinfocs
+
+import (
+	"io"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	bit "github.com/markus-wa/demoinfocs-golang/v5/internal/bitread"
+	"github.com/markus-wa/demoinfocs-golang/v5/pkg/demoinfocs/events"
+	"github.com/markus-wa/demoinfocs-golang/v5/pkg/demoinfocs/msg"
+)
+
+// ErrGrowingFileTimeout signals that a demo in DemoFormatGrowingFile wasn't written to
+// for longer than ParserConfig.GrowingFileTimeout before DEM_Stop was reached.
+var ErrGrowingFileTimeout = errors.New("demo file wasn't written to for longer than ParserConfig.GrowingFileTimeout (ErrGrowingFileTimeout)")
+
+// maxGrowingFilePollInterval is the maximum time between checks for new bytes at the end of a growing demo file.
+const maxGrowingFilePollInterval = 100 * time.Millisecond
+
+// growingFileReader reads a demo file that is still being written (DemoFormatGrowingFile).
+// Instead of returning io.EOF at the end of the file it waits for new bytes until the idle timeout is exceeded,
+// io.EOF is only returned after DEM_Stop.
+//
+// BitReader only processes the last bytes it has read (its sled) once the next read returns,
+// so the last frame is only parsed once the next one is being written.
+type growingFileReader struct {
+	file         io.Reader
+	frames       frameScanner
+	timeout      time.Duration // Zero means no timeout
+	pollInterval time.Duration
+	minRead      int // BitReader treats a first read that is shorter than its sled (8 bytes) as the complete input
+
+	interrupted   chan struct{}
+	interruptOnce sync.Once
+}
+
+// newGrowingFileReader returns a growingFileReader for file, which has to be positioned at the start of the demo
+// (atFrame false) or at the start of a frame (atFrame true).
+func newGrowingFileReader(file io.Reader, timeout time.Duration, atFrame bool) *growingFileReader {
+	pollInterval := maxGrowingFilePollInterval
+	if timeout > 0 {
+		pollInterval = min(pollInterval, timeout/10)
+	}
+
+	var frames frameScanner
+
+	if !atFrame {
+		frames.skip = 16 // file stamp & offsets
+	}
+
+	return &growingFileReader{
+		file:         file,
+		frames:       frames,
+		timeout:      timeout,
+		pollInterval: pollInterval,
+		minRead:      8,
+		interrupted:  make(chan struct{}),
+	}
+}
+
+// Read blocks until at least one byte (or the BitReader's sled for the first read) was read.
+// Returns ErrGrowingFileTimeout if no bytes were written during the idle timeout
+// and ErrCancelled if interrupt() was called.
+func (r *growingFileReader) Read(p []byte) (int, error) {
+	if r.frames.stopped {
+		return 0, io.EOF
+	}
+
+	minRead := min(r.minRead, len(p))
+	idleSince := time.Now()
+	n := 0
+
+	for {
+		m, err := r.file.Read(p[n:])
+		r.frames.scan(p[n : n+m])
+		n += m
+
+		if err != nil && !errors.Is(err, io.EOF) {
+			return n, err
+		}
+
+		if n >= minRead || (n > 0 && r.frames.stopped) {
+			r.minRead = 1
+
+			return n, nil
+		}
+
+		if m > 0 {
+			idleSince = time.Now()
+
+			continue
+		}
+
+		if r.timeout > 0 && time.Since(idleSince) >= r.timeout {
+			return n, ErrGrowingFileTimeout
+		}
+
+		select {
+		case <-r.interrupted:
+			return n, ErrCancelled
+
+		case <-time.After(r.pollInterval):
+		}
+	}
+}
+
+// interrupt stops waiting for new bytes, e.g. when parsing is cancelled.
+func (r *growingFileReader) interrupt() {
+	r.interruptOnce.Do(func() {
+		close(r.interrupted)
+	})
+}
+
+func (r *growingFileReader) Close() error {
+	r.interrupt()
+
+	if c, ok := r.file.(io.Closer); ok {
+		return c.Close()
+	}
+
+	return nil
+}
+
+// frameScanner follows the frame structure of the bytes read from a growing demo file to detect DEM_Stop.
+type frameScanner struct {
+	skip     int       // Remaining bytes of the file header or the payload of the current frame
+	header   [3]uint64 // Command, tick & payload size of the current frame
+	field    int       // Index of the header field that is being read
+	shift    uint
+	stopping bool // The current frame is DEM_Stop
+	stopped  bool // DEM_Stop has been read completely
+}
+
+func (s *frameScanner) scan(b []byte) {
+	for !s.stopped {
+		switch {
+		case s.skip > 0:
+			if len(b) == 0 {
+				return
+			}
+
+			n := min(s.skip, len(b))
+			s.skip -= n
+			b = b[n:]
+
+		case s.stopping:
+			s.stopped = true
+
+		case len(b) == 0:
+			return
+
+		default:
+			s.scanHeaderByte(b[0])
+			b = b[1:]
+		}
+	}
+}
+
+// scanHeaderByte reads a byte of the varints of a frame header.
+func (s *frameScanner) scanHeaderByte(c byte) {
+	s.header[s.field] |= uint64(c&0x7f) << s.shift
+	s.shift += 7
+
+	if c&0x80 != 0 {
+		return
+	}
+
+	s.field++
+	s.shift = 0
+
+	if s.field < len(s.header) {
+		return
+	}
+
+	cmd := msg.EDemoCommands(s.header[0]) & ^msg.EDemoCommands_DEM_IsCompressed
+	s.stopping = cmd == msg.EDemoCommands_DEM_Stop
+	s.skip = int(s.header[2])
+	s.header = [3]uint64{}
+	s.field = 0
+}
+
+// ensureGrowingFileBitReader creates the BitReader for DemoFormatGrowingFile once parsing starts.
+// This isn't done in NewParserWithConfig() as BitReader reads on creation, which blocks until the file header was written.
+func (p *parser) ensureGrowingFileBitReader() {
+	if p.bitReader == nil && p.growingFile != nil {
+		p.bitReader = bit.NewLargeBitReader(p.growingFile)
+	}
+}
+
+func (p *parser) handleDemoStopped(e events.DemoStopped) {
+	p.eventDispatcher.Dispatch(e)
+}
@@ -0,0 +1,177 @@
+package demoinfocs
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/markus-wa/demoinfocs-golang/v5/internal/demotest"
+	"github.com/markus-wa/demoinfocs-golang/v5/pkg/demoinfocs/events"
+)
+
+func growingFileTestConfig(timeout time.Duration) ParserConfig {
+	return ParserConfig{
+		Format:             DemoFormatGrowingFile,
+		GrowingFileTimeout: timeout,
+	}
+}
+
+// growingFile creates an empty demo file that is written to by the returned function.
+func growingFile(t *testing.T) (*os.File, func(b []byte)) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "growing.dem")
+
+	w, err := os.Create(path)
+	assert.NoError(t, err)
+
+	t.Cleanup(func() {
+		assert.NoError(t, w.Close())
+	})
+
+	r, err := os.Open(path)
+	assert.NoError(t, err)
+
+	return r, func(b []byte) {
+		_, err := w.Write(b)
+		assert.NoError(t, err)
+	}
+}
+
+// writeInChunks writes the demo to the file in chunks that are split at the given offsets, with pauses in between.
+func writeInChunks(write func([]byte), demo []byte, splits ...int) {
+	start := 0
+
+	for _, end := range append(splits, len(demo)) {
+		time.Sleep(20 * time.Millisecond)
+
+		write(demo[start:end])
+
+		start = end
+	}
+}
+
+func TestParser_GrowingFile(t *testing.T) {
+	d := demotest.New(t).TickPacket(0).TickPacket(1)
+	frameStart := d.Offset()
+	demo := d.TickPacket(2).TickPacket(3).Stop(4).Bytes()
+
+	f, write := growingFile(t)
+
+	// Splits before the end of the first BitReader sled and in the middle of a frame
+	go writeInChunks(write, demo, 5, frameStart+2, frameStart+len(demo[frameStart:])/2)
+
+	p := NewParserWithConfig(f, growingFileTestConfig(5*time.Second))
+
+	var evs []any
+
+	p.RegisterEventHandler(func(e any) {
+		evs = append(evs, e)
+	})
+
+	assert.NoError(t, p.ParseToEnd())
+	assert.Equal(t, 4, p.GameState().IngameTick())
+	assert.NotEmpty(t, evs)
+	assert.Equal(t, events.DemoStopped{}, evs[len(evs)-1])
+	assert.NoError(t, p.Close())
+}
+
+func TestParser_GrowingFile_Timeout(t *testing.T) {
+	d := demotest.New(t).TickPacket(0).TickPacket(1)
+	frameStart := d.Offset()
+	demo := d.TickPacket(2).Bytes()
+
+	tests := map[string][]byte{
+		"empty":          nil,
+		"frame boundary": demo,
+		"partial frame":  demo[:frameStart+2],
+	}
+
+	for name, written := range tests {
+		t.Run(name, func(t *testing.T) {
+			f, write := growingFile(t)
+			write(written)
+
+			p := NewParserWithConfig(f, growingFileTestConfig(100*time.Millisecond))
+			defer p.Close()
+
+			stopped := false
+
+			p.RegisterEventHandler(func(events.DemoStopped) {
+				stopped = true
+			})
+
+			err := p.ParseToEnd()
+			assert.ErrorIs(t, err, ErrGrowingFileTimeout)
+			assert.False(t, stopped)
+		})
+	}
+}
+
+func TestParser_GrowingFile_Cancel(t *testing.T) {
+	f, write := growingFile(t)
+	write(demotest.New(t).TickPacket(0).Bytes())
+
+	// Without timeout
+	p := NewParserWithConfig(f, growingFileTestConfig(0))
+	defer p.Close()
+
+	time.AfterFunc(100*time.Millisecond, p.Cancel)
+
+	assert.ErrorIs(t, p.ParseToEnd(), ErrCancelled)
+	assert.Equal(t, 0, p.GameState().IngameTick())
+}
+
+func TestParser_GrowingFile_Context(t *testing.T) {
+	f, write := growingFile(t)
+	write(demotest.New(t).TickPacket(0).Bytes())
+
+	p := NewParserWithConfig(f, growingFileTestConfig(0))
+	defer p.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	assert.ErrorIs(t, p.ParseToEndContext(ctx), context.DeadlineExceeded)
+}
+
+func TestResumeParser_GrowingFile(t *testing.T) {
+	demo := checkpointTestDemo(t)
+
+	config := checkpointTestConfig()
+	config.Format = DemoFormatGrowingFile
+	config.GrowingFileTimeout = time.Second
+
+	p := NewParserWithConfig(bytes.NewReader(demo), config)
+	defer p.Close()
+
+	for p.GameState().IngameTick() < checkpointTestTick {
+		_, err := p.ParseNextFrame()
+		assert.NoError(t, err)
+	}
+
+	var cp bytes.Buffer
+
+	assert.NoError(t, p.Checkpoint(&cp))
+
+	resumed, err := ResumeParserWithConfig(&cp, bytes.NewReader(demo), config)
+	assert.NoError(t, err)
+
+	defer resumed.Close()
+
+	stopped := false
+
+	resumed.RegisterEventHandler(func(events.DemoStopped) {
+		stopped = true
+	})
+
+	assert.NoError(t, resumed.ParseToEnd())
+	assert.True(t, stopped)
+	assert.Equal(t, 14, resumed.GameState().IngameTick())
+	assert.ErrorIs(t, resumed.SeekToTick(0), ErrSeekNotSupported)
+}
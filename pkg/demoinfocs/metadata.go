@@ -0,0 +1,370 @@
+package demoinfocs
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/proto"
+
+	bit "github.com/markus-wa/demoinfocs-golang/v5/internal/bitread"
+	common "github.com/markus-wa/demoinfocs-golang/v5/pkg/demoinfocs/common"
+	"github.com/markus-wa/demoinfocs-golang/v5/pkg/demoinfocs/msg"
+)
+
+// Metadata contains basic information about a demo, see ScanMetadata().
+type Metadata struct {
+	Header
+
+	PlaybackTime    time.Duration       // Demo duration
+	PlaybackTicks   int                 // Game duration in ticks
+	PlaybackFrames  int                 // Amount of 'frames' aka demo-ticks recorded
+	RoundStartTicks []int               // Ingame ticks at which rounds started, only available if HasFileInfo is true
+	Rounds          int                 // Amount of rounds started, only available if HasFileInfo is true
+	Players         []common.PlayerInfo // All players (including BOTs, excluding GOTV) that were connected at some point, ordered by player slot
+	HasFileInfo     bool                // Whether the demo contains a CDemoFileInfo message (missing for incomplete demos)
+}
+
+/*
+ScanMetadata reads the metadata (map, server, duration, rounds, players etc.) of a CS2 demo
+without parsing game events or entities, which is a lot faster than Parser.ParseToEnd().
+
+The header and the trailing CDemoFileInfo message are read directly via the offsets in the demo header.
+The player roster is read from the 'userinfo' string table, for which all frames need to be scanned.
+If the demo has no CDemoFileInfo (e.g. incomplete demos) the playback values are calculated from the last tick instead
+and Metadata.Rounds & Metadata.RoundStartTicks are unavailable.
+
+The reader is read from its current position, which is also where it is left afterwards.
+
+Returns ErrInvalidFileType if the input isn't a CS2 demo
+and ErrUnexpectedEndOfDemo if the demo ends before its header is complete.
+*/
+func ScanMetadata(demo io.ReadSeeker) (md *Metadata, err error) {
+	start, err := demo.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get current position")
+	}
+
+	defer func() {
+		_, seekErr := demo.Seek(start, io.SeekStart)
+		if err == nil && seekErr != nil {
+			err = errors.Wrap(seekErr, "failed to restore position")
+		}
+	}()
+
+	var demoHeader [demoHeaderSize]byte
+
+	_, err = io.ReadFull(demo, demoHeader[:])
+	if err != nil {
+		return nil, errors.Wrap(ErrUnexpectedEndOfDemo, err.Error())
+	}
+
+	switch string(demoHeader[:8]) {
+	case "PBDEMS2\x00":
+	case "HL2DEMO\x00":
+		return nil, fmt.Errorf("%w: CS:GO demos are no longer supported, downgrade to v3", ErrInvalidFileType)
+	default:
+		return nil, ErrInvalidFileType
+	}
+
+	scanner := metadataScanner{
+		r:      bufio.NewReader(demo),
+		parser: newParserWithoutInput(ParserConfig{MsgQueueBufferSize: -1}),
+	}
+
+	fileHeader := new(msg.CDemoFileHeader)
+
+	cmd, err := scanner.readFrame(fileHeader)
+	if err != nil {
+		return nil, err
+	}
+
+	if cmd != msg.EDemoCommands_DEM_FileHeader {
+		return nil, fmt.Errorf("%w: expected DEM_FileHeader, got %v", ErrInvalidFileType, cmd)
+	}
+
+	md = &Metadata{
+		Header: newHeader(fileHeader),
+	}
+
+	scanner.parser.header = &header{
+		Filestamp:       "PBDEMS2",
+		NetworkProtocol: md.NetworkProtocol,
+		ServerName:      md.ServerName,
+		ClientName:      md.ClientName,
+		MapName:         md.MapName,
+		GameDirectory:   md.GameDirectory,
+	}
+
+	fileInfoOffset := int64(binary.LittleEndian.Uint32(demoHeader[8:12]))
+	if fileInfoOffset > 0 {
+		md.HasFileInfo = scanner.readFileInfo(demo, start+fileInfoOffset, md)
+	}
+
+	_, err = demo.Seek(start+demoHeaderSize, io.SeekStart)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to seek to first frame")
+	}
+
+	scanner.r.Reset(demo)
+
+	err = scanner.scanStringTables()
+	if err != nil {
+		return nil, err
+	}
+
+	if !md.HasFileInfo {
+		md.PlaybackTicks = scanner.lastTick
+		md.PlaybackFrames = scanner.frames
+		md.PlaybackTime = time.Duration(float32(scanner.lastTick) * scanner.parser.tickInterval * float32(time.Second))
+	}
+
+	md.Players = scanner.players()
+
+	return md, nil
+}
+
+// metadataScanner reads frames without a BitReader and only handles the messages needed for the metadata.
+// The parser is only used for its string table state and never reads any input itself.
+type metadataScanner struct {
+	r        *bufio.Reader
+	parser   *parser
+	lastTick int
+	frames   int
+}
+
+// readFrame reads the next frame and unmarshals it into m.
+func (s *metadataScanner) readFrame(m proto.Message) (msg.EDemoCommands, error) {
+	cmd, _, size, _, err := readFrameHeader(s.r)
+	if err != nil {
+		return 0, errors.Wrap(ErrUnexpectedEndOfDemo, err.Error())
+	}
+
+	err = s.readPayload(cmd, size, m)
+	if err != nil {
+		return 0, err
+	}
+
+	return cmd & ^msg.EDemoCommands_DEM_IsCompressed, nil
+}
+
+// readPayload reads the payload of a frame and unmarshals it into m.
+func (s *metadataScanner) readPayload(cmd msg.EDemoCommands, size uint32, m proto.Message) error {
+	// The size isn't allocated up front as it may be garbage in corrupt or truncated demos,
+	// the buffer only grows as far as there is data.
+	var payload bytes.Buffer
+
+	n, err := payload.ReadFrom(io.LimitReader(s.r, int64(size)))
+	if err == nil && n < int64(size) {
+		err = io.ErrUnexpectedEOF
+	}
+
+	if err != nil {
+		return errors.Wrap(ErrUnexpectedEndOfDemo, err.Error())
+	}
+
+	buf := payload.Bytes()
+
+	if cmd&msg.EDemoCommands_DEM_IsCompressed != 0 {
+		buf, err = snappy.Decode(nil, buf)
+		if err != nil {
+			return errors.Wrap(err, "failed to decompress frame")
+		}
+	}
+
+	err = proto.Unmarshal(buf, m)
+	if err != nil {
+		return errors.Wrap(err, "failed to unmarshal frame")
+	}
+
+	return nil
+}
+
+// readFileInfo reads the CDemoFileInfo message at the given offset.
+// Returns false if there is no valid CDemoFileInfo message at the offset.
+func (s *metadataScanner) readFileInfo(demo io.ReadSeeker, offset int64, md *Metadata) bool {
+	_, err := demo.Seek(offset, io.SeekStart)
+	if err != nil {
+		return false
+	}
+
+	s.r.Reset(demo)
+
+	fileInfo := new(msg.CDemoFileInfo)
+
+	cmd, err := s.readFrame(fileInfo)
+	if err != nil || cmd != msg.EDemoCommands_DEM_FileInfo {
+		return false
+	}
+
+	md.PlaybackTime = time.Duration(fileInfo.GetPlaybackTime() * float32(time.Second))
+	md.PlaybackTicks = int(fileInfo.GetPlaybackTicks())
+	md.PlaybackFrames = int(fileInfo.GetPlaybackFrames())
+
+	for _, tick := range fileInfo.GetGameInfo().GetCs().GetRoundStartTicks() {
+		md.RoundStartTicks = append(md.RoundStartTicks, int(tick))
+	}
+
+	md.Rounds = len(md.RoundStartTicks)
+
+	return true
+}
+
+// scanStringTables reads all frames until DEM_Stop and applies the userinfo string table updates.
+// Packet payloads that aren't needed (e.g. PacketEntities) are skipped without being unmarshalled.
+func (s *metadataScanner) scanStringTables() (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("failed to scan string tables: %v", r)
+		}
+	}()
+
+	for {
+		cmd, tick, size, _, err := readFrameHeader(s.r)
+		if err != nil {
+			// Incomplete demos just end without DEM_Stop
+			return nil
+		}
+
+		s.frames++
+		s.lastTick = int(tick)
+
+		var m proto.Message
+
+		switch cmd & ^msg.EDemoCommands_DEM_IsCompressed { //nolint:exhaustive
+		case msg.EDemoCommands_DEM_Stop:
+			return nil
+
+		case msg.EDemoCommands_DEM_Packet, msg.EDemoCommands_DEM_SignonPacket:
+			m = new(msg.CDemoPacket)
+
+		case msg.EDemoCommands_DEM_FullPacket:
+			m = new(msg.CDemoFullPacket)
+
+		default:
+			_, err = s.r.Discard(int(size))
+			if err != nil {
+				return nil
+			}
+
+			continue
+		}
+
+		err = s.readPayload(cmd, size, m)
+		if errors.Is(err, ErrUnexpectedEndOfDemo) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		switch m := m.(type) {
+		case *msg.CDemoPacket:
+			err = s.handlePacket(m.GetData())
+
+		case *msg.CDemoFullPacket:
+			s.handleFullPacketStringTables(m.GetStringTable())
+
+			err = s.handlePacket(m.GetPacket().GetData())
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// handlePacket unmarshals and handles only the string table and server info messages of a packet.
+func (s *metadataScanner) handlePacket(b []byte) error {
+	if len(b) == 0 {
+		return nil
+	}
+
+	r := bit.NewSmallBitReader(readOnly{bytes.NewReader(b)})
+	defer r.Pool()
+
+	for len(b)*8-r.ActualPosition() > 7 {
+		t := int32(r.ReadUBitInt())
+		size := int(r.ReadVarInt32())
+
+		switch t {
+		case int32(msg.SVC_Messages_svc_ServerInfo):
+			var srvInfo msg.CSVCMsg_ServerInfo
+
+			err := proto.Unmarshal(r.ReadBytes(size), &srvInfo)
+			if err != nil {
+				return errors.Wrap(err, "failed to unmarshal CSVCMsg_ServerInfo")
+			}
+
+			s.parser.tickInterval = srvInfo.GetTickInterval()
+
+		case int32(msg.SVC_Messages_svc_CreateStringTable):
+			tab := new(msg.CSVCMsg_CreateStringTable)
+
+			err := proto.Unmarshal(r.ReadBytes(size), tab)
+			if err != nil {
+				return errors.Wrap(err, "failed to unmarshal CSVCMsg_CreateStringTable")
+			}
+
+			if tab.GetName() == stNameUserInfo {
+				s.parser.handleCreateStringTable(tab)
+			} else {
+				// Only needed so table IDs of updates can be resolved
+				s.parser.stringTables = append(s.parser.stringTables, tab)
+			}
+
+		case int32(msg.SVC_Messages_svc_UpdateStringTable):
+			tab := new(msg.CSVCMsg_UpdateStringTable)
+
+			err := proto.Unmarshal(r.ReadBytes(size), tab)
+			if err != nil {
+				return errors.Wrap(err, "failed to unmarshal CSVCMsg_UpdateStringTable")
+			}
+
+			tableID := int(tab.GetTableId())
+			if tableID < len(s.parser.stringTables) && s.parser.stringTables[tableID].GetName() == stNameUserInfo {
+				s.parser.handleUpdateStringTable(tab)
+			}
+
+		default:
+			r.Skip(size << 3)
+		}
+	}
+
+	return s.parser.error()
+}
+
+func (s *metadataScanner) handleFullPacketStringTables(tables *msg.CDemoStringTables) {
+	for _, tab := range tables.GetTables() {
+		if tab.GetTableName() == stNameUserInfo {
+			s.parser.handleStringTables(&msg.CDemoStringTables{
+				Tables: []*msg.CDemoStringTablesTableT{tab},
+			})
+		}
+	}
+}
+
+func (s *metadataScanner) players() []common.PlayerInfo {
+	slots := make([]int, 0, len(s.parser.rawPlayers))
+
+	for slot, info := range s.parser.rawPlayers {
+		if !info.IsHltv {
+			slots = append(slots, slot)
+		}
+	}
+
+	sort.Ints(slots)
+
+	players := make([]common.PlayerInfo, 0, len(slots))
+
+	for _, slot := range slots {
+		players = append(players, *s.parser.rawPlayers[slot])
+	}
+
+	return players
+}
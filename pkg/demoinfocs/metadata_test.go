@@ -0,0 +1,117 @@
+package demoinfocs
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/markus-wa/demoinfocs-golang/v5/internal/demotest"
+	common "github.com/markus-wa/demoinfocs-golang/v5/pkg/demoinfocs/common"
+	"github.com/markus-wa/demoinfocs-golang/v5/pkg/demoinfocs/msg"
+)
+
+func metadataTestDemo(t *testing.T) *demotest.Demo {
+	t.Helper()
+
+	d := demotest.New(t)
+
+	d.StringTablesFullPacket(0, demotest.UserInfoTable(t, map[int]*msg.CMsgPlayerInfo{
+		0: {Name: proto.String("SourceTV"), Ishltv: proto.Bool(true)},
+		1: {Name: proto.String("s1mple"), Xuid: proto.Uint64(76561198034202275), Userid: proto.Int32(2)},
+		2: {Name: proto.String("BOT Ted"), Fakeplayer: proto.Bool(true), Userid: proto.Int32(3)},
+	}))
+
+	for tick := int32(1); tick < 128; tick++ {
+		d.TickPacket(tick)
+	}
+
+	return d
+}
+
+func TestScanMetadata(t *testing.T) {
+	d := metadataTestDemo(t)
+	d.Stop(128)
+	d.FileInfo(128, &msg.CDemoFileInfo{
+		PlaybackTime:   proto.Float32(2),
+		PlaybackTicks:  proto.Int32(128),
+		PlaybackFrames: proto.Int32(130),
+		GameInfo: &msg.CGameInfo{
+			Cs: &msg.CGameInfo_CCSGameInfo{RoundStartTicks: []int32{10, 64}},
+		},
+	})
+
+	md, err := ScanMetadata(bytes.NewReader(d.Bytes()))
+	assert.NoError(t, err)
+
+	assert.Equal(t, "de_test", md.MapName)
+	assert.Equal(t, "test server", md.ServerName)
+	assert.Equal(t, "SourceTV Demo", md.ClientName)
+	assert.Equal(t, 14070, md.NetworkProtocol)
+	assert.Equal(t, 10093, md.BuildNum)
+	assert.True(t, md.HasFileInfo)
+	assert.Equal(t, 2*time.Second, md.PlaybackTime)
+	assert.Equal(t, 128, md.PlaybackTicks)
+	assert.Equal(t, 130, md.PlaybackFrames)
+	assert.Equal(t, []int{10, 64}, md.RoundStartTicks)
+	assert.Equal(t, 2, md.Rounds)
+	assert.Equal(t, []common.PlayerInfo{
+		{Name: "s1mple", XUID: 76561198034202275, UserID: 2},
+		{Name: "BOT Ted", UserID: 3, IsFakePlayer: true},
+	}, md.Players)
+}
+
+func TestScanMetadata_WithoutFileInfo(t *testing.T) {
+	md, err := ScanMetadata(bytes.NewReader(metadataTestDemo(t).Bytes()))
+	assert.NoError(t, err)
+
+	assert.Equal(t, "de_test", md.MapName)
+	assert.False(t, md.HasFileInfo)
+	assert.Equal(t, 127, md.PlaybackTicks)
+	assert.Zero(t, md.Rounds)
+	assert.Len(t, md.Players, 2)
+}
+
+func TestScanMetadata_RestoresPosition(t *testing.T) {
+	r := bytes.NewReader(metadataTestDemo(t).Stop(128).Bytes())
+
+	_, err := ScanMetadata(r)
+	assert.NoError(t, err)
+
+	pos, err := r.Seek(0, io.SeekCurrent)
+	assert.NoError(t, err)
+	assert.Zero(t, pos)
+}
+
+func TestScanMetadata_InvalidFileType(t *testing.T) {
+	_, err := ScanMetadata(bytes.NewReader([]byte("HL2DEMO\x00\x00\x00\x00\x00\x00\x00\x00\x00")))
+	assert.ErrorIs(t, err, ErrInvalidFileType)
+
+	_, err = ScanMetadata(bytes.NewReader([]byte("PBDE")))
+	assert.ErrorIs(t, err, ErrUnexpectedEndOfDemo)
+}
+
+func TestScanMetadata_CorruptFrameSize(t *testing.T) {
+	d := []byte("PBDEMS2\x00\x00\x00\x00\x00\x00\x00\x00\x00")
+	d = binary.AppendUvarint(d, uint64(msg.EDemoCommands_DEM_FileHeader))
+	d = binary.AppendUvarint(d, 0)
+	d = binary.AppendUvarint(d, math.MaxUint32)
+	d = append(d, "garbage"...)
+
+	var before, after runtime.MemStats
+
+	runtime.ReadMemStats(&before)
+
+	_, err := ScanMetadata(bytes.NewReader(d))
+
+	runtime.ReadMemStats(&after)
+
+	assert.ErrorIs(t, err, ErrUnexpectedEndOfDemo)
+	assert.Less(t, after.TotalAlloc-before.TotalAlloc, uint64(1<<20))
+}
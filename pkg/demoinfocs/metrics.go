@@ -0,0 +1,172 @@
+package demoinfocs
+
+import (
+	"reflect"
+	"runtime"
+	"sync"
+	"time"
+
+	dp "github.com/markus-wa/godispatch"
+
+	"github.com/markus-wa/demoinfocs-golang/v5/pkg/demoinfocs/msg"
+	st "github.com/markus-wa/demoinfocs-golang/v5/pkg/demoinfocs/sendtables"
+)
+
+// Stats contains the metrics that have been collected while parsing, see ParserConfig.Metrics & Parser.Stats().
+type Stats struct {
+	DemoCommands       map[msg.EDemoCommands]MessageStats         // Frames per demo command (without DEM_IsCompressed), Bytes is the size of the payloads as stored in the demo
+	NetMessages        map[int32]MessageStats                     // Net-messages per net-message-ID, Bytes is the size of the protobuf encoded payloads
+	EntityUpdates      map[string]int                             // Entity creations, updates & deletions per server class name (only decoded entities, see ParserConfig.EntityClasses)
+	EntityDecodingTime time.Duration                              // Time spent decoding PacketEntities messages, excluding entity handlers
+	EventHandlers      map[dp.HandlerIdentifier]EventHandlerStats // Calls & wall time per handler registered via RegisterEventHandler() or On()
+}
+
+// MessageStats contains the amount and total size of a type of message.
+type MessageStats struct {
+	Name  string // E.g. DEM_Packet or CSVCMsg_PacketEntities, empty for unknown net-messages
+	Count int
+	Bytes int
+}
+
+// EventHandlerStats contains the amount of calls and the total wall time of an event handler.
+type EventHandlerStats struct {
+	Name  string // Name of the handler function, e.g. main.main.func1
+	Calls int
+	Time  time.Duration
+}
+
+// metrics collects the Stats that aren't available from other parts of the parser, see ParserConfig.Metrics.
+type metrics struct {
+	lock               sync.Mutex
+	demoCommands       map[msg.EDemoCommands]*MessageStats
+	entityUpdates      map[string]int
+	entityDecodingTime time.Duration
+	eventHandlers      map[dp.HandlerIdentifier]*EventHandlerStats
+}
+
+func newMetrics() *metrics {
+	return &metrics{
+		demoCommands:  make(map[msg.EDemoCommands]*MessageStats),
+		entityUpdates: make(map[string]int),
+		eventHandlers: make(map[dp.HandlerIdentifier]*EventHandlerStats),
+	}
+}
+
+func (m *metrics) countDemoCommand(cmd msg.EDemoCommands, size uint32) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	stats := m.demoCommands[cmd]
+	if stats == nil {
+		stats = &MessageStats{Name: cmd.String()}
+		m.demoCommands[cmd] = stats
+	}
+
+	stats.Count++
+	stats.Bytes += int(size)
+}
+
+func (m *metrics) countEntityUpdate(e st.Entity, _ st.EntityOp) error {
+	m.lock.Lock()
+	m.entityUpdates[e.ServerClass().Name()]++
+	m.lock.Unlock()
+
+	return nil
+}
+
+func (m *metrics) addEntityDecodingTime(d time.Duration) {
+	m.lock.Lock()
+	m.entityDecodingTime += d
+	m.lock.Unlock()
+}
+
+// registerEventHandler registers a handler that measures the wall time of the given event handler.
+func (m *metrics) registerEventHandler(dispatcher *eventDispatcher, handler any, priority int) dp.HandlerIdentifier {
+	fn := reflect.ValueOf(handler)
+	if fn.Kind() != reflect.Func {
+		// Let the dispatcher deal with invalid handlers
+		return dispatcher.RegisterHandlerWithPriority(handler, priority)
+	}
+
+	stats := &EventHandlerStats{
+		Name: runtime.FuncForPC(fn.Pointer()).Name(),
+	}
+
+	timed := reflect.MakeFunc(fn.Type(), func(args []reflect.Value) []reflect.Value {
+		start := time.Now()
+		results := fn.Call(args)
+		elapsed := time.Since(start)
+
+		m.lock.Lock()
+		stats.Calls++
+		stats.Time += elapsed
+		m.lock.Unlock()
+
+		return results
+	})
+
+	identifier := dispatcher.RegisterHandlerWithPriority(timed.Interface(), priority)
+
+	m.lock.Lock()
+	m.eventHandlers[identifier] = stats
+	m.lock.Unlock()
+
+	return identifier
+}
+
+// stats returns a copy of the collected metrics.
+func (m *metrics) stats() Stats {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	stats := Stats{
+		DemoCommands:       make(map[msg.EDemoCommands]MessageStats, len(m.demoCommands)),
+		EntityUpdates:      make(map[string]int, len(m.entityUpdates)),
+		EntityDecodingTime: m.entityDecodingTime,
+		EventHandlers:      make(map[dp.HandlerIdentifier]EventHandlerStats, len(m.eventHandlers)),
+	}
+
+	for cmd, s := range m.demoCommands {
+		stats.DemoCommands[cmd] = *s
+	}
+
+	for class, n := range m.entityUpdates {
+		stats.EntityUpdates[class] = n
+	}
+
+	for identifier, s := range m.eventHandlers {
+		stats.EventHandlers[identifier] = *s
+	}
+
+	return stats
+}
+
+/*
+Stats returns the metrics that have been collected so far if ParserConfig.Metrics is set,
+otherwise all fields are empty.
+
+Intended to be called after parsing or between ParseNextFrame() calls,
+while ParseToEnd() is running the entity & event handler stats lag behind as they're collected asynchronously.
+*/
+func (p *parser) Stats() Stats {
+	if p.metrics == nil {
+		return Stats{}
+	}
+
+	stats := p.metrics.stats()
+
+	p.netMessageStatsLock.Lock()
+	defer p.netMessageStatsLock.Unlock()
+
+	stats.NetMessages = make(map[int32]MessageStats, len(p.netMessageStats))
+
+	for id, stat := range p.netMessageStats {
+		stats.NetMessages[id] = MessageStats{
+			Name:  stat.Name,
+			Count: stat.Read,
+			Bytes: stat.bytes,
+		}
+	}
+
+	return stats
+}
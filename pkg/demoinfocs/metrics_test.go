@@ -0,0 +1,88 @@
+package demoinfocs
+
+import (
+	"bytes"
+	"runtime/trace"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/markus-wa/demoinfocs-golang/v5/internal/demotest"
+	"github.com/markus-wa/demoinfocs-golang/v5/pkg/demoinfocs/events"
+	"github.com/markus-wa/demoinfocs-golang/v5/pkg/demoinfocs/msg"
+)
+
+func TestParser_Stats(t *testing.T) {
+	sayText := testSayTextMsg("hello")
+	d := demotest.New(t).TickPacket(0)
+	d.Packet(1, sayText).Packet(2, sayText, testConVarMsg("mp_test", "1"))
+
+	demo := d.Stop(3).Bytes()
+
+	p := NewParserWithConfig(bytes.NewReader(demo), ParserConfig{Metrics: true})
+	defer p.Close()
+
+	sayTexts := 0
+
+	identifier := p.RegisterEventHandler(func(events.SayText) {
+		sayTexts++
+	})
+
+	assert.NoError(t, p.ParseToEnd())
+	assert.Equal(t, 2, sayTexts)
+
+	stats := p.Stats()
+
+	packets := stats.DemoCommands[msg.EDemoCommands_DEM_Packet]
+	assert.Equal(t, "DEM_Packet", packets.Name)
+	assert.Equal(t, 3, packets.Count)
+	assert.Positive(t, packets.Bytes)
+	assert.Equal(t, 1, stats.DemoCommands[msg.EDemoCommands_DEM_Stop].Count)
+	assert.Equal(t, 1, stats.DemoCommands[msg.EDemoCommands_DEM_FileHeader].Count)
+
+	assert.Equal(t, MessageStats{
+		Name:  "CUserMessageSayText",
+		Count: 2,
+		Bytes: 2 * proto.Size(sayText.Msg),
+	}, stats.NetMessages[sayText.Type])
+	assert.Equal(t, 1, stats.NetMessages[int32(msg.NET_Messages_net_SetConVar)].Count)
+
+	handler := stats.EventHandlers[identifier]
+	assert.Equal(t, 2, handler.Calls)
+	assert.Positive(t, handler.Time)
+	assert.Contains(t, handler.Name, "TestParser_Stats")
+
+	// Synthetic demos don't contain entities
+	assert.Empty(t, stats.EntityUpdates)
+}
+
+func TestParser_Stats_Disabled(t *testing.T) {
+	p := NewParser(bytes.NewReader(demotest.New(t).Packet(0, testSayTextMsg("hello")).Stop(1).Bytes()))
+	defer p.Close()
+
+	p.RegisterEventHandler(func(events.SayText) {})
+
+	assert.NoError(t, p.ParseToEnd())
+	assert.Equal(t, Stats{}, p.Stats())
+}
+
+func TestParser_TraceRegions(t *testing.T) {
+	var buf bytes.Buffer
+
+	err := trace.Start(&buf)
+	if err != nil {
+		t.Skip("tracing is already enabled")
+	}
+
+	p := NewParser(bytes.NewReader(demotest.New(t).Packet(0, testSayTextMsg("hello")).Stop(1).Bytes()))
+	defer p.Close()
+
+	assert.NoError(t, p.ParseToEnd())
+
+	trace.Stop()
+
+	for _, region := range []string{"demoinfocs.parseHeader", "demoinfocs.processFrame", "demoinfocs.decodeNetMessages", "demoinfocs.frameParsed"} {
+		assert.Contains(t, buf.String(), region)
+	}
+}
@@ -18,6 +18,8 @@ protoc -Iproto \
        --go_opt=Ms2/usermessages.proto=github.com/markus-wa/demoinfocs-golang/v5/pkg/demoinfocs/msg \
        --go_opt=Ms2/cs_gameevents.proto=github.com/markus-wa/demoinfocs-golang/v5/pkg/demoinfocs/msg \
        --go_opt=Ms2/te.proto=github.com/markus-wa/demoinfocs-golang/v5/pkg/demoinfocs/msg \
+       --go_opt=Ms2/usercmd.proto=github.com/markus-wa/demoinfocs-golang/v5/pkg/demoinfocs/msg \
+       --go_opt=Ms2/cs_usercmd.proto=github.com/markus-wa/demoinfocs-golang/v5/pkg/demoinfocs/msg \
        --go_opt=module=github.com/markus-wa/demoinfocs-golang/v5/pkg/demoinfocs/msg \
        s2/cstrike15_gcmessages.proto \
        s2/cstrike15_usermessages.proto \
@@ -31,4 +33,6 @@ protoc -Iproto \
        s2/gameevents.proto \
        s2/usermessages.proto \
        s2/cs_gameevents.proto \
-       s2/te.proto
+       s2/te.proto \
+       s2/usercmd.proto \
+       s2/cs_usercmd.proto
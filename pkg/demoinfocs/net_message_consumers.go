@@ -0,0 +1,260 @@
+package demoinfocs
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"sync"
+
+	dp "github.com/markus-wa/godispatch"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/markus-wa/demoinfocs-golang/v5/pkg/demoinfocs/events"
+	"github.com/markus-wa/demoinfocs-golang/v5/pkg/demoinfocs/msg"
+)
+
+// netMessageConsumers keeps track of the parameter types of registered net-message handlers,
+// so net-messages that nobody listens to don't need to be unmarshalled.
+type netMessageConsumers struct {
+	lock     sync.Mutex
+	handlers map[dp.HandlerIdentifier]reflect.Type // Parameter types of registered handlers
+	cache    map[reflect.Type]bool                 // Whether a net-message type has handlers, reset on (un-)registration
+}
+
+func (c *netMessageConsumers) add(identifier dp.HandlerIdentifier, handler any) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.handlers == nil {
+		c.handlers = make(map[dp.HandlerIdentifier]reflect.Type)
+	}
+
+	c.handlers[identifier] = reflect.TypeOf(handler).In(0)
+	c.cache = nil
+}
+
+func (c *netMessageConsumers) remove(identifier dp.HandlerIdentifier) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	delete(c.handlers, identifier)
+	c.cache = nil
+}
+
+func (c *netMessageConsumers) removeAll() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	clear(c.handlers)
+	c.cache = nil
+}
+
+// has returns true if at least one handler would receive net-messages of the given type.
+func (c *netMessageConsumers) has(msgType reflect.Type) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	consumed, ok := c.cache[msgType]
+	if ok {
+		return consumed
+	}
+
+	for _, t := range c.handlers {
+		if msgType.AssignableTo(t) {
+			consumed = true
+
+			break
+		}
+	}
+
+	if c.cache == nil {
+		c.cache = make(map[reflect.Type]bool)
+	}
+
+	c.cache[msgType] = consumed
+
+	return consumed
+}
+
+var userCommandsType = reflect.TypeFor[*msg.CSVCMsg_UserCommands]()
+
+// consumesNetMessage returns true if net-messages of the given type need to be unmarshalled.
+// User commands are only needed by user handlers or to dispatch PlayerInput events.
+func (p *parser) consumesNetMessage(msgType reflect.Type) bool {
+	if msgType == userCommandsType && p.hasPlayerInputHandlers() {
+		return true
+	}
+
+	return p.netMessageConsumers.has(msgType)
+}
+
+// netMessageStat is the NetMessageCount of a net-message type along with its Go type.
+type netMessageStat struct {
+	NetMessageCount
+
+	typ   reflect.Type
+	bytes int // Size of the payloads of all read messages, see Parser.Stats()
+}
+
+// netMessageStat returns the stats of the given net-message type, creating them on first use.
+// The creator is nil for unknown net-message types.
+func (p *parser) netMessageStat(msgType int32, creator NetMessageCreator) *netMessageStat {
+	p.netMessageStatsLock.Lock()
+	defer p.netMessageStatsLock.Unlock()
+
+	stat := p.netMessageStats[msgType]
+	if stat == nil {
+		stat = new(netMessageStat)
+
+		if creator != nil {
+			m := creator()
+			stat.Name = string(proto.MessageName(m).Name())
+			stat.typ = reflect.TypeOf(m)
+		}
+
+		p.netMessageStats[msgType] = stat
+	}
+
+	return stat
+}
+
+// countNetMessage increments the read count of a net-message type.
+func (p *parser) countNetMessage(stat *netMessageStat, size int) {
+	p.netMessageStatsLock.Lock()
+
+	stat.Read++
+	stat.bytes += size
+
+	p.netMessageStatsLock.Unlock()
+}
+
+// encodedNetMessage is queued for every known net-message, it's only unmarshalled when it's dispatched.
+// This way handlers that are registered while parsing (e.g. inside an event handler)
+// receive all messages that haven't been dispatched yet, even though the parser reads ahead.
+type encodedNetMessage struct {
+	id      int32
+	buf     []byte
+	creator NetMessageCreator
+	stat    *netMessageStat
+	pos     framePosition // Position of the frame the message was read from, for ParseError
+}
+
+// handleEncodedNetMessage unmarshals and dispatches a net-message, unless nobody is interested in it.
+func (p *parser) handleEncodedNetMessage(m encodedNetMessage) {
+	// Unmarshalling is expensive, skip messages nobody is interested in
+	if !p.consumesNetMessage(m.stat.typ) {
+		return
+	}
+
+	msg := m.creator()
+
+	err := proto.Unmarshal(m.buf, msg)
+	if err != nil {
+		err = m.pos.parseError(fmt.Errorf("failed to unmarshal net-message: %w", err), m.id)
+
+		if p.config.RecoveryMode {
+			p.eventDispatcher.Dispatch(events.ParserWarn{
+				Message: fmt.Sprintf("skipping frames until the next full packet after corrupt net-message: %v", err),
+				Type:    events.WarnTypeCorruptFrameSkipped,
+			})
+
+			p.resyncPending.Store(true)
+		} else {
+			p.setError(err)
+		}
+
+		return
+	}
+
+	p.netMessageStatsLock.Lock()
+	m.stat.Decoded++
+	p.netMessageStatsLock.Unlock()
+
+	p.msgDispatcher.Dispatch(msg)
+}
+
+// rawNetMessage is queued for net-messages that have raw handlers, see Parser.RegisterRawNetMessageHandler().
+type rawNetMessage struct {
+	id      int32
+	payload []byte
+}
+
+type rawNetMessageHandler struct {
+	identifier dp.HandlerIdentifier
+	handle     func(tick int, payload []byte)
+}
+
+// rawNetMessageHandlers keeps track of the handlers registered via Parser.RegisterRawNetMessageHandler().
+type rawNetMessageHandlers struct {
+	lock     sync.RWMutex
+	handlers map[int32][]rawNetMessageHandler // Maps net-message-IDs to handlers in order of registration
+}
+
+func (h *rawNetMessageHandlers) add(id int32, handler func(tick int, payload []byte)) dp.HandlerIdentifier {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	if h.handlers == nil {
+		h.handlers = make(map[int32][]rawNetMessageHandler)
+	}
+
+	identifier := dp.HandlerIdentifier(new(int))
+	h.handlers[id] = append(h.handlers[id], rawNetMessageHandler{
+		identifier: identifier,
+		handle:     handler,
+	})
+
+	return identifier
+}
+
+// remove removes the handler with the given identifier, if it's a raw handler.
+func (h *rawNetMessageHandlers) remove(identifier dp.HandlerIdentifier) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	for id, handlers := range h.handlers {
+		// Not in-place, get() may have handed out the old slice
+		handlers = slices.DeleteFunc(slices.Clone(handlers), func(handler rawNetMessageHandler) bool {
+			return handler.identifier == identifier
+		})
+
+		if len(handlers) == 0 {
+			delete(h.handlers, id)
+		} else {
+			h.handlers[id] = handlers
+		}
+	}
+}
+
+func (h *rawNetMessageHandlers) removeAll() {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	clear(h.handlers)
+}
+
+func (h *rawNetMessageHandlers) has(id int32) bool {
+	h.lock.RLock()
+	defer h.lock.RUnlock()
+
+	return len(h.handlers[id]) > 0
+}
+
+// get returns the handlers of the given net-message-ID.
+// The returned slice is never modified, so handlers may (un-)register other handlers while it's being iterated.
+func (h *rawNetMessageHandlers) get(id int32) []rawNetMessageHandler {
+	h.lock.RLock()
+	defer h.lock.RUnlock()
+
+	return h.handlers[id]
+}
+
+func (p *parser) handleRawNetMessage(m rawNetMessage) {
+	for _, handler := range p.rawNetMessageHandlers.get(m.id) {
+		handler.handle(p.gameState.ingameTick, m.payload)
+	}
+}
+
+func (p *parser) handleUnknownNetMessage(e events.UnknownNetMessage) {
+	p.eventDispatcher.Dispatch(e)
+}
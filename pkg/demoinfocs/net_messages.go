@@ -1,7 +1,9 @@
 package demoinfocs
 
 import (
+	"context"
 	"fmt"
+	"runtime/trace"
 
 	"github.com/markus-wa/go-unassert"
 
@@ -15,11 +17,30 @@ func (p *parser) onEntity(e sendtables.Entity, op sendtables.EntityOp) error {
 		p.gameState.entities[e.ID()] = e
 	} else if op&sendtables.EntityOpDeleted > 0 {
 		delete(p.gameState.entities, e.ID())
+		delete(p.entitiesOutsidePVS, e.ID())
+	} else if op&sendtables.EntityOpLeft > 0 {
+		p.entitiesOutsidePVS[e.ID()] = struct{}{}
+
+		if p.isPOV() {
+			p.eventDispatcher.Dispatch(events.EntityLeftPVS{Entity: e})
+		}
+	} else if op&sendtables.EntityOpEntered > 0 {
+		delete(p.entitiesOutsidePVS, e.ID())
+
+		if p.isPOV() {
+			p.eventDispatcher.Dispatch(events.EntityEnteredPVS{Entity: e})
+		}
 	}
 
 	return nil
 }
 
+func (p *parser) handlePacketEntities(m *msg.CSVCMsg_PacketEntities) error {
+	defer trace.StartRegion(context.Background(), "demoinfocs.packetEntities").End()
+
+	return p.stParser.OnPacketEntities(m)
+}
+
 func (p *parser) handleSetConVar(setConVar *msg.CNETMsg_SetConVar) {
 	updated := make(map[string]string)
 	for _, cvar := range setConVar.Convars.Cvars {
@@ -33,9 +54,11 @@ func (p *parser) handleSetConVar(setConVar *msg.CNETMsg_SetConVar) {
 }
 
 func (p *parser) handleServerInfo(srvInfo *msg.CSVCMsg_ServerInfo) {
-	// srvInfo.MapCrc might be interesting as well
 	p.tickInterval = srvInfo.GetTickInterval()
 
+	info := newServerInfo(srvInfo)
+	p.serverInfo = &info
+
 	p.eventDispatcher.Dispatch(events.TickRateInfoAvailable{
 		TickRate: p.TickRate(),
 		TickTime: p.TickTime(),
@@ -1,12 +1,15 @@
 package demoinfocs
 
 import (
+	"context"
 	_ "embed"
 	"fmt"
 	"io"
 	"os"
+	"reflect"
 	"runtime/debug"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/golang/geo/r3"
@@ -20,9 +23,10 @@ import (
 	"github.com/markus-wa/demoinfocs-golang/v5/pkg/demoinfocs/events"
 	"github.com/markus-wa/demoinfocs-golang/v5/pkg/demoinfocs/msg"
 	st "github.com/markus-wa/demoinfocs-golang/v5/pkg/demoinfocs/sendtables"
+	"github.com/markus-wa/demoinfocs-golang/v5/pkg/demoinfocs/sendtables/sendtablescs2"
 )
 
-//go:generate ifacemaker -f parser.go -f parsing.go -s parser -i Parser -p demoinfocs -D -y "Parser is an auto-generated interface for Parser, intended to be used when mockability is needed." -c "DO NOT EDIT: Auto generated" -o parser_interface.go
+//go:generate ifacemaker -f parser.go -f parsing.go -f recovery.go -f seek.go -f checkpoint.go -f metrics.go -f envelope.go -f pov.go -s parser -i Parser -p demoinfocs -D -y "Parser is an auto-generated interface for Parser, intended to be used when mockability is needed." -c "DO NOT EDIT: Auto generated" -o parser_interface.go
 
 type sendTableParser interface {
 	ReadEnterPVS(r *bit.BitReader, index int, entities map[int]st.Entity, slot int) st.Entity
@@ -33,6 +37,9 @@ type sendTableParser interface {
 	OnServerInfo(m *msg.CSVCMsg_ServerInfo) error
 	OnPacketEntities(m *msg.CSVCMsg_PacketEntities) error
 	OnEntity(h st.EntityHandler)
+	ResetEntities() error
+	SetEntityFilter(filter sendtablescs2.EntityFilter)
+	OnEntitiesDecoded(h func(time.Duration))
 }
 
 // header contains information from a demo's header.
@@ -71,6 +78,63 @@ func (h *header) FrameTime() time.Duration {
 	return time.Duration(h.PlaybackTime.Nanoseconds() / int64(h.PlaybackFrames))
 }
 
+// Header contains information from a demo's CDemoFileHeader message, see Parser.Header().
+type Header struct {
+	MapName         string // E.g. de_ancient, de_nuke, cs_office, etc.
+	ServerName      string // Server's 'hostname' config value
+	ClientName      string // Usually 'SourceTV Demo', name of the recording player for POV demos
+	GameDirectory   string // Usually 'csgo'
+	NetworkProtocol int    // Network protocol version of the game
+	BuildNum        int    // Build number of the game
+	DemoVersionName string // Name of the demo format version
+	DemoVersionGUID string // GUID of the demo format version
+}
+
+func newHeader(h *msg.CDemoFileHeader) Header {
+	return Header{
+		MapName:         h.GetMapName(),
+		ServerName:      h.GetServerName(),
+		ClientName:      h.GetClientName(),
+		GameDirectory:   h.GetGameDirectory(),
+		NetworkProtocol: int(h.GetNetworkProtocol()),
+		BuildNum:        int(h.GetBuildNum()),
+		DemoVersionName: h.GetDemoVersionName(),
+		DemoVersionGUID: h.GetDemoVersionGuid(),
+	}
+}
+
+// ServerInfo contains information from the CSVCMsg_ServerInfo net-message, see Parser.ServerInfo().
+//
+// Unlike CS:GO, CS2 doesn't send a map CRC anymore.
+// Header.BuildNum together with MapName and the game session config can be used to identify map versions instead.
+type ServerInfo struct {
+	MapName           string                                // E.g. de_ancient, de_nuke, cs_office, etc.
+	HostName          string                                // Server's 'hostname' config value
+	GameDirectory     string                                // Usually 'csgo'
+	AddonName         string                                // Workshop addon(s) that were mounted, if any
+	MaxClients        int                                   // Maximum amount of clients the server allowed
+	MaxClasses        int                                   // Amount of server-classes
+	PlayerSlot        int                                   // Player slot of the recording client, -1 if unknown
+	IsDedicated       bool                                  // Whether the server was a dedicated server
+	IsHLTV            bool                                  // Whether the demo was recorded by GOTV
+	GameSessionConfig *msg.CSVCMsg_GameSessionConfiguration // May be nil
+}
+
+func newServerInfo(srvInfo *msg.CSVCMsg_ServerInfo) ServerInfo {
+	return ServerInfo{
+		MapName:           srvInfo.GetMapName(),
+		HostName:          srvInfo.GetHostName(),
+		GameDirectory:     srvInfo.GetGameDir(),
+		AddonName:         srvInfo.GetAddonName(),
+		MaxClients:        int(srvInfo.GetMaxClients()),
+		MaxClasses:        int(srvInfo.GetMaxClasses()),
+		PlayerSlot:        int(srvInfo.GetPlayerSlot()),
+		IsDedicated:       srvInfo.GetIsDedicated(),
+		IsHLTV:            srvInfo.GetIsHltv(),
+		GameSessionConfig: srvInfo.GetGameSessionConfig(),
+	}
+}
+
 /*
 Parser can parse a CS:GO demo.
 Creating a new instance is done via NewParser().
@@ -103,10 +167,12 @@ type parser struct {
 	msgQueue                        chan any                  // Queue of net-messages
 	msgDispatcher                   *dp.Dispatcher            // Net-message dispatcher
 	gameEventHandler                gameEventHandler
-	eventDispatcher                 *dp.Dispatcher
-	currentFrame                    int     // Demo-frame, not ingame-tick
-	tickInterval                    float32 // Duration between ticks in seconds
-	header                          *header // Pointer so we can check for nil
+	eventDispatcher                 *eventDispatcher
+	currentFrame                    int         // Demo-frame, not ingame-tick
+	tickInterval                    float32     // Duration between ticks in seconds
+	header                          *header     // Pointer so we can check for nil
+	demoHeader                      *Header     // Contents of CDemoFileHeader, nil until the first frame has been parsed
+	serverInfo                      *ServerInfo // Contents of CSVCMsg_ServerInfo, nil until received
 	gameState                       *gameState
 	demoInfoProvider                demoInfoProvider // Provides demo infos to other packages that the core package depends on
 	err                             error            // Contains a error that occurred during parsing if any
@@ -119,6 +185,28 @@ type parser struct {
 	 */
 	recordingPlayerSlot           int
 	disableMimicSource1GameEvents bool
+	demoSeeker                    io.ReadSeeker             // Set if the demo stream supports seeking, see SeekToTick()
+	cstvReader                    *cstv.Reader              // Set if the demo stream is a CSTV broadcast, see Checkpoint()
+	demoStartOffset               int64                     // Position of the demo inside demoSeeker
+	demoCloser                    io.Closer                 // The original BitReader, closes the demo once seeking replaced bitReader
+	keyframes                     []keyframe                // Positions of DEM_FullPacket frames, built on the first seek
+	roundStartTicks               map[int]int               // Maps round numbers to the ingame tick at which they started
+	framesRead                    int                       // Number of frames read by parseFrame(), unlike currentFrame this isn't updated asynchronously
+	framePos                      framePosition             // Position of the frame that is currently being read, for ParseError
+	bitReaderOffset               int64                     // Byte offset of the start of bitReader from the start of the demo
+	entityClassDependencies       map[string]string         // Maps server class names to the game state feature that depends on them, see ParserConfig.EntityClasses
+	recoveryStats                 RecoveryStats             // Frames & ticks skipped in RecoveryMode
+	resyncPending                 atomic.Bool               // Set by the message queue when a PacketEntities message was corrupt in RecoveryMode
+	netMessageConsumers           netMessageConsumers       // Handler types of msgDispatcher, net-messages without handlers aren't unmarshalled
+	netMessageStats               map[int32]*netMessageStat // Maps net-message-IDs to read & decode counts, see NetMessageCounts()
+	netMessageStatsLock           sync.Mutex                // Used to sync up NetMessageCounts() with the parsing go-routine
+	rawNetMessageHandlers         rawNetMessageHandlers     // See RegisterRawNetMessageHandler()
+	journal                       frameJournal              // Frames needed for Checkpoint(), only recorded if ParserConfig.EnableCheckpoints is set
+	growingFile                   *growingFileReader        // Input of DemoFormatGrowingFile until the BitReader is created, see ensureGrowingFileBitReader()
+	metrics                       *metrics                  // Only set if ParserConfig.Metrics is enabled
+	tickDonePending               bool                      // Whether TickDone still has to be dispatched for the current ingame tick
+	delayedEventOrigin            *eventOrigin              // Origin of the delayed game event that is currently being dispatched, see RegisterEnvelopeHandler()
+	entitiesOutsidePVS            map[int]struct{}          // Entities that left the PVS of the recording player, see IsEntityInPVS()
 
 	// Additional fields, mainly caching & tracking things
 
@@ -241,6 +329,37 @@ func (p *parser) Progress() float32 {
 	return float32(p.currentFrame) / float32(p.header.PlaybackFrames)
 }
 
+// Header returns the information from the demo's CDemoFileHeader message (map, server, build etc.).
+//
+// Returns nil if the header hasn't been parsed yet, it's available after the first frame.
+func (p *parser) Header() *Header {
+	if p.demoHeader == nil {
+		return nil
+	}
+
+	h := *p.demoHeader
+
+	return &h
+}
+
+// ServerInfo returns the information from the CSVCMsg_ServerInfo net-message (max clients, player slot etc.).
+//
+// Returns nil if the message hasn't been received yet, it's usually sent within the first few frames.
+func (p *parser) ServerInfo() *ServerInfo {
+	if p.serverInfo == nil {
+		return nil
+	}
+
+	info := *p.serverInfo
+
+	// the message is shared with net-message handlers and must not be modified through the copy
+	if info.GameSessionConfig != nil {
+		info.GameSessionConfig = proto.Clone(info.GameSessionConfig).(*msg.CSVCMsg_GameSessionConfiguration)
+	}
+
+	return &info
+}
+
 /*
 RegisterEventHandler registers a handler for game events.
 
@@ -254,11 +373,43 @@ Example:
 	})
 
 Parameter handler has to be of type any because Go generics only work on functions, not methods.
+See On() for a type-safe alternative.
+
+The handler has priority 0, see RegisterEventHandlerWithPriority() for the order in which handlers are called.
 
 Returns an identifier with which the handler can be removed via UnregisterEventHandler().
 */
 func (p *parser) RegisterEventHandler(handler any) dp.HandlerIdentifier {
-	return p.eventDispatcher.RegisterHandler(handler)
+	return p.RegisterEventHandlerWithPriority(handler, 0)
+}
+
+/*
+RegisterEventHandlerWithPriority registers a handler for game events, like RegisterEventHandler(),
+that is called before handlers with a lower priority.
+
+Handlers of an event are called in order of descending priority,
+handlers with the same priority are called in the order in which they were registered.
+This applies to all handlers that accept the event, regardless of whether they
+handle the event type itself or an interface (e.g. func(any) or func(events.GrenadeEventIf)).
+RegisterEventHandler() & On() use priority 0, negative priorities can be used to run after them.
+
+Each event is passed to all of its handlers before the next event is dispatched.
+Events are dispatched in the order in which the parser encounters them, except for some game events
+that are delayed until the end of the frame so that the entity state they depend on is up to date
+(e.g. RoundStart, RoundEnd, PlayerFlashed, ItemPickup & InfernoStart, and most player & bomb events before players are connected).
+These are dispatched after all other events of the frame and before events.FrameDone,
+in the order in which they occurred - regardless of the priority of their handlers.
+
+Handlers that are registered or unregistered by a handler take effect from the next dispatched event.
+
+Returns an identifier with which the handler can be removed via UnregisterEventHandler().
+*/
+func (p *parser) RegisterEventHandlerWithPriority(handler any, priority int) dp.HandlerIdentifier {
+	if p.metrics != nil {
+		return p.metrics.registerEventHandler(p.eventDispatcher, handler, priority)
+	}
+
+	return p.eventDispatcher.RegisterHandlerWithPriority(handler, priority)
 }
 
 // UnregisterEventHandler removes a game event handler via identifier.
@@ -274,13 +425,19 @@ RegisterNetMessageHandler registers a handler for net-messages.
 The handler must be of type func(*<MessageType>) where MessageType is the kind of net-message to be handled.
 
 Parameter handler has to be of type any because Go generics only work on functions, not methods.
+See OnNetMessage() for a type-safe alternative.
+
+Net-messages that no handler accepts aren't unmarshalled, see NetMessageCounts().
 
 Returns an identifier with which the handler can be removed via UnregisterNetMessageHandler().
 
 See also: RegisterEventHandler()
 */
 func (p *parser) RegisterNetMessageHandler(handler any) dp.HandlerIdentifier {
-	return p.msgDispatcher.RegisterHandler(handler)
+	identifier := p.msgDispatcher.RegisterHandler(handler)
+	p.netMessageConsumers.add(identifier, handler)
+
+	return identifier
 }
 
 // UnregisterNetMessageHandler removes a net-message handler via identifier.
@@ -288,6 +445,104 @@ func (p *parser) RegisterNetMessageHandler(handler any) dp.HandlerIdentifier {
 // The identifier is returned at registration by RegisterNetMessageHandler().
 func (p *parser) UnregisterNetMessageHandler(identifier dp.HandlerIdentifier) {
 	p.msgDispatcher.UnregisterHandler(identifier)
+	p.netMessageConsumers.remove(identifier)
+	p.rawNetMessageHandlers.remove(identifier)
+}
+
+/*
+RegisterRawNetMessageHandler registers a handler for the raw (still encoded) payload of net-messages with the given ID.
+The handler receives the current ingame tick and the protobuf encoded payload of each such message.
+
+This works for any ID, including ones that the parser doesn't know about (e.g. new messages after a game update),
+and makes it possible to experiment with them without regenerating the protobuf messages in the msg package.
+Unknown net-messages that have no raw handler are dispatched as events.UnknownNetMessage instead.
+
+Returns an identifier with which the handler can be removed via UnregisterNetMessageHandler().
+
+See also: RegisterNetMessageHandler() & ParserConfig.AdditionalNetMessageCreators
+*/
+func (p *parser) RegisterRawNetMessageHandler(id int32, handler func(tick int, payload []byte)) dp.HandlerIdentifier {
+	return p.rawNetMessageHandlers.add(id, handler)
+}
+
+// NetMessageCount contains how often net-messages of a type have been read and unmarshalled, see Parser.NetMessageCounts().
+type NetMessageCount struct {
+	Name    string // Name of the protobuf message, e.g. CSVCMsg_ServerInfo
+	Read    int    // Amount of messages that have been read from the demo
+	Decoded int    // Amount of messages that have been unmarshalled, less than Read if there were no handlers for some of them
+}
+
+/*
+NetMessageCounts returns how many net-messages of each type have been read and unmarshalled so far, keyed by net-message-ID.
+
+Net-messages are only unmarshalled if a handler (internal or registered via RegisterNetMessageHandler()) accepts their type
+when they're dispatched, so Decoded may lag behind Read while the parser reads ahead of the handlers.
+*/
+func (p *parser) NetMessageCounts() map[int32]NetMessageCount {
+	p.netMessageStatsLock.Lock()
+	defer p.netMessageStatsLock.Unlock()
+
+	counts := make(map[int32]NetMessageCount, len(p.netMessageStats))
+
+	for id, stat := range p.netMessageStats {
+		counts[id] = stat.NetMessageCount
+	}
+
+	return counts
+}
+
+/*
+On registers a type-safe handler for game events of type E.
+
+Example:
+
+	demoinfocs.On(parser, func(e events.WeaponFire) {
+		fmt.Printf("%s fired his %s\n", e.Shooter.Name, e.Weapon.Type)
+	})
+
+E must be one of the events from the events package (see events.Types())
+or an interface that is implemented by at least one of them, like events.GrenadeEventIf or any.
+Panics if E isn't a known event type (e.g. *events.Kill instead of events.Kill), as such a handler would never be called.
+
+Returns an identifier with which the handler can be removed via Parser.UnregisterEventHandler().
+*/
+func On[E any](p Parser, handler func(E)) dp.HandlerIdentifier {
+	mustBeEventType[E]()
+
+	return p.RegisterEventHandler(handler)
+}
+
+// OnWithPriority is like On() but registers the handler with a priority,
+// see Parser.RegisterEventHandlerWithPriority() for the order in which handlers are called.
+func OnWithPriority[E any](p Parser, handler func(E), priority int) dp.HandlerIdentifier {
+	mustBeEventType[E]()
+
+	return p.RegisterEventHandlerWithPriority(handler, priority)
+}
+
+func mustBeEventType[E any]() {
+	t := reflect.TypeFor[E]()
+
+	if !events.IsHandlerType(t) {
+		panic(fmt.Sprintf("%v is not a known event type (see events.Types())", t))
+	}
+}
+
+/*
+OnNetMessage registers a type-safe handler for net-messages of type M, e.g. *msg.CSVCMsg_ServerInfo.
+
+Example:
+
+	demoinfocs.OnNetMessage(parser, func(m *msg.CSVCMsg_ServerInfo) {
+		fmt.Println("max clients:", m.GetMaxClients())
+	})
+
+Returns an identifier with which the handler can be removed via Parser.UnregisterNetMessageHandler().
+
+See also: On() & ParserConfig.AdditionalNetMessageCreators
+*/
+func OnNetMessage[M proto.Message](p Parser, handler func(M)) dp.HandlerIdentifier {
+	return p.RegisterNetMessageHandler(handler)
 }
 
 // Close closes any open resources used by the Parser (go routines, file handles).
@@ -301,6 +556,15 @@ func (p *parser) Close() error {
 		if err != nil {
 			return errors.Wrap(err, "failed to close BitReader")
 		}
+	} else if p.growingFile != nil {
+		return p.growingFile.Close()
+	}
+
+	if p.demoCloser != nil {
+		err := p.demoCloser.Close()
+		if err != nil {
+			return errors.Wrap(err, "failed to close demo")
+		}
 	}
 
 	return nil
@@ -343,6 +607,7 @@ func (p *parser) poolBitReader(r *bit.BitReader) {
 
 // NewParser creates a new Parser with the default configuration.
 // The demostream io.Reader (e.g. os.File or bytes.Reader) must provide demo data in the '.DEM' format.
+// gzip, bzip2 and zstd compressed demos are decompressed transparently, see also OpenDemo().
 //
 // See also: NewCustomParser() & DefaultParserConfig
 func NewParser(demostream io.Reader) Parser {
@@ -354,6 +619,12 @@ type DemoFormat byte
 const (
 	DemoFormatFile DemoFormat = iota
 	DemoFormatCSTVBroadcast
+
+	// DemoFormatGrowingFile is a '.dem' file that is still being written, e.g. by 'tv_record'.
+	// Instead of ending at the end of the file, the parser waits for new bytes until DEM_Stop is reached
+	// (see events.DemoStopped) or the file isn't written to for longer than ParserConfig.GrowingFileTimeout.
+	// Compression and seeking aren't supported, use Cancel() or ParseToEndContext() to stop waiting early.
+	DemoFormatGrowingFile
 )
 
 // NewCSTVBroadcastParser creates a new Parser for a live CSTV broadcast.
@@ -369,7 +640,20 @@ func NewCSTVBroadcastParser(baseUrl string) (Parser, error) {
 //
 // See also: NewParserWithConfig() & DefaultParserConfig
 func NewCSTVBroadcastParserWithConfig(baseUrl string, config ParserConfig) (Parser, error) {
-	r, err := cstv.NewReader(baseUrl, config.CSTVTimeout)
+	return NewCSTVBroadcastParserWithConfigContext(context.Background(), baseUrl, config)
+}
+
+// NewCSTVBroadcastParserWithConfigContext creates a new Parser for a live CSTV broadcast with a custom configuration.
+// All HTTP requests to the CSTV server are aborted once the context is done.
+//
+// See also: NewCSTVBroadcastParserWithConfig()
+func NewCSTVBroadcastParserWithConfigContext(ctx context.Context, baseUrl string, config ParserConfig) (Parser, error) {
+	opts := config.CSTVOptions
+	if opts.Backoff == nil {
+		opts.Backoff = cstv.DefaultBackoff(config.CSTVTimeout)
+	}
+
+	r, err := cstv.NewReaderWithOptions(ctx, baseUrl, opts)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create CSTV reader: %w", err)
 	}
@@ -386,17 +670,30 @@ type ParserCallback func(Parser) error
 //
 // Returns an error if the parser encounters an error.
 func ParseWithConfig(r io.Reader, config ParserConfig, configure ParserCallback) error {
+	return ParseWithConfigContext(context.Background(), r, config, configure)
+}
+
+// ParseWithConfigContext parses a demo from the given io.Reader with a custom configuration
+// until the end or until the context is done.
+// The handler is called with the Parser instance.
+//
+// Returns an error if the parser encounters an error or the context's error if it's done before the end.
+func ParseWithConfigContext(ctx context.Context, r io.Reader, config ParserConfig, configure ParserCallback) error {
 	p := NewParserWithConfig(r, config)
 	defer p.Close()
 
+	return parseWithContext(ctx, p, configure, "failed to parse demo")
+}
+
+func parseWithContext(ctx context.Context, p Parser, configure ParserCallback, errMsg string) error {
 	err := configure(p)
 	if err != nil {
 		return fmt.Errorf("failed to configure parser: %w", err)
 	}
 
-	err = p.ParseToEnd()
+	err = p.ParseToEndContext(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to parse demo: %w", err)
+		return fmt.Errorf("%s: %w", errMsg, err)
 	}
 
 	return nil
@@ -410,11 +707,29 @@ func Parse(r io.Reader, configure ParserCallback) error {
 	return ParseWithConfig(r, DefaultParserConfig, configure)
 }
 
+// ParseWithContext parses a demo from the given io.Reader until the end or until the context is done.
+// The handler is called with the Parser instance.
+//
+// Returns an error if the parser encounters an error or the context's error if it's done before the end.
+func ParseWithContext(ctx context.Context, r io.Reader, configure ParserCallback) error {
+	return ParseWithConfigContext(ctx, r, DefaultParserConfig, configure)
+}
+
 // ParseFileWithConfig parses a demo file at the given path with a custom configuration.
 // The handler is called with the Parser instance.
 //
 // Returns an error if the file can't be opened or if the parser encounters an error.
 func ParseFileWithConfig(path string, config ParserConfig, configure ParserCallback) error {
+	return ParseFileWithConfigContext(context.Background(), path, config, configure)
+}
+
+// ParseFileWithConfigContext parses a demo file at the given path with a custom configuration
+// until the end or until the context is done.
+// The handler is called with the Parser instance.
+//
+// Returns an error if the file can't be opened, if the parser encounters an error
+// or the context's error if it's done before the end.
+func ParseFileWithConfigContext(ctx context.Context, path string, config ParserConfig, configure ParserCallback) error {
 	f, err := os.Open(path)
 	if err != nil {
 		return fmt.Errorf("failed to open file: %w", err)
@@ -422,10 +737,11 @@ func ParseFileWithConfig(path string, config ParserConfig, configure ParserCallb
 
 	defer f.Close()
 
-	return ParseWithConfig(f, config, configure)
+	return ParseWithConfigContext(ctx, f, config, configure)
 }
 
 // ParseFile parses a demo file at the given path.
+// Compressed demos (e.g. '.dem.gz', '.dem.bz2' & '.dem.zst') are decompressed transparently.
 // The handler is called with the Parser instance.
 //
 // Returns an error if the file can't be opened or if the parser encounters an error.
@@ -433,30 +749,39 @@ func ParseFile(path string, configure ParserCallback) error {
 	return ParseFileWithConfig(path, DefaultParserConfig, configure)
 }
 
+// ParseFileWithContext parses a demo file at the given path until the end or until the context is done.
+// The handler is called with the Parser instance.
+//
+// Returns an error if the file can't be opened, if the parser encounters an error
+// or the context's error if it's done before the end.
+func ParseFileWithContext(ctx context.Context, path string, configure ParserCallback) error {
+	return ParseFileWithConfigContext(ctx, path, DefaultParserConfig, configure)
+}
+
 // ParseCSTVBroadcastWithConfig parses a live CSTV broadcast from the given base URL with a custom configuration.
 // The handler is called with the Parser instance.
 // The baseUrl is the base URL of the CSTV broadcast, e.g. "http://localhost:8080/s85568392932860274t1733091777".
 // Returns an error if the CSTV reader can't be created or if the parser encounters an error.
 // Note that the CSTV broadcast is a live stream and will not end until the broadcast ends.
 func ParseCSTVBroadcastWithConfig(baseUrl string, config ParserConfig, configure ParserCallback) error {
-	p, err := NewCSTVBroadcastParserWithConfig(baseUrl, config)
+	return ParseCSTVBroadcastWithConfigContext(context.Background(), baseUrl, config, configure)
+}
+
+// ParseCSTVBroadcastWithConfigContext parses a live CSTV broadcast from the given base URL with a custom configuration
+// until the broadcast ends or until the context is done.
+// The handler is called with the Parser instance.
+//
+// Returns an error if the CSTV reader can't be created, if the parser encounters an error
+// or the context's error if it's done before the end.
+func ParseCSTVBroadcastWithConfigContext(ctx context.Context, baseUrl string, config ParserConfig, configure ParserCallback) error {
+	p, err := NewCSTVBroadcastParserWithConfigContext(ctx, baseUrl, config)
 	if err != nil {
 		return fmt.Errorf("failed to create CSTV broadcast parser: %w", err)
 	}
 
 	defer p.Close()
 
-	err = configure(p)
-	if err != nil {
-		return fmt.Errorf("failed to configure parser: %w", err)
-	}
-
-	err = p.ParseToEnd()
-	if err != nil {
-		return fmt.Errorf("failed to parse CSTV broadcast: %w", err)
-	}
-
-	return nil
+	return parseWithContext(ctx, p, configure, "failed to parse CSTV broadcast")
 }
 
 // ParseCSTVBroadcast parses a live CSTV broadcast from the given base URL.
@@ -468,6 +793,14 @@ func ParseCSTVBroadcast(baseUrl string, configure ParserCallback) error {
 	return ParseCSTVBroadcastWithConfig(baseUrl, DefaultParserConfig, configure)
 }
 
+// ParseCSTVBroadcastWithContext parses a live CSTV broadcast from the given base URL
+// until the broadcast ends or until the context is done.
+//
+// See also: ParseCSTVBroadcast()
+func ParseCSTVBroadcastWithContext(ctx context.Context, baseUrl string, configure ParserCallback) error {
+	return ParseCSTVBroadcastWithConfigContext(ctx, baseUrl, DefaultParserConfig, configure)
+}
+
 // ParserConfig contains the configuration for creating a new Parser.
 type ParserConfig struct {
 	// MsgQueueBufferSize defines the size of the internal net-message queue.
@@ -481,6 +814,7 @@ type ParserConfig struct {
 
 	// AdditionalNetMessageCreators maps net-message-IDs to creators (instantiators).
 	// The creators should return a new instance of the correct protobuf-message type (from the msg package).
+	// They are only used for net-message-IDs that the parser doesn't know about, built-in message types can't be replaced.
 	// Interesting net-message-IDs can easily be discovered with the build-tag 'debugdemoinfocs'; when looking for 'UnhandledMessage'.
 	// Check out parsing.go to see which net-messages are already being parsed by default.
 	// This is a beta feature and may be changed or replaced without notice.
@@ -504,34 +838,103 @@ type ParserConfig struct {
 	// This is required as a workaround for some POV demos that seem to contain rare PacketEntities parsing issues.
 	IgnorePacketEntitiesPanic bool
 
+	// RecoveryMode tells the parser to skip corrupt frames & packets instead of returning an error.
+	// A ParserWarn with Type WarnTypeCorruptFrameSkipped is dispatched for each corrupt frame or PacketEntities message,
+	// afterwards all frames are skipped until the next DEM_FullPacket, from which entity state is restored.
+	// The amount of skipped frames & ticks is available via Parser.RecoveryStats().
+	// Truncated demos still return ErrUnexpectedEndOfDemo.
+	RecoveryMode bool
+
+	// EntityClasses declares which entities should be decoded, which can speed up parsing considerably.
+	// It maps server class names (e.g. "CCSPlayerPawn") or path.Match() patterns (e.g. "CWeapon*") to the properties
+	// that should be decoded for entities of matching classes, nil means all properties.
+	// Properties also match if they're nested inside a listed property (e.g. "m_pWeaponServices" includes "m_pWeaponServices.m_hMyWeapons").
+	// Entities of other classes are skipped, they aren't available via GameState().Entities() and don't trigger entity handlers.
+	// If EntityClasses is nil (default) all entities are decoded.
+	//
+	// Classes that the game state depends on are always decoded with all properties if they match.
+	// For each of them that doesn't match, a ParserWarn of type WarnTypeEntityClassSkipped is dispatched
+	// before events.DataTablesParsed, naming the parts of the game state that won't be available.
+	EntityClasses map[string][]string
+
+	// EnableCheckpoints tells the parser to keep the frames in memory that are needed for Parser.Checkpoint(),
+	// that's the frames that set up the demo and all frames since the last DEM_FullPacket.
+	// See also ResumeParser() & ResumeCSTVBroadcastParserWithConfig().
+	EnableCheckpoints bool
+
+	// Metrics tells the parser to collect metrics about the parsed messages, entity decoding and event handlers,
+	// see Parser.Stats(). This adds some overhead, especially for event handlers.
+	Metrics bool
+
 	// DemoFormat is the format of the demo file (e.g. ".dem" file or live CSTV broadcast).
 	Format DemoFormat
 
 	// CSTVTimeout is the timeout for CSTV broadcasts.
 	// It's the maximum time to retry for a response from the CSTV server, using an exponential backoff mechanism, starting at 1s.
-	// Only used when Format is DemoFormatCSTVBroadcast.
+	// Only used when Format is DemoFormatCSTVBroadcast and CSTVOptions.Backoff isn't set.
 	CSTVTimeout time.Duration
+
+	// CSTVOptions configures the HTTP requests to the CSTV server, e.g. a custom client, authorization headers or the retry policy.
+	// Only used by NewCSTVBroadcastParserWithConfig() and its variants.
+	CSTVOptions cstv.Options
+
+	// GrowingFileTimeout is the maximum time to wait for new bytes at the end of a demo file that is still being written,
+	// after which parsing fails with ErrGrowingFileTimeout. Zero means waiting until DEM_Stop is reached.
+	// Only used when Format is DemoFormatGrowingFile.
+	GrowingFileTimeout time.Duration
 }
 
 // DefaultParserConfig is the default Parser configuration used by NewParser().
 var DefaultParserConfig = ParserConfig{
 	MsgQueueBufferSize: -1,
 	CSTVTimeout:        10 * time.Second,
+	GrowingFileTimeout: 30 * time.Second,
 }
 
 // NewParserWithConfig returns a new Parser with a custom configuration.
+// Demos in DemoFormatFile are transparently decompressed like in NewParser().
+// For DemoFormatGrowingFile, demostream should be the file that is being written (e.g. os.File).
 //
 // See also: NewParser() & ParserConfig
 func NewParserWithConfig(demostream io.Reader, config ParserConfig) Parser {
-	var p parser
+	p := newParserWithoutInput(config)
+
+	switch config.Format {
+	case DemoFormatFile:
+		var err error
+
+		demostream, err = decompress(demostream)
+		p.setError(err)
+
+		if seeker, ok := demostream.(io.ReadSeeker); ok {
+			// Not all io.Seekers can actually seek (e.g. os.Stdin)
+			offset, err := seeker.Seek(0, io.SeekCurrent)
+			if err == nil {
+				p.demoSeeker = seeker
+				p.demoStartOffset = offset
+			}
+		}
 
-	// Init parser
-	p.config = config
-	if p.config.Format == DemoFormatFile {
 		p.bitReader = bit.NewLargeBitReader(demostream)
-	} else {
+
+	case DemoFormatGrowingFile:
+		// The BitReader is created once parsing starts, see ensureGrowingFileBitReader()
+		p.growingFile = newGrowingFileReader(demostream, config.GrowingFileTimeout, false)
+
+	default:
+		p.cstvReader, _ = demostream.(*cstv.Reader)
 		p.bitReader = bit.NewSmallBitReader(demostream)
 	}
+
+	return p
+}
+
+// newParserWithoutInput initialises a parser, the BitReader has to be set by the caller. See NewParserWithConfig().
+func newParserWithoutInput(config ParserConfig) *parser {
+	var p parser
+
+	// Init parser
+	p.config = config
 	p.equipmentMapping = make(map[st.ServerClass]common.EquipmentType)
 	p.rawPlayers = make(map[int]*common.PlayerInfo)
 	p.triggers = make(map[int]*boundingBoxInformation)
@@ -543,34 +946,57 @@ func NewParserWithConfig(demostream io.Reader, config ParserConfig) Parser {
 	p.bombsiteA.index = -1
 	p.bombsiteB.index = -1
 	p.recordingPlayerSlot = -1
+	p.roundStartTicks = make(map[int]int)
+	p.entityClassDependencies = make(map[string]string)
+	p.netMessageStats = make(map[int32]*netMessageStat)
+	p.entitiesOutsidePVS = make(map[int]struct{})
 	p.disableMimicSource1GameEvents = config.DisableMimicSource1Events
 	p.source2FallbackGameEventListBin = config.Source2FallbackGameEventListBin
 	p.ignorePacketEntitiesPanic = config.IgnorePacketEntitiesPanic
 
+	if config.Metrics {
+		p.metrics = newMetrics()
+	}
+
 	dispatcherCfg := dp.Config{
 		PanicHandler: func(v any) {
+			if handlerPanic, ok := v.(dp.ConsumerCodePanic); ok {
+				p.setError(newHandlerPanicError(handlerPanic))
+
+				return
+			}
+
 			p.setError(fmt.Errorf("%v\nstacktrace:\n%s", v, debug.Stack()))
 		},
 	}
 	p.msgDispatcher = dp.NewDispatcherWithConfig(dispatcherCfg)
-	p.eventDispatcher = dp.NewDispatcherWithConfig(dispatcherCfg)
-
-	p.msgDispatcher.RegisterHandler(p.handleGameEventList)
-	p.msgDispatcher.RegisterHandler(p.handleGameEvent)
-	p.msgDispatcher.RegisterHandler(p.handleServerInfo)
-	p.msgDispatcher.RegisterHandler(p.handleCreateStringTable)
-	p.msgDispatcher.RegisterHandler(p.handleUpdateStringTable)
-	p.msgDispatcher.RegisterHandler(p.handleSetConVar)
-	p.msgDispatcher.RegisterHandler(p.handleServerRankUpdate)
-	p.msgDispatcher.RegisterHandler(p.handleMessageSayText)
-	p.msgDispatcher.RegisterHandler(p.handleMessageSayText2)
-	p.msgDispatcher.RegisterHandler(p.handleSendTables)
-	p.msgDispatcher.RegisterHandler(p.handleFileInfo)
-	p.msgDispatcher.RegisterHandler(p.handleDemoFileHeader)
-	p.msgDispatcher.RegisterHandler(p.handleClassInfo)
-	p.msgDispatcher.RegisterHandler(p.handleStringTables)
-	p.msgDispatcher.RegisterHandler(p.handleFrameParsed)
-	p.msgDispatcher.RegisterHandler(p.gameState.handleIngameTickNumber)
+	p.eventDispatcher = newEventDispatcher()
+
+	p.RegisterNetMessageHandler(p.handleGameEventList)
+	p.RegisterNetMessageHandler(p.handleGameEvent)
+	p.RegisterNetMessageHandler(p.handleServerInfo)
+	p.RegisterNetMessageHandler(p.handleCreateStringTable)
+	p.RegisterNetMessageHandler(p.handleUpdateStringTable)
+	p.RegisterNetMessageHandler(p.handleSetConVar)
+	p.RegisterNetMessageHandler(p.handleServerRankUpdate)
+	p.RegisterNetMessageHandler(p.handleMessageSayText)
+	p.RegisterNetMessageHandler(p.handleMessageSayText2)
+	p.RegisterNetMessageHandler(p.handleSendTables)
+	p.RegisterNetMessageHandler(p.handleFileInfo)
+	p.RegisterNetMessageHandler(p.handleDemoFileHeader)
+	p.RegisterNetMessageHandler(p.handleClassInfo)
+	p.RegisterNetMessageHandler(p.handleStringTables)
+	p.RegisterNetMessageHandler(p.handleFrameParsed)
+	p.RegisterNetMessageHandler(p.handleIngameTickNumber)
+	p.RegisterNetMessageHandler(p.handleLastTickDone)
+	p.RegisterNetMessageHandler(p.handleRawNetMessage)
+	p.RegisterNetMessageHandler(p.handleUnknownNetMessage)
+	p.RegisterNetMessageHandler(p.handleDemoStopped)
+	p.msgDispatcher.RegisterHandler(p.handleEncodedNetMessage)
+
+	// Not registered as net-message consumers, user commands are only unmarshalled if there are PlayerInput handlers
+	p.msgDispatcher.RegisterHandler(p.handleDemoUserCmd)
+	p.msgDispatcher.RegisterHandler(p.handleUserCommands)
 
 	if config.MsgQueueBufferSize >= 0 {
 		p.initMsgQueue(config.MsgQueueBufferSize)
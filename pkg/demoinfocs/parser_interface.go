@@ -3,9 +3,13 @@
 package demoinfocs
 
 import (
+	"context"
 	_ "embed"
+	"io"
 	"time"
 
+	common "github.com/markus-wa/demoinfocs-golang/v5/pkg/demoinfocs/common"
+	events "github.com/markus-wa/demoinfocs-golang/v5/pkg/demoinfocs/events"
 	st "github.com/markus-wa/demoinfocs-golang/v5/pkg/demoinfocs/sendtables"
 	dp "github.com/markus-wa/godispatch"
 )
@@ -59,6 +63,14 @@ type Parser interface {
 	// Might not be 100% correct since it's just based on the reported tick count of the header.
 	// May always return 0 if the demo header is corrupt.
 	Progress() float32
+	// Header returns the information from the demo's CDemoFileHeader message (map, server, build etc.).
+	//
+	// Returns nil if the header hasn't been parsed yet, it's available after the first frame.
+	Header() *Header
+	// ServerInfo returns the information from the CSVCMsg_ServerInfo net-message (max clients, player slot etc.).
+	//
+	// Returns nil if the message hasn't been received yet, it's usually sent within the first few frames.
+	ServerInfo() *ServerInfo
 	/*
 	   RegisterEventHandler registers a handler for game events.
 
@@ -72,10 +84,35 @@ type Parser interface {
 	   	})
 
 	   Parameter handler has to be of type any because Go generics only work on functions, not methods.
+	   See On() for a type-safe alternative.
+
+	   The handler has priority 0, see RegisterEventHandlerWithPriority() for the order in which handlers are called.
 
 	   Returns an identifier with which the handler can be removed via UnregisterEventHandler().
 	*/
 	RegisterEventHandler(handler any) dp.HandlerIdentifier
+	/*
+	   RegisterEventHandlerWithPriority registers a handler for game events, like RegisterEventHandler(),
+	   that is called before handlers with a lower priority.
+
+	   Handlers of an event are called in order of descending priority,
+	   handlers with the same priority are called in the order in which they were registered.
+	   This applies to all handlers that accept the event, regardless of whether they
+	   handle the event type itself or an interface (e.g. func(any) or func(events.GrenadeEventIf)).
+	   RegisterEventHandler() & On() use priority 0, negative priorities can be used to run after them.
+
+	   Each event is passed to all of its handlers before the next event is dispatched.
+	   Events are dispatched in the order in which the parser encounters them, except for some game events
+	   that are delayed until the end of the frame so that the entity state they depend on is up to date
+	   (e.g. RoundStart, RoundEnd, PlayerFlashed, ItemPickup & InfernoStart, and most player & bomb events before players are connected).
+	   These are dispatched after all other events of the frame and before events.FrameDone,
+	   in the order in which they occurred - regardless of the priority of their handlers.
+
+	   Handlers that are registered or unregistered by a handler take effect from the next dispatched event.
+
+	   Returns an identifier with which the handler can be removed via UnregisterEventHandler().
+	*/
+	RegisterEventHandlerWithPriority(handler any, priority int) dp.HandlerIdentifier
 	// UnregisterEventHandler removes a game event handler via identifier.
 	//
 	// The identifier is returned at registration by RegisterEventHandler().
@@ -86,6 +123,9 @@ type Parser interface {
 	   The handler must be of type func(*<MessageType>) where MessageType is the kind of net-message to be handled.
 
 	   Parameter handler has to be of type any because Go generics only work on functions, not methods.
+	   See OnNetMessage() for a type-safe alternative.
+
+	   Net-messages that no handler accepts aren't unmarshalled, see NetMessageCounts().
 
 	   Returns an identifier with which the handler can be removed via UnregisterNetMessageHandler().
 
@@ -96,6 +136,26 @@ type Parser interface {
 	//
 	// The identifier is returned at registration by RegisterNetMessageHandler().
 	UnregisterNetMessageHandler(identifier dp.HandlerIdentifier)
+	/*
+	   RegisterRawNetMessageHandler registers a handler for the raw (still encoded) payload of net-messages with the given ID.
+	   The handler receives the current ingame tick and the protobuf encoded payload of each such message.
+
+	   This works for any ID, including ones that the parser doesn't know about (e.g. new messages after a game update),
+	   and makes it possible to experiment with them without regenerating the protobuf messages in the msg package.
+	   Unknown net-messages that have no raw handler are dispatched as events.UnknownNetMessage instead.
+
+	   Returns an identifier with which the handler can be removed via UnregisterNetMessageHandler().
+
+	   See also: RegisterNetMessageHandler() & ParserConfig.AdditionalNetMessageCreators
+	*/
+	RegisterRawNetMessageHandler(id int32, handler func(tick int, payload []byte)) dp.HandlerIdentifier
+	/*
+	   NetMessageCounts returns how many net-messages of each type have been read and unmarshalled so far, keyed by net-message-ID.
+
+	   Net-messages are only unmarshalled if a handler (internal or registered via RegisterNetMessageHandler()) accepts their type
+	   when they're dispatched, so Decoded may lag behind Read while the parser reads ahead of the handlers.
+	*/
+	NetMessageCounts() map[int32]NetMessageCount
 	// Close closes any open resources used by the Parser (go routines, file handles).
 	// This must be called before discarding the Parser to avoid memory leaks.
 	// Returns an error if closing of underlying resources fails.
@@ -105,6 +165,12 @@ type Parser interface {
 	//
 	// See also: ParseNextFrame() for other possible errors.
 	ParseToEnd() (err error)
+	// ParseToEndContext attempts to parse the demo until the end or until the context is done.
+	// Aborts and returns the context's error (e.g. context.DeadlineExceeded) if the context is done before the end.
+	// Message and event handlers that have already been queued are still called before it returns.
+	//
+	// See also: ParseToEnd()
+	ParseToEndContext(ctx context.Context) (err error)
 	// Cancel aborts ParseToEnd() and drains the internal event queues.
 	// No further events will be sent to event or message handlers after this.
 	Cancel()
@@ -114,9 +180,93 @@ type Parser interface {
 	   Returns true unless the demo command 'stop' or an error was encountered.
 
 	   May return ErrUnexpectedEndOfDemo for incomplete / corrupt demos.
-	   May panic if the demo is corrupt in some way.
+	   Returns a *ParseError with the position of the problem if the demo is corrupt in some way.
+	   Returns a *HandlerPanicError if an event or net-message handler panics.
 
 	   See also: ParseToEnd() for parsing the complete demo in one go (faster).
 	*/
 	ParseNextFrame() (moreFrames bool, err error)
+	// RecoveryStats returns the amount of frames & ticks that have been skipped because of corrupt data.
+	// Always returns zero values if ParserConfig.RecoveryMode isn't enabled.
+	RecoveryStats() RecoveryStats
+	/*
+	   SeekToTick moves the parser to the given ingame tick.
+
+	   Seeking requires the demo to be provided as io.ReadSeeker (e.g. os.File).
+	   On the first call the parser indexes all DEM_FullPacket frames (keyframes) of the demo.
+	   String tables and entity state are then restored from the closest keyframe before the tick
+	   and the following frames are replayed until the tick is reached.
+	   If the tick is ahead of the current position and no keyframe is closer, the parser just replays forward.
+
+	   No game events are dispatched for the replayed frames, net-message handlers are called as usual.
+	   After seeking, Parser.GameState() reflects the state at the given tick and parsing can be continued
+	   via ParseNextFrame() or ParseToEnd().
+
+	   Returns ErrSeekNotSupported if the demo isn't seekable
+	   and ErrSeekOutOfRange if the tick is before the first keyframe or after the end of the demo.
+	*/
+	SeekToTick(tick int) error
+	/*
+	   SeekToRound moves the parser to the start of the given round (starting at 1).
+
+	   Rounds that have already been parsed are seeked to via SeekToTick().
+	   Rounds that haven't been reached yet are searched for by replaying the demo forward.
+
+	   See SeekToTick() for requirements and possible errors.
+	*/
+	SeekToRound(round int) error
+	/*
+	   Checkpoint writes the current state of the parser to w, so parsing can be continued later via ResumeParser(),
+	   e.g. after a restart of the process.
+
+	   Requires ParserConfig.EnableCheckpoints.
+	   Must not be called from event or net-message handlers or while ParseToEnd() is running,
+	   call it between ParseNextFrame() calls instead.
+
+	   Checkpoints contain the frames that set up the demo (send tables, server classes, string tables etc.),
+	   the last DEM_FullPacket frame (keyframe) and all frames after it, the position in the demo and the ConVars.
+	   On resume, string tables, server classes and entity state are restored by replaying these frames
+	   (the same way as SeekToTick() does), which also restores the game state that is based on them (players, teams, rules, grenades etc.).
+
+	   For CSTV broadcasts the position is the fragment of the next frame (see cstv.Reader.Position()),
+	   which requires the Parser to read from a *cstv.Reader, e.g. via NewCSTVBroadcastParser().
+
+	   See also: ResumeParser() & ResumeCSTVBroadcastParserWithConfig()
+	*/
+	Checkpoint(w io.Writer) error
+	/*
+	   Stats returns the metrics that have been collected so far if ParserConfig.Metrics is set,
+	   otherwise all fields are empty.
+
+	   Intended to be called after parsing or between ParseNextFrame() calls,
+	   while ParseToEnd() is running the entity & event handler stats lag behind as they're collected asynchronously.
+	*/
+	Stats() Stats
+	/*
+	   RegisterEnvelopeHandler registers a handler that receives all game events wrapped in an events.Envelope,
+	   which contains the ingame tick, frame, round & time at which the event originated.
+
+	   Unlike calling GameState().IngameTick() etc. from within a handler,
+	   this also returns the correct values for game events that are delayed until the end of the frame.
+
+	   The handler has priority 0, see RegisterEventHandlerWithPriority() for the order in which handlers are called.
+
+	   Returns an identifier with which the handler can be removed via UnregisterEventHandler().
+	*/
+	RegisterEnvelopeHandler(handler func(events.Envelope)) dp.HandlerIdentifier
+	/*
+	   RecordingPlayer returns the player that recorded the demo if it's a locally recorded (POV) demo.
+
+	   Returns nil for GOTV demos, before the recording player has been detected (see events.POVRecordingPlayerDetected)
+	   and before the player's entity has been created.
+	*/
+	RecordingPlayer() *common.Player
+	/*
+	   IsEntityInPVS returns false if the entity with the given ID has left the PVS (potentially visible set) of the recording player.
+	   The properties of such entities (position, health etc.) aren't updated until they enter the PVS again, i.e. they may be stale.
+
+	   Entities usually only leave the PVS in locally recorded (POV) demos, GOTV demos contain the whole game.
+	   See also events.EntityLeftPVS and events.EntityEnteredPVS.
+	*/
+	IsEntityInPVS(entityID int) bool
 }
@@ -1,6 +1,8 @@
 package demoinfocs
 
 import (
+	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -10,6 +12,11 @@ import (
 
 	dispatch "github.com/markus-wa/godispatch"
 	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/markus-wa/demoinfocs-golang/v5/internal/demotest"
+	"github.com/markus-wa/demoinfocs-golang/v5/pkg/demoinfocs/events"
+	"github.com/markus-wa/demoinfocs-golang/v5/pkg/demoinfocs/msg"
 )
 
 func TestParser_CurrentFrame(t *testing.T) {
@@ -60,6 +67,371 @@ func TestParser_TickTime_FallbackToHeader(t *testing.T) {
 	assert.Equal(t, time.Duration(200)*time.Millisecond, p.TickTime())
 }
 
+func TestParser_Header(t *testing.T) {
+	p := NewParser(bytes.NewReader(demotest.New(t).Stop(0).Bytes()))
+	defer p.Close()
+
+	assert.Nil(t, p.Header())
+
+	_, err := p.ParseNextFrame()
+	assert.NoError(t, err)
+
+	assert.Equal(t, &Header{
+		MapName:         "de_test",
+		ServerName:      "test server",
+		ClientName:      "SourceTV Demo",
+		GameDirectory:   "csgo",
+		NetworkProtocol: 14070,
+		BuildNum:        10093,
+		DemoVersionGUID: "8e9d71ab-04a1-4c01-bb61-acfede27c046",
+	}, p.Header())
+}
+
+func TestParser_ServerInfo(t *testing.T) {
+	d := demotest.New(t)
+	d.Packet(0, demotest.NetMsg{Type: int32(msg.SVC_Messages_svc_ServerInfo), Msg: &msg.CSVCMsg_ServerInfo{
+		MaxClients:   proto.Int32(64),
+		MaxClasses:   proto.Int32(512),
+		TickInterval: proto.Float32(1. / 64),
+		MapName:      proto.String("de_test"),
+		IsHltv:       proto.Bool(true),
+		GameSessionConfig: &msg.CSVCMsg_GameSessionConfiguration{
+			Gamemode: proto.String("competitive"),
+		},
+	}})
+
+	p := NewParser(bytes.NewReader(d.Stop(1).Bytes()))
+	defer p.Close()
+
+	assert.Nil(t, p.ServerInfo())
+	assert.NoError(t, p.ParseToEnd())
+
+	info := p.ServerInfo()
+	assert.Equal(t, "de_test", info.MapName)
+	assert.Equal(t, 64, info.MaxClients)
+	assert.Equal(t, 512, info.MaxClasses)
+	assert.Equal(t, -1, info.PlayerSlot)
+	assert.True(t, info.IsHLTV)
+	assert.Equal(t, "competitive", info.GameSessionConfig.GetGamemode())
+	assert.Equal(t, float64(64), p.TickRate())
+
+	// returns a copy
+	info.MapName = "de_other"
+	info.GameSessionConfig.Gamemode = proto.String("casual")
+
+	assert.Equal(t, "de_test", p.ServerInfo().MapName)
+	assert.Equal(t, "competitive", p.ServerInfo().GameSessionConfig.GetGamemode())
+}
+
+func TestOn(t *testing.T) {
+	d := demotest.New(t).TickPacket(0).TickPacket(1)
+
+	p := NewParser(bytes.NewReader(d.Stop(2).Bytes()))
+	defer p.Close()
+
+	var (
+		frames int
+		ticks  int
+		all    int
+	)
+
+	On(p, func(events.FrameDone) {
+		frames++
+	})
+	On(p, func(events.TickDone) {
+		ticks++
+	})
+	On(p, func(any) {
+		all++
+	})
+
+	assert.NoError(t, p.ParseToEnd())
+	assert.Equal(t, 4, frames)
+	assert.Equal(t, 3, ticks)
+	assert.Equal(t, 7, all)
+}
+
+func TestParser_TickDone(t *testing.T) {
+	sayText := testSayTextMsg("hello")
+	d := demotest.New(t).Packet(1, sayText).Packet(1, sayText).TickPacket(2)
+
+	p := NewParser(bytes.NewReader(d.Stop(3).Bytes()))
+	defer p.Close()
+
+	var evs []string
+
+	p.RegisterEventHandler(func(e any) {
+		switch e := e.(type) {
+		case events.TickDone:
+			evs = append(evs, fmt.Sprintf("TickDone %d", e.Tick))
+		default:
+			evs = append(evs, fmt.Sprintf("%T", e))
+		}
+	})
+
+	assert.NoError(t, p.ParseToEnd())
+
+	// The header frame is at tick 0, both frames of tick 1 are done before TickDone
+	assert.Equal(t, []string{
+		"events.FrameDone", "TickDone 0",
+		"events.SayText", "events.FrameDone", "events.SayText", "events.FrameDone", "TickDone 1",
+		"events.FrameDone", "TickDone 2",
+		"events.FrameDone", "TickDone 3",
+	}, evs)
+}
+
+func TestOn_UnknownEventType(t *testing.T) {
+	p := NewParser(new(DevNullReader))
+	defer p.Close()
+
+	assert.Panics(t, func() {
+		On(p, func(*events.Kill) {})
+	})
+	assert.Panics(t, func() {
+		On(p, func(error) {})
+	})
+	assert.NotPanics(t, func() {
+		On(p, func(events.GrenadeEventIf) {})
+	})
+}
+
+func TestOnNetMessage(t *testing.T) {
+	d := demotest.New(t).TickPacket(0).TickPacket(1)
+
+	p := NewParser(bytes.NewReader(d.Stop(2).Bytes()))
+	defer p.Close()
+
+	var ticks []uint32
+
+	OnNetMessage(p, func(m *msg.CNETMsg_Tick) {
+		ticks = append(ticks, m.GetTick())
+	})
+
+	assert.NoError(t, p.ParseToEnd())
+	assert.Equal(t, []uint32{0, 1}, ticks)
+}
+
+func TestParser_NetMessageCounts_NoHandler(t *testing.T) {
+	d := demotest.New(t).TickPacket(0).TickPacket(1)
+	d.Packet(2, demotest.NetMsg{Type: int32(msg.SVC_Messages_svc_Print), Msg: &msg.CSVCMsg_Print{Text: proto.String("test")}})
+
+	p := NewParser(bytes.NewReader(d.Stop(3).Bytes()))
+	defer p.Close()
+
+	assert.NoError(t, p.ParseToEnd())
+	assert.Equal(t, map[int32]NetMessageCount{
+		int32(msg.NET_Messages_net_Tick):  {Name: "CNETMsg_Tick", Read: 2},
+		int32(msg.SVC_Messages_svc_Print): {Name: "CSVCMsg_Print", Read: 1},
+	}, p.NetMessageCounts())
+}
+
+func TestParser_NetMessageCounts_Handler(t *testing.T) {
+	d := demotest.New(t).TickPacket(0).TickPacket(1)
+	d.Packet(2, demotest.NetMsg{Type: int32(msg.SVC_Messages_svc_Print), Msg: &msg.CSVCMsg_Print{Text: proto.String("test")}})
+
+	p := NewParser(bytes.NewReader(d.Stop(3).Bytes()))
+	defer p.Close()
+
+	var texts []string
+
+	OnNetMessage(p, func(m *msg.CSVCMsg_Print) {
+		texts = append(texts, m.GetText())
+	})
+
+	id := OnNetMessage(p, func(*msg.CNETMsg_Tick) {})
+	p.UnregisterNetMessageHandler(id)
+
+	assert.NoError(t, p.ParseToEnd())
+	assert.Equal(t, []string{"test"}, texts)
+	assert.Equal(t, map[int32]NetMessageCount{
+		int32(msg.NET_Messages_net_Tick):  {Name: "CNETMsg_Tick", Read: 2},
+		int32(msg.SVC_Messages_svc_Print): {Name: "CSVCMsg_Print", Read: 1, Decoded: 1},
+	}, p.NetMessageCounts())
+}
+
+func TestParser_RegisterNetMessageHandler_WhileParsing(t *testing.T) {
+	d := demotest.New(t).TickPacket(0)
+	d.Packet(1, demotest.NetMsg{Type: int32(msg.SVC_Messages_svc_Print), Msg: &msg.CSVCMsg_Print{Text: proto.String("a")}})
+	d.Packet(2, demotest.NetMsg{Type: int32(msg.SVC_Messages_svc_Print), Msg: &msg.CSVCMsg_Print{Text: proto.String("b")}})
+
+	p := NewParserWithConfig(bytes.NewReader(d.Stop(3).Bytes()), ParserConfig{MsgQueueBufferSize: 1000})
+	defer p.Close()
+
+	var (
+		registered bool
+		texts      []string
+	)
+
+	On(p, func(events.FrameDone) {
+		if registered {
+			return
+		}
+
+		// the parser reads ahead of the handlers
+		assert.Eventually(t, func() bool {
+			return p.NetMessageCounts()[int32(msg.SVC_Messages_svc_Print)].Read == 2
+		}, time.Second, time.Millisecond)
+
+		OnNetMessage(p, func(m *msg.CSVCMsg_Print) {
+			texts = append(texts, m.GetText())
+		})
+
+		registered = true
+	})
+
+	assert.NoError(t, p.ParseToEnd())
+	assert.Equal(t, []string{"a", "b"}, texts)
+}
+
+func TestParser_NetMessageCounts_InterfaceHandler(t *testing.T) {
+	d := demotest.New(t).TickPacket(0).TickPacket(1)
+
+	p := NewParser(bytes.NewReader(d.Stop(2).Bytes()))
+	defer p.Close()
+
+	var msgs []proto.Message
+
+	p.RegisterNetMessageHandler(func(m proto.Message) {
+		if _, ok := m.(*msg.CNETMsg_Tick); ok {
+			msgs = append(msgs, m)
+		}
+	})
+
+	assert.NoError(t, p.ParseToEnd())
+	assert.Len(t, msgs, 2)
+	assert.Equal(t, 2, p.NetMessageCounts()[int32(msg.NET_Messages_net_Tick)].Decoded)
+}
+
+func TestEvents(t *testing.T) {
+	d := demotest.New(t).TickPacket(0).TickPacket(1)
+
+	p := NewParser(bytes.NewReader(d.Stop(2).Bytes()))
+	defer p.Close()
+
+	var ticks []int
+
+	for e, err := range Events(p) {
+		assert.NoError(t, err)
+
+		if _, ok := e.(events.FrameDone); ok {
+			ticks = append(ticks, p.GameState().IngameTick())
+		}
+	}
+
+	assert.Equal(t, []int{0, 0, 1, 2}, ticks)
+}
+
+func TestEvents_Break(t *testing.T) {
+	d := demotest.New(t).TickPacket(0).TickPacket(1)
+
+	p := NewParser(bytes.NewReader(d.Stop(2).Bytes()))
+	defer p.Close()
+
+	frames := 0
+
+	for _, err := range Events(p) {
+		assert.NoError(t, err)
+
+		frames++
+
+		break
+	}
+
+	assert.Equal(t, 1, frames)
+	assert.ErrorIs(t, p.ParseToEnd(), ErrCancelled)
+}
+
+func TestEvents_Error(t *testing.T) {
+	d := demotest.New(t).TickPacket(0)
+	d.RawFrame(msg.EDemoCommands_DEM_Packet, 1, []byte{0xff, 0xff})
+
+	p := NewParser(bytes.NewReader(d.Stop(2).Bytes()))
+	defer p.Close()
+
+	var (
+		frames int
+		errs   []error
+	)
+
+	for e, err := range Events(p) {
+		if err != nil {
+			errs = append(errs, err)
+		} else if _, ok := e.(events.FrameDone); ok {
+			frames++
+		}
+	}
+
+	assert.Equal(t, 2, frames)
+	assert.Len(t, errs, 1)
+
+	var parseErr *ParseError
+
+	assert.ErrorAs(t, errs[0], &parseErr)
+}
+
+func TestEventsOf(t *testing.T) {
+	d := demotest.New(t).TickPacket(0).TickPacket(1)
+
+	p := NewParser(bytes.NewReader(d.Stop(2).Bytes()))
+	defer p.Close()
+
+	frames := 0
+
+	for _, err := range EventsOf[events.FrameDone](p) {
+		assert.NoError(t, err)
+
+		frames++
+	}
+
+	assert.Equal(t, 4, frames)
+
+	assert.Panics(t, func() {
+		EventsOf[*events.Kill](p)
+	})
+}
+
+func TestParser_ParseToEndContext_Cancel(t *testing.T) {
+	d := demotest.New(t)
+	for tick := int32(0); tick < 100; tick++ {
+		d.TickPacket(tick)
+	}
+
+	p := NewParserWithConfig(bytes.NewReader(d.Stop(100).Bytes()), ParserConfig{MsgQueueBufferSize: 0})
+	defer p.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	frames := 0
+	p.RegisterEventHandler(func(events.FrameDone) {
+		frames++
+
+		if frames == 10 {
+			cancel()
+		}
+	})
+
+	err := p.ParseToEndContext(ctx)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Less(t, frames, 100)
+}
+
+func TestParseWithContext_DeadlineExceeded(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 0)
+	defer cancel()
+
+	called := false
+
+	err := ParseWithContext(ctx, bytes.NewReader(demotest.New(t).Stop(0).Bytes()), func(p Parser) error {
+		called = true
+
+		return nil
+	})
+
+	assert.True(t, called)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
 func TestParser_Progress_NoHeader(t *testing.T) {
 	assert.Zero(t, new(parser).Progress())
 	assert.Zero(t, (&parser{header: &header{}}).Progress())
@@ -70,10 +442,9 @@ func TestRecoverFromUnexpectedEOF(t *testing.T) {
 	assert.ErrorIs(t, recoverFromUnexpectedEOF(io.ErrUnexpectedEOF), ErrUnexpectedEndOfDemo)
 	assert.ErrorIs(t, recoverFromUnexpectedEOF(io.EOF), ErrUnexpectedEndOfDemo)
 
-	assert.Panics(t, func() {
-		r := recoverFromUnexpectedEOF(errors.New("test"))
-		assert.Failf(t, "expected panic, got recovery", "recovered value = '%v'", r)
-	})
+	err := errors.New("test")
+	assert.ErrorIs(t, recoverFromUnexpectedEOF(err), err)
+	assert.ErrorContains(t, recoverFromUnexpectedEOF("test"), "test")
 }
 
 type consumerCodePanicMock struct {
@@ -89,10 +460,195 @@ func (ucp consumerCodePanicMock) Value() any {
 }
 
 func TestRecoverFromPanic_ConsumerCodePanic(t *testing.T) {
-	assert.PanicsWithValue(t, 1, func() {
-		err := recoverFromUnexpectedEOF(consumerCodePanicMock{value: 1})
-		assert.Nil(t, err)
+	var panicErr *HandlerPanicError
+
+	assert.ErrorAs(t, recoverFromUnexpectedEOF(consumerCodePanicMock{value: consumerCodePanicMock{value: 1}}), &panicErr)
+	assert.Equal(t, 1, panicErr.Value)
+	assert.EqualError(t, recoverFromUnexpectedEOF(consumerCodePanicMock{value: 1}), "panic in handler: 1")
+
+	err := errors.New("test")
+	assert.ErrorIs(t, recoverFromUnexpectedEOF(consumerCodePanicMock{value: err}), err)
+}
+
+func TestParser_ParseToEnd_HandlerPanic(t *testing.T) {
+	for _, bufSize := range []int{0, -1} {
+		testParserHandlerPanic(t, bufSize)
+	}
+}
+
+func testParserHandlerPanic(t *testing.T, msgQueueBufferSize int) {
+	t.Helper()
+
+	d := demotest.New(t).TickPacket(0).TickPacket(1).Stop(2)
+	p := NewParserWithConfig(bytes.NewReader(d.Bytes()), ParserConfig{MsgQueueBufferSize: msgQueueBufferSize})
+
+	defer p.Close()
+
+	p.RegisterEventHandler(func(events.FrameDone) {
+		panic("test")
+	})
+
+	err := p.ParseToEnd()
+
+	var (
+		panicErr *HandlerPanicError
+		parseErr *ParseError
+	)
+
+	assert.ErrorAs(t, err, &panicErr)
+	assert.Equal(t, "test", panicErr.Value)
+	assert.NotEmpty(t, panicErr.Stack)
+	assert.False(t, errors.As(err, &parseErr))
+}
+
+func TestParser_ParseToEnd_ParseError_CorruptFrame(t *testing.T) {
+	d := demotest.New(t).TickPacket(0)
+	offset := d.Offset()
+	d.RawFrame(msg.EDemoCommands_DEM_Packet, 1, []byte{0xff, 0xff})
+
+	p := NewParser(bytes.NewReader(d.Stop(2).Bytes()))
+	defer p.Close()
+
+	err := p.ParseToEnd()
+
+	var parseErr *ParseError
+
+	assert.ErrorAs(t, err, &parseErr)
+	assert.Equal(t, msg.EDemoCommands_DEM_Packet, parseErr.Command)
+	assert.Equal(t, int32(-1), parseErr.NetMessageType)
+	assert.Equal(t, 1, parseErr.Tick)
+	assert.Equal(t, 2, parseErr.Frame)
+	assert.Equal(t, int64(offset), parseErr.Offset)
+}
+
+func TestParser_UnknownNetMessage(t *testing.T) {
+	nop := &msg.CNETMsg_NOP{}
+	d := demotest.New(t).Packet(0, demotest.NetMsg{Type: 9999, Msg: nop}).Packet(1, demotest.NetMsg{Type: 9999, Msg: nop})
+
+	p := NewParser(bytes.NewReader(d.Stop(2).Bytes()))
+	defer p.Close()
+
+	var (
+		unknown []events.UnknownNetMessage
+		warns   []events.ParserWarn
+	)
+
+	p.RegisterEventHandler(func(e events.UnknownNetMessage) {
+		unknown = append(unknown, e)
+	})
+	p.RegisterEventHandler(func(e events.ParserWarn) {
+		warns = append(warns, e)
 	})
+
+	assert.NoError(t, p.ParseToEnd())
+
+	payload, err := proto.Marshal(nop)
+	assert.NoError(t, err)
+
+	assert.Equal(t, []events.UnknownNetMessage{
+		{ID: 9999, Payload: payload},
+		{ID: 9999, Payload: payload},
+	}, unknown)
+	assert.Len(t, warns, 1)
+	assert.Equal(t, events.WarnType(events.WarnTypeUnknownNetMessage), warns[0].Type)
+	assert.Equal(t, NetMessageCount{Read: 2}, p.NetMessageCounts()[9999])
+}
+
+func TestParser_RegisterRawNetMessageHandler(t *testing.T) {
+	printMsg := &msg.CSVCMsg_Print{Text: proto.String("test")}
+	d := demotest.New(t).TickPacket(0).Packet(1, demotest.NetMsg{Type: 9999, Msg: printMsg})
+	d.Packet(2, demotest.NetMsg{Type: int32(msg.SVC_Messages_svc_Print), Msg: printMsg})
+
+	p := NewParser(bytes.NewReader(d.Stop(3).Bytes()))
+	defer p.Close()
+
+	type rawMsg struct {
+		id      int32
+		tick    int
+		payload []byte
+	}
+
+	var (
+		raw     []rawMsg
+		unknown int
+	)
+
+	for _, id := range []int32{9999, int32(msg.SVC_Messages_svc_Print)} {
+		p.RegisterRawNetMessageHandler(id, func(tick int, payload []byte) {
+			raw = append(raw, rawMsg{id, tick, payload})
+		})
+	}
+
+	p.UnregisterNetMessageHandler(p.RegisterRawNetMessageHandler(9999, func(int, []byte) {
+		t.Error("unregistered raw handler was called")
+	}))
+
+	p.RegisterEventHandler(func(events.UnknownNetMessage) {
+		unknown++
+	})
+
+	assert.NoError(t, p.ParseToEnd())
+
+	payload, err := proto.Marshal(printMsg)
+	assert.NoError(t, err)
+
+	assert.Equal(t, []rawMsg{
+		{9999, 1, payload},
+		{int32(msg.SVC_Messages_svc_Print), 2, payload},
+	}, raw)
+	assert.Zero(t, unknown)
+	assert.Zero(t, p.NetMessageCounts()[int32(msg.SVC_Messages_svc_Print)].Decoded, "raw handlers shouldn't cause unmarshalling")
+}
+
+func TestParser_AdditionalNetMessageCreators(t *testing.T) {
+	d := demotest.New(t).Packet(0,
+		demotest.NetMsg{Type: 9999, Msg: &msg.CSVCMsg_Print{Text: proto.String("test")}},
+		demotest.NetMsg{Type: int32(msg.SVC_Messages_svc_Print), Msg: &msg.CSVCMsg_Print{Text: proto.String("built-in")}},
+	)
+
+	p := NewParserWithConfig(bytes.NewReader(d.Stop(1).Bytes()), ParserConfig{
+		MsgQueueBufferSize: -1,
+		AdditionalNetMessageCreators: map[int]NetMessageCreator{
+			9999: func() proto.Message { return new(msg.CSVCMsg_Print) },
+			// Built-in creators can't be replaced
+			int(msg.SVC_Messages_svc_Print): func() proto.Message { return new(msg.CNETMsg_StringCmd) },
+		},
+	})
+	defer p.Close()
+
+	var (
+		texts    []string
+		replaced int
+	)
+
+	OnNetMessage(p, func(m *msg.CSVCMsg_Print) {
+		texts = append(texts, m.GetText())
+	})
+	OnNetMessage(p, func(*msg.CNETMsg_StringCmd) {
+		replaced++
+	})
+
+	assert.NoError(t, p.ParseToEnd())
+	assert.Equal(t, []string{"test", "built-in"}, texts)
+	assert.Zero(t, replaced)
+}
+
+func TestParser_ParseNextFrame_ParseError_Truncated(t *testing.T) {
+	b := demotest.New(t).TickPacket(0).Bytes()
+
+	p := NewParser(bytes.NewReader(b[:len(b)-2]))
+	defer p.Close()
+
+	_, err := p.ParseNextFrame()
+	assert.NoError(t, err)
+
+	_, err = p.ParseNextFrame()
+
+	var parseErr *ParseError
+
+	assert.ErrorIs(t, err, ErrUnexpectedEndOfDemo)
+	assert.ErrorAs(t, err, &parseErr)
+	assert.Equal(t, 1, parseErr.Frame)
 }
 
 func TestParser_SetError(t *testing.T) {
@@ -1,9 +1,13 @@
 package demoinfocs
 
 import (
+	"context"
 	"fmt"
 	"io"
+	"iter"
 	"math"
+	"runtime/debug"
+	"runtime/trace"
 	"time"
 
 	"github.com/golang/snappy"
@@ -38,13 +42,121 @@ var (
 	ErrInvalidFileType = errors.New("invalid File-Type; expecting HL2DEMO in the first 8 bytes (ErrInvalidFileType)")
 )
 
+// ParseError is returned when a frame or net-message of the demo can't be decoded.
+// It wraps the underlying error, so errors.Is() and errors.As() can be used with it
+// (e.g. errors.Is(err, ErrUnexpectedEndOfDemo) for truncated demos).
+type ParseError struct {
+	Err            error             // The underlying error
+	Command        msg.EDemoCommands // Demo command of the frame, without the DEM_IsCompressed flag
+	NetMessageType int32             // Net-message type ID, -1 if the error isn't caused by a specific net-message
+	Tick           int               // Ingame tick of the frame
+	Frame          int               // Number of the frame in the demo, starting at 0
+	Offset         int64             // Byte offset of the frame from the start of the demo
+}
+
+func (e *ParseError) Error() string {
+	if e.NetMessageType >= 0 {
+		return fmt.Sprintf("failed to parse net-message type %d of frame %d (%s, tick %d, offset %d): %v",
+			e.NetMessageType, e.Frame, e.Command, e.Tick, e.Offset, e.Err)
+	}
+
+	return fmt.Sprintf("failed to parse frame %d (%s, tick %d, offset %d): %v", e.Frame, e.Command, e.Tick, e.Offset, e.Err)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// HandlerPanicError is returned when an event or net-message handler panics.
+// It's never wrapped in a *ParseError as the demo itself isn't at fault.
+type HandlerPanicError struct {
+	Value any    // The value the handler panicked with
+	Stack []byte // Stack trace of the panic
+}
+
+// newHandlerPanicError unwraps the value of a panic that may have passed through multiple dispatchers.
+func newHandlerPanicError(p dispatch.ConsumerCodePanic) *HandlerPanicError {
+	v := p.Value()
+
+	for inner, ok := v.(dispatch.ConsumerCodePanic); ok; inner, ok = v.(dispatch.ConsumerCodePanic) {
+		v = inner.Value()
+	}
+
+	return &HandlerPanicError{Value: v, Stack: debug.Stack()}
+}
+
+func (e *HandlerPanicError) Error() string {
+	return fmt.Sprintf("panic in handler: %v", e.Value)
+}
+
+// Unwrap returns the value the handler panicked with if it's an error.
+func (e *HandlerPanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+
+	return err
+}
+
+// framePosition identifies the frame that is currently being read, see ParseError.
+type framePosition struct {
+	command msg.EDemoCommands
+	tick    int
+	frame   int
+	offset  int64
+}
+
+// newParseError wraps err in a *ParseError for the frame that is currently being read.
+// Errors that already are a *ParseError and handler panics are returned as is.
+func (p *parser) newParseError(err error, netMessageType int32) error {
+	if err == nil {
+		return nil
+	}
+
+	var (
+		parseErr *ParseError
+		panicErr *HandlerPanicError
+	)
+
+	if errors.As(err, &parseErr) || errors.As(err, &panicErr) {
+		return err
+	}
+
+	return p.framePos.parseError(err, netMessageType)
+}
+
+// parseError wraps err in a *ParseError for the frame at this position.
+func (pos framePosition) parseError(err error, netMessageType int32) *ParseError {
+	return &ParseError{
+		Err:            err,
+		Command:        pos.command,
+		NetMessageType: netMessageType,
+		Tick:           pos.tick,
+		Frame:          pos.frame,
+		Offset:         pos.offset,
+	}
+}
+
+// recoverFrameError converts a panic that happened while reading a frame into a *ParseError,
+// or a *HandlerPanicError if a handler panicked.
+func (p *parser) recoverFrameError(r any) error {
+	return p.newParseError(recoverFromUnexpectedEOF(r), -1)
+}
+
 // parseHeader attempts to parse the header of the demo and returns it.
 // If not done manually this will be called by Parser.ParseNextFrame() or Parser.ParseToEnd().
 //
 // Returns ErrInvalidFileType if the filestamp (first 8 bytes) doesn't match HL2DEMO.
 func (p *parser) parseHeader() (header, error) {
+	defer trace.StartRegion(context.Background(), "demoinfocs.parseHeader").End()
+
 	var h header
 
+	// E.g. the demo couldn't be decompressed
+	if err := p.error(); err != nil {
+		return h, err
+	}
+
+	p.ensureGrowingFileBitReader()
+
 	isCSTVBroadcast := p.config.Format == DemoFormatCSTVBroadcast
 
 	if isCSTVBroadcast {
@@ -64,7 +176,9 @@ func (p *parser) parseHeader() (header, error) {
 
 		var warnFunc func(error)
 
-		if p.ignorePacketEntitiesPanic {
+		if p.config.RecoveryMode {
+			warnFunc = p.onCorruptPacketEntities
+		} else if p.ignorePacketEntitiesPanic {
 			warnFunc = func(err error) {
 				p.eventDispatcher.Dispatch(events.ParserWarn{
 					Type:    events.WarnTypePacketEntitiesPanic,
@@ -77,8 +191,13 @@ func (p *parser) parseHeader() (header, error) {
 
 		p.stParser.OnEntity(p.onEntity)
 
+		if p.metrics != nil {
+			p.stParser.OnEntity(p.metrics.countEntityUpdate)
+			p.stParser.OnEntitiesDecoded(p.metrics.addEntityDecodingTime)
+		}
+
 		p.RegisterNetMessageHandler(p.stParser.OnServerInfo)
-		p.RegisterNetMessageHandler(p.stParser.OnPacketEntities)
+		p.RegisterNetMessageHandler(p.handlePacketEntities)
 
 	default:
 		return h, ErrInvalidFileType
@@ -95,6 +214,31 @@ func (p *parser) parseHeader() (header, error) {
 	return h, nil
 }
 
+// ensureMsgQueue re-creates the message queue if it has been closed after reaching the end of the demo.
+// This is needed to continue parsing after seeking backwards.
+func (p *parser) ensureMsgQueue() {
+	if p.msgQueue != nil {
+		return
+	}
+
+	if p.config.MsgQueueBufferSize >= 0 {
+		p.initMsgQueue(p.config.MsgQueueBufferSize)
+	} else {
+		p.initMsgQueue(msgQueueSize(p.header.PlaybackTicks))
+	}
+}
+
+// closeMsgQueue stops the goroutine handling the message queue, see ensureMsgQueue().
+// Message queues must be in sync when calling this.
+func (p *parser) closeMsgQueue() {
+	p.msgDispatcher.RemoveAllQueues()
+
+	if p.msgQueue != nil {
+		close(p.msgQueue)
+		p.msgQueue = nil
+	}
+}
+
 func msgQueueSize(ticks int) int {
 	const (
 		msgQueueMinSize = 50000
@@ -112,18 +256,29 @@ func msgQueueSize(ticks int) int {
 //
 // See also: ParseNextFrame() for other possible errors.
 func (p *parser) ParseToEnd() (err error) {
+	return p.ParseToEndContext(context.Background())
+}
+
+// ParseToEndContext attempts to parse the demo until the end or until the context is done.
+// Aborts and returns the context's error (e.g. context.DeadlineExceeded) if the context is done before the end.
+// Message and event handlers that have already been queued are still called before it returns.
+//
+// See also: ParseToEnd()
+func (p *parser) ParseToEndContext(ctx context.Context) (err error) {
 	defer func() {
 		// Make sure all the messages of the demo are handled
 		p.msgDispatcher.SyncAllQueues()
-		p.msgDispatcher.RemoveAllQueues()
-
-		// Close msgQueue
-		if p.msgQueue != nil {
-			close(p.msgQueue)
-		}
+		p.closeMsgQueue()
 
 		if err == nil {
-			err = recoverFromUnexpectedEOF(recover())
+			r := recover()
+
+			if r != nil && ctx.Err() != nil {
+				// Reading from the input (e.g. a CSTV broadcast) failed because the context is done
+				err = ctx.Err()
+			} else {
+				err = p.recoverFrameError(r)
+			}
 		}
 
 		// any errors that happened during SyncAllQueues()
@@ -136,6 +291,11 @@ func (p *parser) ParseToEnd() (err error) {
 		}
 	}()
 
+	if p.growingFile != nil {
+		// Stop waiting for new bytes once the context is done
+		defer context.AfterFunc(ctx, p.growingFile.interrupt)()
+	}
+
 	if p.header == nil {
 		_, err = p.parseHeader()
 		if err != nil {
@@ -143,7 +303,13 @@ func (p *parser) ParseToEnd() (err error) {
 		}
 	}
 
+	p.ensureMsgQueue()
+
 	for {
+		if err = ctx.Err(); err != nil {
+			return
+		}
+
 		if !p.parseFrame() {
 			return p.error()
 		}
@@ -154,6 +320,8 @@ func (p *parser) ParseToEnd() (err error) {
 	}
 }
 
+// recoverFromUnexpectedEOF converts a recovered panic into an error.
+// EOFs are reported as ErrUnexpectedEndOfDemo.
 func recoverFromUnexpectedEOF(r any) error {
 	if r == nil {
 		return nil
@@ -163,12 +331,20 @@ func recoverFromUnexpectedEOF(r any) error {
 		return errors.Wrap(ErrUnexpectedEndOfDemo, "unexpected EOF")
 	}
 
-	switch err := r.(type) {
+	switch v := r.(type) {
 	case dispatch.ConsumerCodePanic:
-		panic(err.Value())
+		return newHandlerPanicError(v)
+
+	case error:
+		// Errors of the input that aren't caused by the demo, see growingFileReader
+		if errors.Is(v, ErrGrowingFileTimeout) || errors.Is(v, ErrCancelled) {
+			return v
+		}
+
+		return fmt.Errorf("%w\nstacktrace:\n%s", v, debug.Stack())
 
 	default:
-		panic(err)
+		return fmt.Errorf("%v\nstacktrace:\n%s", v, debug.Stack())
 	}
 }
 
@@ -178,6 +354,12 @@ func (p *parser) Cancel() {
 	p.setError(ErrCancelled)
 	p.eventDispatcher.UnregisterAllHandlers()
 	p.msgDispatcher.UnregisterAllHandlers()
+	p.netMessageConsumers.removeAll()
+	p.rawNetMessageHandlers.removeAll()
+
+	if p.growingFile != nil {
+		p.growingFile.interrupt()
+	}
 }
 
 /*
@@ -186,7 +368,8 @@ ParseNextFrame attempts to parse the next frame / demo-tick (not ingame tick).
 Returns true unless the demo command 'stop' or an error was encountered.
 
 May return ErrUnexpectedEndOfDemo for incomplete / corrupt demos.
-May panic if the demo is corrupt in some way.
+Returns a *ParseError with the position of the problem if the demo is corrupt in some way.
+Returns a *HandlerPanicError if an event or net-message handler panics.
 
 See also: ParseToEnd() for parsing the complete demo in one go (faster).
 */
@@ -196,13 +379,12 @@ func (p *parser) ParseNextFrame() (moreFrames bool, err error) {
 		p.msgDispatcher.SyncAllQueues()
 
 		// Close msgQueue (only if we are done)
-		if p.msgQueue != nil && !moreFrames {
-			p.msgDispatcher.RemoveAllQueues()
-			close(p.msgQueue)
+		if !moreFrames {
+			p.closeMsgQueue()
 		}
 
 		if err == nil {
-			err = recoverFromUnexpectedEOF(recover())
+			err = p.recoverFrameError(recover())
 		}
 	}()
 
@@ -213,11 +395,107 @@ func (p *parser) ParseNextFrame() (moreFrames bool, err error) {
 		}
 	}
 
+	p.ensureMsgQueue()
+
 	moreFrames = p.parseFrame()
 
 	return moreFrames, p.error()
 }
 
+/*
+Events returns an iterator over the game events of the demo, in the order in which they are dispatched.
+Parsing continues from the current position while iterating and stops at the end of the demo.
+
+Example:
+
+	for e, err := range demoinfocs.Events(parser) {
+		if err != nil {
+			return err
+		}
+
+		if kill, ok := e.(events.Kill); ok {
+			fmt.Println(kill.Killer, "killed", kill.Victim)
+		}
+	}
+
+The demo is parsed frame by frame via Parser.ParseNextFrame(), so Parser.GameState() reflects the state at the end of the frame in which an event was dispatched
+(unlike in event handlers, which are called while the frame is being processed).
+If an error occurs it's yielded as the last element after all events that were dispatched before it.
+
+Stopping the iteration early (e.g. via break) cancels parsing via Parser.Cancel().
+
+See also: EventsOf()
+*/
+func Events(p Parser) iter.Seq2[any, error] {
+	return func(yield func(any, error) bool) {
+		var pending []any
+
+		handlerID := p.RegisterEventHandler(func(e any) {
+			pending = append(pending, e)
+		})
+		defer p.UnregisterEventHandler(handlerID)
+
+		for {
+			moreFrames, err := p.ParseNextFrame()
+
+			for _, e := range pending {
+				if !yield(e, nil) {
+					p.Cancel()
+
+					return
+				}
+			}
+
+			pending = pending[:0]
+
+			if err != nil {
+				yield(nil, err)
+
+				return
+			}
+
+			if !moreFrames {
+				return
+			}
+		}
+	}
+}
+
+/*
+EventsOf returns an iterator over the game events of type E, see Events().
+
+Example:
+
+	for kill, err := range demoinfocs.EventsOf[events.Kill](parser) {
+		if err != nil {
+			return err
+		}
+
+		fmt.Println(kill.Killer, "killed", kill.Victim)
+	}
+
+Like On(), this panics if E isn't a known event type.
+*/
+func EventsOf[E any](p Parser) iter.Seq2[E, error] {
+	mustBeEventType[E]()
+
+	return func(yield func(E, error) bool) {
+		for e, err := range Events(p) {
+			if err != nil {
+				var zero E
+
+				yield(zero, err)
+
+				return
+			}
+
+			if e, ok := e.(E); ok && !yield(e, nil) {
+				return
+			}
+		}
+	}
+}
+
 var demoCommandMsgsCreators = map[msg.EDemoCommands]NetMessageCreator{
 	msg.EDemoCommands_DEM_Stop:            func() proto.Message { return &msg.CDemoStop{} },
 	msg.EDemoCommands_DEM_FileHeader:      func() proto.Message { return &msg.CDemoFileHeader{} },
@@ -239,44 +517,99 @@ var demoCommandMsgsCreators = map[msg.EDemoCommands]NetMessageCreator{
 	msg.EDemoCommands_DEM_Recovery:        func() proto.Message { return &msg.CDemoRecovery{} },
 }
 
+// parseFrame reads and queues the next frame.
+// Returns false if the end of the demo has been reached or an error occurred (see p.error()).
 func (p *parser) parseFrame() bool {
-	cmd := msg.EDemoCommands(p.bitReader.ReadVarInt32())
+	if p.config.RecoveryMode {
+		return p.parseFrameWithRecovery()
+	}
 
-	msgType := cmd & ^msg.EDemoCommands_DEM_IsCompressed
-	msgCompressed := (cmd & msg.EDemoCommands_DEM_IsCompressed) != 0
+	more, err := p.processFrame(p.readFrameHeader())
+	if err != nil {
+		p.setError(err)
 
-	var (
-		tick uint32
-		size uint32
-	)
+		return false
+	}
 
-	isCSTVBroadcast := p.config.Format == DemoFormatCSTVBroadcast
+	return more
+}
 
-	if isCSTVBroadcast {
-		tick = uint32(p.bitReader.ReadInt(32))
+// frameHeader contains the command, tick and payload size of a frame.
+type frameHeader struct {
+	cmd  msg.EDemoCommands
+	tick uint32
+	size uint32
+}
 
-		p.bitReader.Skip(8)
+// readFrameHeader reads the header of the next frame and updates p.framePos.
+func (p *parser) readFrameHeader() frameHeader {
+	p.ensureGrowingFileBitReader()
 
-		if cmd == msg.EDemoCommands_DEM_Stop {
-			p.msgQueue <- ingameTickNumber(int32(tick))
-			p.msgQueue <- frameParsedToken
+	p.framePos = framePosition{
+		command: -1,
+		frame:   p.framesRead,
+		offset:  p.bitReaderOffset + int64(p.bitReader.ActualPosition()>>3),
+	}
 
-			return false
-		}
+	var h frameHeader
+
+	h.cmd = msg.EDemoCommands(p.bitReader.ReadVarInt32())
+	p.framePos.command = h.cmd & ^msg.EDemoCommands_DEM_IsCompressed
+
+	if p.config.Format == DemoFormatCSTVBroadcast {
+		h.tick = uint32(p.bitReader.ReadInt(32))
+
+		p.bitReader.Skip(8)
 
-		size = uint32(p.bitReader.ReadInt(32))
+		// DEM_Stop has no payload size in broadcasts
+		if h.cmd != msg.EDemoCommands_DEM_Stop {
+			h.size = uint32(p.bitReader.ReadInt(32))
+		}
 	} else {
-		tick = p.bitReader.ReadVarInt32()
+		h.tick = p.bitReader.ReadVarInt32()
 
 		// This appears to actually be an int32, where a -1 means pre-game.
-		if tick == 4294967295 {
-			tick = 0
+		if h.tick == 4294967295 {
+			h.tick = 0
 		}
 
-		size = p.bitReader.ReadVarInt32()
+		h.size = p.bitReader.ReadVarInt32()
 	}
 
-	p.msgQueue <- ingameTickNumber(int32(tick))
+	p.framePos.tick = int(h.tick)
+
+	return h
+}
+
+// processFrame reads the payload of a frame and queues it.
+// Returns false if the end of the demo has been reached.
+//
+//nolint:funlen,gocognit
+func (p *parser) processFrame(h frameHeader) (bool, error) {
+	defer func() {
+		p.framesRead++
+	}()
+
+	defer trace.StartRegion(context.Background(), "demoinfocs.processFrame").End()
+
+	msgType := h.cmd & ^msg.EDemoCommands_DEM_IsCompressed
+	msgCompressed := (h.cmd & msg.EDemoCommands_DEM_IsCompressed) != 0
+
+	if p.metrics != nil {
+		p.metrics.countDemoCommand(msgType, h.size)
+	}
+
+	isCSTVBroadcast := p.config.Format == DemoFormatCSTVBroadcast
+
+	if isCSTVBroadcast && h.cmd == msg.EDemoCommands_DEM_Stop {
+		p.msgQueue <- ingameTickNumber(int32(h.tick))
+		p.msgQueue <- frameParsedToken
+		p.msgQueue <- lastTickDoneToken
+
+		return false, nil
+	}
+
+	p.msgQueue <- ingameTickNumber(int32(h.tick))
 
 	msgCreator := demoCommandMsgsCreators[msgType]
 	if msgCreator == nil {
@@ -284,33 +617,35 @@ func (p *parser) parseFrame() bool {
 			Message: fmt.Sprintf("skipping unknown demo commands message type with value %d", msgType),
 			Type:    events.WarnUnknownDemoCommandMessageType,
 		})
-		p.bitReader.Skip(int(size) << 3)
+		p.bitReader.Skip(int(h.size) << 3)
 
-		return true
+		return true, nil
 	}
 
-	buf := p.bitReader.ReadBytes(int(size))
+	buf := p.bitReader.ReadBytes(int(h.size))
+
+	if p.config.EnableCheckpoints {
+		p.journal.record(h, buf)
+	}
 
 	if msgCompressed {
 		var err error
 
 		buf, err = snappy.Decode(nil, buf)
 		if err != nil {
-			if errors.Is(err, snappy.ErrCorrupt) {
+			if errors.Is(err, snappy.ErrCorrupt) && !p.config.RecoveryMode {
 				p.eventDispatcher.Dispatch(events.ParserWarn{
 					Message: "compressed message is corrupt",
 				})
 			} else {
-				panic(err)
+				return false, p.newParseError(errors.Wrap(err, "failed to decompress frame"), -1)
 			}
 		}
 	}
 
 	m := msgCreator()
 
-	if m == nil {
-		panic(fmt.Sprintf("Unknown demo command: %d", msgType))
-	}
+	var err error
 
 	if isCSTVBroadcast {
 		switch m := m.(type) {
@@ -321,36 +656,46 @@ func (p *parser) parseFrame() bool {
 			m.Msgs = [][]byte{buf[1:]} // TODO: index might be a varint, also we should collect all entries into one msg
 
 		default:
-			err := proto.Unmarshal(buf, m)
-			if err != nil {
-				panic(err) // FIXME: avoid panic
-			}
+			err = proto.Unmarshal(buf, m)
 		}
 	} else {
-		err := proto.Unmarshal(buf, m)
-		if err != nil {
-			panic(err) // FIXME: avoid panic
-		}
+		err = proto.Unmarshal(buf, m)
+	}
+
+	if err != nil {
+		return false, p.newParseError(errors.Wrap(err, "failed to unmarshal frame"), -1)
 	}
 
 	p.msgQueue <- m
 
 	switch m := m.(type) {
 	case *msg.CDemoPacket:
-		p.handleDemoPacket(m)
+		err = p.handleDemoPacket(m)
 
 	case *msg.CDemoFullPacket:
 		p.msgQueue <- m.StringTable
 
 		if m.Packet.GetData() != nil {
-			p.handleDemoPacket(m.Packet)
+			err = p.handleDemoPacket(m.Packet)
 		}
 	}
 
+	if err != nil {
+		return false, err
+	}
+
 	// Queue up some post processing
 	p.msgQueue <- frameParsedToken
 
-	return msgType != msg.EDemoCommands_DEM_Stop
+	if msgType == msg.EDemoCommands_DEM_Stop {
+		p.msgQueue <- lastTickDoneToken
+
+		if p.config.Format == DemoFormatGrowingFile {
+			p.msgQueue <- events.DemoStopped{}
+		}
+	}
+
+	return msgType != msg.EDemoCommands_DEM_Stop, nil
 }
 
 type frameParsedTokenType struct{}
@@ -358,12 +703,44 @@ type frameParsedTokenType struct{}
 var frameParsedToken = new(frameParsedTokenType)
 
 func (p *parser) handleFrameParsed(*frameParsedTokenType) {
+	defer trace.StartRegion(context.Background(), "demoinfocs.frameParsed").End()
+
 	p.processFrameGameEvents()
 
 	p.currentFrame++
 	p.eventDispatcher.Dispatch(events.FrameDone{})
 }
 
+// handleIngameTickNumber dispatches TickDone for the previous tick when the frames of a new tick start.
+func (p *parser) handleIngameTickNumber(n ingameTickNumber) {
+	if p.tickDonePending && int(n) != p.gameState.ingameTick {
+		p.dispatchTickDone()
+	}
+
+	p.gameState.handleIngameTickNumber(n)
+	p.tickDonePending = true
+}
+
+type lastTickDoneTokenType struct{}
+
+// lastTickDoneToken is queued at the end of the demo, as there's no next tick to dispatch TickDone for the last one.
+var lastTickDoneToken = new(lastTickDoneTokenType)
+
+func (p *parser) handleLastTickDone(*lastTickDoneTokenType) {
+	if p.tickDonePending {
+		p.dispatchTickDone()
+	}
+}
+
+func (p *parser) dispatchTickDone() {
+	p.tickDonePending = false
+
+	p.eventDispatcher.Dispatch(events.TickDone{
+		Tick: p.gameState.ingameTick,
+		Time: p.CurrentTime(),
+	})
+}
+
 // CS2 demos playback info are available in the CDemoFileInfo message that should be parsed at the end of the demo.
 // Demos may not contain it, as a workaround we update values with the last parser information at the end of parsing.
 func (p *parser) ensurePlaybackValuesAreSet() {
@@ -0,0 +1,38 @@
+package demoinfocs
+
+import (
+	common "github.com/markus-wa/demoinfocs-golang/v5/pkg/demoinfocs/common"
+)
+
+// isPOV returns true if the demo has been recorded locally by a player, see events.POVRecordingPlayerDetected.
+func (p *parser) isPOV() bool {
+	return p.recordingPlayerSlot != -1
+}
+
+/*
+RecordingPlayer returns the player that recorded the demo if it's a locally recorded (POV) demo.
+
+Returns nil for GOTV demos, before the recording player has been detected (see events.POVRecordingPlayerDetected)
+and before the player's entity has been created.
+*/
+func (p *parser) RecordingPlayer() *common.Player {
+	if !p.isPOV() {
+		return nil
+	}
+
+	// The player controller entity-ID is the player slot + 1
+	return p.gameState.playersByEntityID[p.recordingPlayerSlot+1]
+}
+
+/*
+IsEntityInPVS returns false if the entity with the given ID has left the PVS (potentially visible set) of the recording player.
+The properties of such entities (position, health etc.) aren't updated until they enter the PVS again, i.e. they may be stale.
+
+Entities usually only leave the PVS in locally recorded (POV) demos, GOTV demos contain the whole game.
+See also events.EntityLeftPVS and events.EntityEnteredPVS.
+*/
+func (p *parser) IsEntityInPVS(entityID int) bool {
+	_, outside := p.entitiesOutsidePVS[entityID]
+
+	return !outside
+}
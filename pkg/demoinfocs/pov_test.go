@@ -0,0 +1,120 @@
+package demoinfocs
+
+import (
+	"testing"
+
+	"github.com/golang/geo/r3"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+
+	common "github.com/markus-wa/demoinfocs-golang/v5/pkg/demoinfocs/common"
+	events "github.com/markus-wa/demoinfocs-golang/v5/pkg/demoinfocs/events"
+	msg "github.com/markus-wa/demoinfocs-golang/v5/pkg/demoinfocs/msg"
+	st "github.com/markus-wa/demoinfocs-golang/v5/pkg/demoinfocs/sendtables"
+	stfake "github.com/markus-wa/demoinfocs-golang/v5/pkg/demoinfocs/sendtables/fake"
+)
+
+func TestParser_RecordingPlayer(t *testing.T) {
+	p := newParserWithoutInput(ParserConfig{})
+
+	pl := &common.Player{Name: "recorder"}
+	p.gameState.playersByEntityID[3] = pl
+
+	assert.Nil(t, p.RecordingPlayer())
+
+	p.recordingPlayerSlot = 2
+
+	assert.Equal(t, pl, p.RecordingPlayer())
+}
+
+func TestParser_IsEntityInPVS(t *testing.T) {
+	p := newParserWithoutInput(ParserConfig{})
+	p.recordingPlayerSlot = 0
+
+	var evs []any
+
+	p.RegisterEventHandler(func(e any) {
+		evs = append(evs, e)
+	})
+
+	entity := new(stfake.Entity)
+	entity.On("ID").Return(10)
+
+	for _, op := range []st.EntityOp{st.EntityOpCreatedEntered, st.EntityOpUpdated, st.EntityOpLeft} {
+		assert.NoError(t, p.onEntity(entity, op))
+	}
+
+	assert.False(t, p.IsEntityInPVS(10))
+	assert.True(t, p.IsEntityInPVS(11))
+
+	assert.NoError(t, p.onEntity(entity, st.EntityOpUpdatedEntered))
+	assert.True(t, p.IsEntityInPVS(10))
+
+	assert.NoError(t, p.onEntity(entity, st.EntityOpLeft))
+	assert.NoError(t, p.onEntity(entity, st.EntityOpDeletedLeft))
+	assert.True(t, p.IsEntityInPVS(10))
+
+	assert.Equal(t, []any{
+		events.EntityLeftPVS{Entity: entity},
+		events.EntityEnteredPVS{Entity: entity},
+		events.EntityLeftPVS{Entity: entity},
+	}, evs)
+}
+
+func TestParser_IsEntityInPVS_GOTV(t *testing.T) {
+	p := newParserWithoutInput(ParserConfig{})
+
+	p.RegisterEventHandler(func(e any) {
+		assert.Fail(t, "unexpected event", "%T", e)
+	})
+
+	entity := new(stfake.Entity)
+	entity.On("ID").Return(10)
+
+	assert.NoError(t, p.onEntity(entity, st.EntityOpCreatedEntered))
+	assert.NoError(t, p.onEntity(entity, st.EntityOpLeft))
+	assert.False(t, p.IsEntityInPVS(10))
+}
+
+func TestPOVGameEvents(t *testing.T) {
+	p := newParserWithoutInput(ParserConfig{})
+
+	pl := &common.Player{Name: "recorder", UserID: 2}
+	p.gameState.playersByUserID[2] = pl
+
+	var evs []any
+
+	p.RegisterEventHandler(func(e any) {
+		if _, ok := e.(events.GenericGameEvent); !ok {
+			evs = append(evs, e)
+		}
+	})
+
+	p.gameEventDescs = map[int32]*msg.CMsgSource1LegacyGameEventListDescriptorT{
+		1: gameEventDescriptor("enter_buyzone", "userid", "canbuy"),
+		2: gameEventDescriptor("exit_bombzone", "userid", "hasbomb", "isplanted"),
+		3: gameEventDescriptor("player_ping", "userid", "entityid", "x", "y", "z", "urgent"),
+		4: gameEventDescriptor("player_ping_stop", "entityid"),
+	}
+
+	p.handleGameEvent(gameEvent(1, &msg.CMsgSource1LegacyGameEventKeyT{ValShort: proto.Int32(2)}, &msg.CMsgSource1LegacyGameEventKeyT{ValBool: proto.Bool(true)}))
+	p.handleGameEvent(gameEvent(2, &msg.CMsgSource1LegacyGameEventKeyT{ValShort: proto.Int32(2)}, &msg.CMsgSource1LegacyGameEventKeyT{ValBool: proto.Bool(true)}, &msg.CMsgSource1LegacyGameEventKeyT{ValBool: proto.Bool(false)}))
+	p.handleGameEvent(gameEvent(3,
+		&msg.CMsgSource1LegacyGameEventKeyT{ValShort: proto.Int32(2)},
+		&msg.CMsgSource1LegacyGameEventKeyT{ValShort: proto.Int32(100)},
+		&msg.CMsgSource1LegacyGameEventKeyT{ValFloat: proto.Float32(1)},
+		&msg.CMsgSource1LegacyGameEventKeyT{ValFloat: proto.Float32(2)},
+		&msg.CMsgSource1LegacyGameEventKeyT{ValFloat: proto.Float32(3)},
+		&msg.CMsgSource1LegacyGameEventKeyT{ValBool: proto.Bool(true)},
+	))
+	p.handleGameEvent(gameEvent(4, &msg.CMsgSource1LegacyGameEventKeyT{ValShort: proto.Int32(100)}))
+	p.handleGameEvent(gameEvent(4, &msg.CMsgSource1LegacyGameEventKeyT{ValShort: proto.Int32(100)}))
+
+	assert.Equal(t, []any{
+		events.BuyZoneEntered{Player: pl, CanBuy: true},
+		events.BombZoneLeft{Player: pl, HasBomb: true},
+		events.PlayerPing{Player: pl, Position: r3.Vector{X: 1, Y: 2, Z: 3}, IsUrgent: true},
+		events.PlayerPingStop{Player: pl},
+		events.PlayerPingStop{},
+	}, evs)
+}
@@ -0,0 +1,138 @@
+package demoinfocs
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+
+	"github.com/markus-wa/demoinfocs-golang/v5/pkg/demoinfocs/events"
+	"github.com/markus-wa/demoinfocs-golang/v5/pkg/demoinfocs/msg"
+)
+
+// RecoveryStats contains information about the data that was skipped in ParserConfig.RecoveryMode,
+// see Parser.RecoveryStats().
+type RecoveryStats struct {
+	Resyncs       int // Amount of times the parser had to resynchronise because of corrupt frames or PacketEntities
+	SkippedFrames int // Amount of frames that were discarded, including the corrupt frames themselves
+	SkippedTicks  int // Amount of ingame ticks between corrupt frames and the full packets the parser resynchronised at
+}
+
+// RecoveryStats returns the amount of frames & ticks that have been skipped because of corrupt data.
+// Always returns zero values if ParserConfig.RecoveryMode isn't enabled.
+func (p *parser) RecoveryStats() RecoveryStats {
+	return p.recoveryStats
+}
+
+// parseFrameWithRecovery is parseFrame() for ParserConfig.RecoveryMode.
+// Corrupt frames are skipped along with all following frames until the next DEM_FullPacket.
+func (p *parser) parseFrameWithRecovery() bool {
+	if p.resyncPending.CompareAndSwap(true, false) {
+		// A PacketEntities message of a previous frame was corrupt, entity state can't be trusted anymore
+		return p.resync(p.framePos.tick)
+	}
+
+	more, err := p.processFrame(p.readFrameHeader())
+	if err == nil {
+		return more
+	}
+
+	if errors.Is(err, ErrUnexpectedEndOfDemo) {
+		p.setError(err)
+
+		return false
+	}
+
+	p.skipCorruptFrame(err)
+
+	return p.resync(p.framePos.tick)
+}
+
+func (p *parser) skipCorruptFrame(err error) {
+	p.recoveryStats.SkippedFrames++
+
+	p.eventDispatcher.Dispatch(events.ParserWarn{
+		Message: fmt.Sprintf("skipping frames until the next full packet after corrupt frame: %v", err),
+		Type:    events.WarnTypeCorruptFrameSkipped,
+	})
+}
+
+// resync skips frames until the next DEM_FullPacket and restores entity state from it.
+// Returns false if the end of the demo is reached instead.
+func (p *parser) resync(fromTick int) bool {
+	p.recoveryStats.Resyncs++
+
+	for {
+		h := p.readFrameHeader()
+
+		switch h.cmd & ^msg.EDemoCommands_DEM_IsCompressed { //nolint:exhaustive
+		case msg.EDemoCommands_DEM_FullPacket:
+			p.recoveryStats.SkippedTicks += max(0, int(h.tick)-fromTick)
+
+			more, err := p.processKeyframe(h)
+			if err == nil {
+				return more
+			}
+
+			if errors.Is(err, ErrUnexpectedEndOfDemo) {
+				p.setError(err)
+
+				return false
+			}
+
+			// The full packet is corrupt as well, keep looking
+			p.skipCorruptFrame(err)
+
+			fromTick = int(h.tick)
+
+		case msg.EDemoCommands_DEM_Stop:
+			p.recoveryStats.SkippedTicks += max(0, int(h.tick)-fromTick)
+
+			more, err := p.processFrame(h)
+			if err != nil {
+				p.setError(err)
+
+				return false
+			}
+
+			return more
+
+		default:
+			p.bitReader.Skip(int(h.size) << 3)
+
+			p.framesRead++
+			p.recoveryStats.SkippedFrames++
+		}
+	}
+}
+
+// processKeyframe resets entity state and processes the given DEM_FullPacket frame without dispatching game events.
+func (p *parser) processKeyframe(h frameHeader) (more bool, err error) {
+	restore := p.muteEvents()
+
+	defer func() {
+		p.msgDispatcher.SyncAllQueues()
+		restore()
+
+		if err == nil {
+			err = p.error()
+		}
+	}()
+
+	err = p.resetEntityState()
+	if err != nil {
+		return false, err
+	}
+
+	return p.processFrame(h)
+}
+
+// onCorruptPacketEntities is called by the sendtables parser when a PacketEntities message can't be parsed in RecoveryMode.
+// It runs on the message queue, the resync happens before the next frame is read.
+func (p *parser) onCorruptPacketEntities(err error) {
+	p.eventDispatcher.Dispatch(events.ParserWarn{
+		Message: fmt.Sprintf("skipping frames until the next full packet after corrupt PacketEntities: %v", err),
+		Type:    events.WarnTypeCorruptFrameSkipped,
+	})
+
+	p.resyncPending.Store(true)
+}
@@ -0,0 +1,139 @@
+package demoinfocs
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/markus-wa/demoinfocs-golang/v5/internal/demotest"
+	"github.com/markus-wa/demoinfocs-golang/v5/pkg/demoinfocs/events"
+	"github.com/markus-wa/demoinfocs-golang/v5/pkg/demoinfocs/msg"
+)
+
+var corruptFramePayload = []byte{0xff, 0xff}
+
+func newRecoveryTestParser(t *testing.T, demo []byte) *parser {
+	t.Helper()
+
+	p := NewParserWithConfig(bytes.NewReader(demo), ParserConfig{
+		MsgQueueBufferSize: 0,
+		RecoveryMode:       true,
+	}).(*parser)
+
+	t.Cleanup(func() {
+		assert.NoError(t, p.Close())
+	})
+
+	return p
+}
+
+// recordFrames records the ingame ticks of all FrameDone events and all ParserWarn events of the given type.
+func recordFrames(p *parser, warnType events.WarnType) (ticks *[]int, warns *[]events.ParserWarn) {
+	ticks, warns = new([]int), new([]events.ParserWarn)
+
+	p.RegisterEventHandler(func(events.FrameDone) {
+		*ticks = append(*ticks, p.GameState().IngameTick())
+	})
+
+	p.RegisterEventHandler(func(e events.ParserWarn) {
+		if e.Type == warnType {
+			*warns = append(*warns, e)
+		}
+	})
+
+	return ticks, warns
+}
+
+func TestParser_RecoveryMode_SkipsToNextFullPacket(t *testing.T) {
+	d := demotest.New(t).FullPacket(0).TickPacket(1).TickPacket(2)
+	d.RawFrame(msg.EDemoCommands_DEM_Packet, 3, corruptFramePayload)
+	d.TickPacket(4).TickPacket(5).FullPacket(6).TickPacket(7)
+
+	p := newRecoveryTestParser(t, d.Stop(8).Bytes())
+	ticks, warns := recordFrames(p, events.WarnTypeCorruptFrameSkipped)
+
+	assert.NoError(t, p.ParseToEnd())
+
+	// Frames of ticks 3-5 are skipped, the full packet at tick 6 is parsed without dispatching events
+	assert.Equal(t, []int{0, 0, 1, 2, 7, 8}, *ticks)
+	assert.Len(t, *warns, 1)
+	assert.Equal(t, RecoveryStats{
+		Resyncs:       1,
+		SkippedFrames: 3,
+		SkippedTicks:  3,
+	}, p.RecoveryStats())
+	assert.Equal(t, 8, p.GameState().IngameTick())
+}
+
+func TestParser_RecoveryMode_CorruptFullPacket(t *testing.T) {
+	d := demotest.New(t).FullPacket(0)
+	d.RawFrame(msg.EDemoCommands_DEM_Packet, 1, corruptFramePayload)
+	d.RawFrame(msg.EDemoCommands_DEM_FullPacket, 2, corruptFramePayload)
+	d.TickPacket(3).FullPacket(4).TickPacket(5)
+
+	p := newRecoveryTestParser(t, d.Stop(6).Bytes())
+	ticks, warns := recordFrames(p, events.WarnTypeCorruptFrameSkipped)
+
+	assert.NoError(t, p.ParseToEnd())
+
+	assert.Equal(t, []int{0, 0, 5, 6}, *ticks)
+	assert.Len(t, *warns, 2)
+	assert.Equal(t, RecoveryStats{
+		Resyncs:       1,
+		SkippedFrames: 3,
+		SkippedTicks:  3,
+	}, p.RecoveryStats())
+}
+
+func TestParser_RecoveryMode_NoFullPacketBeforeStop(t *testing.T) {
+	d := demotest.New(t).FullPacket(0)
+	d.RawFrame(msg.EDemoCommands_DEM_Packet, 1, corruptFramePayload)
+	d.TickPacket(2)
+
+	p := newRecoveryTestParser(t, d.Stop(3).Bytes())
+
+	assert.NoError(t, p.ParseToEnd())
+	assert.Equal(t, RecoveryStats{
+		Resyncs:       1,
+		SkippedFrames: 2,
+		SkippedTicks:  2,
+	}, p.RecoveryStats())
+	assert.Equal(t, 3, p.GameState().IngameTick())
+}
+
+func TestParser_RecoveryMode_CorruptPacketEntities(t *testing.T) {
+	d := demotest.New(t).FullPacket(0).TickPacket(1).TickPacket(2).FullPacket(3)
+
+	p := newRecoveryTestParser(t, d.Stop(4).Bytes())
+	ticks, warns := recordFrames(p, events.WarnTypeCorruptFrameSkipped)
+
+	for i := 0; i < 3; i++ {
+		_, err := p.ParseNextFrame()
+		assert.NoError(t, err)
+	}
+
+	// Usually called by the sendtables parser on the message queue
+	p.onCorruptPacketEntities(errors.New("test"))
+
+	assert.NoError(t, p.ParseToEnd())
+
+	assert.Equal(t, []int{0, 0, 1, 4}, *ticks)
+	assert.Len(t, *warns, 1)
+	assert.Equal(t, RecoveryStats{
+		Resyncs:       1,
+		SkippedFrames: 1,
+		SkippedTicks:  2,
+	}, p.RecoveryStats())
+}
+
+func TestParser_RecoveryMode_Truncated(t *testing.T) {
+	d := demotest.New(t).FullPacket(0)
+	d.RawFrame(msg.EDemoCommands_DEM_Packet, 1, corruptFramePayload)
+	b := d.TickPacket(2).Bytes()
+
+	p := newRecoveryTestParser(t, b[:len(b)-2])
+
+	assert.ErrorIs(t, p.ParseToEnd(), ErrUnexpectedEndOfDemo)
+}
@@ -2,8 +2,10 @@ package demoinfocs
 
 import (
 	"bytes"
+	"context"
 	"embed"
 	"fmt"
+	"runtime/trace"
 	"slices"
 	"time"
 
@@ -31,6 +33,7 @@ func (p *parser) handleClassInfo(msg *msg.CDemoClassInfo) {
 	debugAllServerClasses(p.ServerClasses())
 
 	p.bindEntities()
+	p.applyEntityClassFilter()
 
 	p.eventDispatcher.Dispatch(events.DataTablesParsed{})
 }
@@ -296,15 +299,81 @@ func (m *pendingMessage) priority() int {
 	return 0
 }
 
-func (p *parser) handleDemoPacket(pack *msg.CDemoPacket) {
+// netMessageCreator returns the creator for the given net-message-ID, or nil if the ID is unknown.
+// ParserConfig.AdditionalNetMessageCreators are only used for IDs without a built-in creator.
+func (p *parser) netMessageCreator(t int32) NetMessageCreator {
+	var creator NetMessageCreator
+
+	switch {
+	case t < int32(msg.SVC_Messages_svc_ServerInfo):
+		creator = netMsgCreators[msg.NET_Messages(t)]
+	case t < int32(msg.EBaseUserMessages_UM_AchievementEvent):
+		creator = svcMsgCreators[msg.SVC_Messages(t)]
+	case t < int32(msg.EBaseGameEvents_GE_VDebugGameSessionIDEvent):
+		creator = usrMsgCreators[msg.EBaseUserMessages(t)]
+
+		if creator == nil {
+			creator = emCreators[msg.EBaseEntityMessages(t)]
+		}
+	case t < int32(msg.ECstrike15UserMessages_CS_UM_VGUIMenu):
+		creator = gameEventCreators[msg.EBaseGameEvents(t)]
+	case t < int32(msg.ETEProtobufIds_TE_EffectDispatchId):
+		creator = csUsrMsgCreators[msg.ECstrike15UserMessages(t)]
+	case t < int32(msg.ECsgoGameEvents_GE_PlayerAnimEventId):
+		creator = teCreators[msg.ETEProtobufIds(t)]
+	default:
+		creator = csgoGameEventCreators[msg.ECsgoGameEvents(t)]
+	}
+
+	if creator == nil {
+		creator = p.additionalNetMessageCreators[int(t)]
+	}
+
+	return creator
+}
+
+// skipUnknownNetMessage counts a net-message without creator and queues an events.UnknownNetMessage for it,
+// unless it's consumed by a raw net-message handler.
+// The first message of each unknown type also causes a ParserWarn.
+func (p *parser) skipUnknownNetMessage(m pendingMessage, raw bool) {
+	stat := p.netMessageStat(m.t, nil)
+
+	if stat.Read == 0 {
+		p.eventDispatcher.Dispatch(events.ParserWarn{
+			Message: fmt.Sprintf("skipping unknown net-message type %d, see events.UnknownNetMessage & Parser.RegisterRawNetMessageHandler()", m.t),
+			Type:    events.WarnTypeUnknownNetMessage,
+		})
+	}
+
+	p.countNetMessage(stat, len(m.buf))
+
+	if !raw {
+		p.msgQueue <- events.UnknownNetMessage{
+			ID:      m.t,
+			Payload: m.buf,
+		}
+	}
+}
+
+// handleDemoPacket queues the net-messages of a packet, they're unmarshalled when they're dispatched, see handleEncodedNetMessage().
+// Returns a *ParseError if the packet is corrupt.
+func (p *parser) handleDemoPacket(pack *msg.CDemoPacket) (err error) {
 	b := pack.GetData()
 
 	if len(b) == 0 {
-		return
+		return nil
 	}
 
+	defer trace.StartRegion(context.Background(), "demoinfocs.decodeNetMessages").End()
+
 	r := bitread.NewSmallBitReader(bytes.NewReader(b))
 
+	defer func() {
+		if rec := recover(); rec != nil {
+			err = p.newParseError(fmt.Errorf("corrupt packet: %v", rec), -1)
+		}
+	}()
+
 	p.pendingMessagesCache = p.pendingMessagesCache[:0]
 
 	for len(b)*8-r.ActualPosition() > 7 {
@@ -320,48 +389,39 @@ func (p *parser) handleDemoPacket(pack *msg.CDemoPacket) {
 	})
 
 	for _, m := range p.pendingMessagesCache {
-		var msgCreator NetMessageCreator
-
-		if m.t < int32(msg.SVC_Messages_svc_ServerInfo) {
-			msgCreator = netMsgCreators[msg.NET_Messages(m.t)]
-		} else if m.t < int32(msg.EBaseUserMessages_UM_AchievementEvent) {
-			msgCreator = svcMsgCreators[msg.SVC_Messages(m.t)]
-		} else if m.t < int32(msg.EBaseGameEvents_GE_VDebugGameSessionIDEvent) {
-			msgCreator = usrMsgCreators[msg.EBaseUserMessages(m.t)]
-
-			if msgCreator == nil {
-				msgCreator = emCreators[msg.EBaseEntityMessages(m.t)]
-			}
-		} else if m.t < int32(msg.ECstrike15UserMessages_CS_UM_VGUIMenu) {
-			msgCreator = gameEventCreators[msg.EBaseGameEvents(m.t)]
-		} else if m.t < int32(msg.ETEProtobufIds_TE_EffectDispatchId) {
-			msgCreator = csUsrMsgCreators[msg.ECstrike15UserMessages(m.t)]
-		} else if m.t < int32(msg.ECsgoGameEvents_GE_PlayerAnimEventId) {
-			msgCreator = teCreators[msg.ETEProtobufIds(m.t)]
-		} else {
-			msgCreator = csgoGameEventCreators[msg.ECsgoGameEvents(m.t)]
+		raw := p.rawNetMessageHandlers.has(m.t)
+		if raw {
+			p.msgQueue <- rawNetMessage{id: m.t, payload: m.buf}
 		}
 
+		msgCreator := p.netMessageCreator(m.t)
 		if msgCreator == nil {
-			panic(fmt.Errorf("unknown message type %d", m.t))
+			p.skipUnknownNetMessage(m, raw)
+
+			continue
 		}
 
-		msg := msgCreator()
+		stat := p.netMessageStat(m.t, msgCreator)
 
-		err := proto.Unmarshal(m.buf, msg)
-		if err != nil {
-			panic(err) // FIXME: avoid panic
-		}
+		p.countNetMessage(stat, len(m.buf))
 
-		p.msgQueue <- msg
+		p.msgQueue <- encodedNetMessage{
+			id:      m.t,
+			buf:     m.buf,
+			creator: msgCreator,
+			stat:    stat,
+			pos:     p.framePos,
+		}
 	}
+
+	return nil
 }
 
 func (p *parser) handleFullPacket(msg *msg.CDemoFullPacket) {
 	p.handleStringTables(msg.StringTable)
 
 	if msg.Packet.GetData() != nil {
-		p.handleDemoPacket(msg.Packet)
+		p.setError(p.handleDemoPacket(msg.Packet))
 	}
 }
 
@@ -391,6 +451,9 @@ func getGameEventListBinForProtocol(networkProtocol int) ([]byte, error) {
 }
 
 func (p *parser) handleDemoFileHeader(msg *msg.CDemoFileHeader) {
+	h := newHeader(msg)
+	p.demoHeader = &h
+
 	p.header.ClientName = msg.GetClientName()
 	p.header.ServerName = msg.GetServerName()
 	p.header.GameDirectory = msg.GetGameDirectory()
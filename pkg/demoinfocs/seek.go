@@ -0,0 +1,361 @@
+package demoinfocs
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/pkg/errors"
+
+	bit "github.com/markus-wa/demoinfocs-golang/v5/internal/bitread"
+	"github.com/markus-wa/demoinfocs-golang/v5/pkg/demoinfocs/msg"
+)
+
+// Seeking errors
+var (
+	// ErrSeekNotSupported signals that the demo can't be seeked in,
+	// either because the input isn't an io.ReadSeeker or because it's a live CSTV broadcast.
+	ErrSeekNotSupported = errors.New("seeking requires an io.ReadSeeker demo file (ErrSeekNotSupported)")
+
+	// ErrSeekOutOfRange signals that the requested tick or round isn't part of the demo.
+	ErrSeekOutOfRange = errors.New("seek target is outside of the demo (ErrSeekOutOfRange)")
+)
+
+// demoHeaderSize is the size of the filestamp and the file info & spawn groups offsets at the start of a PBDEMS2 demo.
+const demoHeaderSize = 16
+
+// keyframe is the position of a DEM_FullPacket frame inside the demo.
+type keyframe struct {
+	tick   int   // Ingame tick of the frame
+	frame  int   // Number of frames before the keyframe
+	offset int64 // Byte offset of the frame, relative to the start of the demo
+}
+
+// readOnly hides io.Seeker from BitReader so its positions stay relative to where it was opened.
+type readOnly struct {
+	io.Reader
+}
+
+// buildKeyframeIndex scans the frame headers of the whole demo and records the offsets of all DEM_FullPacket frames.
+// Frame payloads are skipped, so this is a lot cheaper than parsing the demo.
+// The position of the underlying reader is restored afterwards.
+func (p *parser) buildKeyframeIndex() error {
+	pos, err := p.demoSeeker.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return errors.Wrap(err, "failed to get current position")
+	}
+
+	_, err = p.demoSeeker.Seek(p.demoStartOffset+demoHeaderSize, io.SeekStart)
+	if err != nil {
+		return errors.Wrap(err, "failed to seek to first frame")
+	}
+
+	r := bufio.NewReader(p.demoSeeker)
+	offset := int64(demoHeaderSize)
+	index := make([]keyframe, 0)
+
+	for frame := 0; ; frame++ {
+		frameOffset := offset
+
+		cmd, tick, size, n, err := readFrameHeader(r)
+		if err != nil {
+			break // end of demo (or truncated frame header)
+		}
+
+		offset += n
+
+		msgType := cmd & ^msg.EDemoCommands_DEM_IsCompressed
+
+		if msgType == msg.EDemoCommands_DEM_FullPacket {
+			index = append(index, keyframe{
+				tick:   int(tick),
+				frame:  frame,
+				offset: frameOffset,
+			})
+		}
+
+		if msgType == msg.EDemoCommands_DEM_Stop {
+			break
+		}
+
+		discarded, err := r.Discard(int(size))
+		offset += int64(discarded)
+
+		if err != nil {
+			break
+		}
+	}
+
+	p.keyframes = index
+
+	_, err = p.demoSeeker.Seek(pos, io.SeekStart)
+	if err != nil {
+		return errors.Wrap(err, "failed to restore position")
+	}
+
+	return nil
+}
+
+// readFrameHeader reads the command, tick and payload size of the next frame from a PBDEMS2 demo.
+// Also returns the number of bytes read.
+func readFrameHeader(r io.ByteReader) (cmd msg.EDemoCommands, tick, size uint32, n int64, err error) {
+	var values [3]uint32
+
+	for i := range values {
+		v, read, err := readFrameVarInt(r)
+		n += read
+
+		if err != nil {
+			return 0, 0, 0, n, err
+		}
+
+		values[i] = v
+	}
+
+	tick = values[1]
+
+	// This appears to actually be an int32, where a -1 means pre-game.
+	if tick == 4294967295 {
+		tick = 0
+	}
+
+	return msg.EDemoCommands(values[0]), tick, values[2], n, nil
+}
+
+func readFrameVarInt(r io.ByteReader) (uint32, int64, error) {
+	var (
+		res uint32
+		n   int64
+	)
+
+	for count := uint(0); count < 5; count++ {
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, n, err
+		}
+
+		n++
+		res |= uint32(b&0x7f) << (7 * count)
+
+		if b&0x80 == 0 {
+			break
+		}
+	}
+
+	return res, n, nil
+}
+
+// lastKeyframeBefore returns the last keyframe at or before the given tick, or nil if there is none.
+func (p *parser) lastKeyframeBefore(tick int) *keyframe {
+	i := sort.Search(len(p.keyframes), func(i int) bool {
+		return p.keyframes[i].tick > tick
+	})
+
+	if i == 0 {
+		return nil
+	}
+
+	return &p.keyframes[i-1]
+}
+
+/*
+SeekToTick moves the parser to the given ingame tick.
+
+Seeking requires the demo to be provided as io.ReadSeeker (e.g. os.File).
+On the first call the parser indexes all DEM_FullPacket frames (keyframes) of the demo.
+String tables and entity state are then restored from the closest keyframe before the tick
+and the following frames are replayed until the tick is reached.
+If the tick is ahead of the current position and no keyframe is closer, the parser just replays forward.
+
+No game events are dispatched for the replayed frames, net-message handlers are called as usual.
+After seeking, Parser.GameState() reflects the state at the given tick and parsing can be continued
+via ParseNextFrame() or ParseToEnd().
+
+Returns ErrSeekNotSupported if the demo isn't seekable
+and ErrSeekOutOfRange if the tick is before the first keyframe or after the end of the demo.
+*/
+func (p *parser) SeekToTick(tick int) error {
+	err := p.prepareSeek()
+	if err != nil {
+		return err
+	}
+
+	current := p.gameState.ingameTick
+	kf := p.lastKeyframeBefore(tick)
+
+	if kf == nil && tick < current {
+		return fmt.Errorf("%w: no keyframe before tick %d", ErrSeekOutOfRange, tick)
+	}
+
+	if kf != nil && (tick < current || kf.tick > current) {
+		err = p.restoreKeyframe(*kf)
+		if err != nil {
+			return err
+		}
+	}
+
+	return p.replayWhile(func() bool {
+		return p.gameState.ingameTick < tick
+	})
+}
+
+/*
+SeekToRound moves the parser to the start of the given round (starting at 1).
+
+Rounds that have already been parsed are seeked to via SeekToTick().
+Rounds that haven't been reached yet are searched for by replaying the demo forward.
+
+See SeekToTick() for requirements and possible errors.
+*/
+func (p *parser) SeekToRound(round int) error {
+	if tick, ok := p.roundStartTicks[round]; ok {
+		return p.SeekToTick(tick)
+	}
+
+	err := p.prepareSeek()
+	if err != nil {
+		return err
+	}
+
+	// Round hasn't been seen yet, replay forward from the current position until it starts
+	err = p.replayWhile(func() bool {
+		_, found := p.roundStartTicks[round]
+
+		return !found
+	})
+	if err != nil {
+		return fmt.Errorf("round %d not found: %w", round, err)
+	}
+
+	return nil
+}
+
+// prepareSeek makes sure the header has been parsed and the keyframe index has been built.
+func (p *parser) prepareSeek() error {
+	if p.demoSeeker == nil || p.config.Format != DemoFormatFile {
+		return ErrSeekNotSupported
+	}
+
+	if p.header == nil {
+		_, err := p.parseHeader()
+		if err != nil {
+			return err
+		}
+	}
+
+	p.ensureMsgQueue()
+
+	if p.keyframes == nil {
+		err := p.buildKeyframeIndex()
+		if err != nil {
+			return err
+		}
+	}
+
+	p.msgDispatcher.SyncAllQueues()
+
+	return p.error()
+}
+
+// restoreKeyframe resets entity state, moves the BitReader to the given keyframe and parses it.
+func (p *parser) restoreKeyframe(kf keyframe) error {
+	restore := p.muteEvents()
+	defer restore()
+
+	err := p.resetEntityState()
+	if err != nil {
+		return err
+	}
+
+	_, err = p.demoSeeker.Seek(p.demoStartOffset+kf.offset, io.SeekStart)
+	if err != nil {
+		return errors.Wrap(err, "failed to seek to keyframe")
+	}
+
+	// The original BitReader isn't closed as that would close the underlying file, Close() takes care of that
+	if p.demoCloser == nil {
+		p.demoCloser = p.bitReader
+	}
+
+	p.bitReader = bit.NewLargeBitReader(readOnly{p.demoSeeker})
+
+	p.bitReaderOffset = kf.offset
+	p.currentFrame = kf.frame
+	p.framesRead = kf.frame
+	p.gameState.ingameTick = kf.tick
+
+	// Parse the keyframe right away so entity state is restored even if it's the seek target
+	return p.replayWhile(func() bool {
+		return p.currentFrame == kf.frame
+	})
+}
+
+// resetEntityState destroys all entities and clears state that depends on previous frames.
+// Used before restoring entity state from a keyframe.
+func (p *parser) resetEntityState() error {
+	err := p.stParser.ResetEntities()
+	if err != nil {
+		return errors.Wrap(err, "failed to reset entities")
+	}
+
+	p.delayedEventHandlers = p.delayedEventHandlers[:0]
+	p.gameState.resetTransientState()
+
+	return nil
+}
+
+// replayWhile parses frames without dispatching game events as long as cond returns true.
+//
+// Returns ErrSeekOutOfRange if the end of the demo is reached before cond returns false.
+func (p *parser) replayWhile(cond func() bool) (err error) {
+	restore := p.muteEvents()
+
+	defer func() {
+		p.msgDispatcher.SyncAllQueues()
+		restore()
+
+		if err == nil {
+			err = p.recoverFrameError(recover())
+		}
+
+		if err == nil {
+			err = p.error()
+		}
+	}()
+
+	for cond() {
+		if !p.parseFrame() {
+			if err = p.error(); err != nil {
+				return err
+			}
+
+			return ErrSeekOutOfRange
+		}
+
+		p.msgDispatcher.SyncAllQueues()
+
+		if err = p.error(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// muteEvents replaces the event dispatcher with one without handlers until the returned function is called.
+// Message queues must be in sync when calling this and the returned function.
+func (p *parser) muteEvents() (restore func()) {
+	p.msgDispatcher.SyncAllQueues()
+
+	original := p.eventDispatcher
+	p.eventDispatcher = newEventDispatcher()
+
+	return func() {
+		p.eventDispatcher = original
+	}
+}
+
+func (p *parser) recordRoundStart() {
+	p.roundStartTicks[p.gameState.totalRoundsPlayed+1] = p.gameState.ingameTick
+}
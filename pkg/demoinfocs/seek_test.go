@@ -0,0 +1,214 @@
+package demoinfocs
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/markus-wa/demoinfocs-golang/v5/internal/demotest"
+	common "github.com/markus-wa/demoinfocs-golang/v5/pkg/demoinfocs/common"
+	"github.com/markus-wa/demoinfocs-golang/v5/pkg/demoinfocs/events"
+)
+
+// seekTestDemo returns a demo with ticks 0-29 and keyframes at ticks 0, 10 & 20.
+// Frame 0 is the file header, tick t is parsed in frame t+1 (so CurrentFrame() is t+2 afterwards).
+func seekTestDemo(t *testing.T) []byte {
+	t.Helper()
+
+	d := demotest.New(t)
+
+	for tick := int32(0); tick < 30; tick++ {
+		if tick%10 == 0 {
+			d.FullPacket(tick)
+		} else {
+			d.TickPacket(tick)
+		}
+	}
+
+	return d.Stop(30).Bytes()
+}
+
+func newSeekTestParser(t *testing.T) *parser {
+	t.Helper()
+
+	p := NewParserWithConfig(bytes.NewReader(seekTestDemo(t)), ParserConfig{MsgQueueBufferSize: 0}).(*parser)
+
+	t.Cleanup(func() {
+		assert.NoError(t, p.Close())
+	})
+
+	return p
+}
+
+func TestParser_SeekToTick_Forward(t *testing.T) {
+	p := newSeekTestParser(t)
+
+	err := p.SeekToTick(25)
+	assert.NoError(t, err)
+
+	assert.Equal(t, 25, p.GameState().IngameTick())
+	assert.Equal(t, 27, p.CurrentFrame())
+
+	more, err := p.ParseNextFrame()
+	assert.NoError(t, err)
+	assert.True(t, more)
+	assert.Equal(t, 26, p.GameState().IngameTick())
+	assert.Equal(t, 28, p.CurrentFrame())
+}
+
+func TestParser_SeekToTick_Backward(t *testing.T) {
+	p := newSeekTestParser(t)
+
+	assert.NoError(t, p.ParseToEnd())
+	assert.Equal(t, 30, p.GameState().IngameTick())
+
+	err := p.SeekToTick(5)
+	assert.NoError(t, err)
+	assert.Equal(t, 5, p.GameState().IngameTick())
+	assert.Equal(t, 7, p.CurrentFrame())
+
+	err = p.SeekToTick(10)
+	assert.NoError(t, err)
+	assert.Equal(t, 10, p.GameState().IngameTick())
+	assert.Equal(t, 12, p.CurrentFrame())
+
+	var ticks []int
+
+	p.RegisterEventHandler(func(events.FrameDone) {
+		ticks = append(ticks, p.GameState().IngameTick())
+	})
+
+	assert.NoError(t, p.ParseToEnd())
+	assert.Len(t, ticks, 20) // ticks 11-29 + stop
+	assert.Equal(t, 11, ticks[0])
+}
+
+func TestParser_SeekToTick_NoEventsForReplayedFrames(t *testing.T) {
+	p := newSeekTestParser(t)
+
+	frames := 0
+
+	p.RegisterEventHandler(func(events.FrameDone) {
+		frames++
+	})
+
+	assert.NoError(t, p.SeekToTick(15))
+	assert.Zero(t, frames)
+
+	_, err := p.ParseNextFrame()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, frames)
+}
+
+func TestParser_SeekToTick_KeyframeIndex(t *testing.T) {
+	p := newSeekTestParser(t)
+
+	assert.NoError(t, p.SeekToTick(0))
+
+	ticks := make([]int, 0, len(p.keyframes))
+	frames := make([]int, 0, len(p.keyframes))
+
+	for _, kf := range p.keyframes {
+		ticks = append(ticks, kf.tick)
+		frames = append(frames, kf.frame)
+	}
+
+	assert.Equal(t, []int{0, 10, 20}, ticks)
+	assert.Equal(t, []int{1, 11, 21}, frames)
+}
+
+func TestParser_SeekToTick_OutOfRange(t *testing.T) {
+	p := newSeekTestParser(t)
+
+	err := p.SeekToTick(100)
+	assert.ErrorIs(t, err, ErrSeekOutOfRange)
+}
+
+func TestParser_SeekToTick_NotSupported(t *testing.T) {
+	p := NewParser(bytes.NewBuffer(seekTestDemo(t)))
+	defer p.Close()
+
+	assert.ErrorIs(t, p.SeekToTick(5), ErrSeekNotSupported)
+	assert.ErrorIs(t, p.SeekToRound(1), ErrSeekNotSupported)
+}
+
+func TestParser_SeekToRound(t *testing.T) {
+	p := NewParserWithConfig(bytes.NewReader(testMatchDemo(t).Bytes()), ParserConfig{MsgQueueBufferSize: 0}).(*parser)
+	defer p.Close()
+
+	var roundStarts []int
+
+	p.RegisterEventHandler(func(events.RoundStart) {
+		roundStarts = append(roundStarts, p.GameState().IngameTick())
+	})
+
+	assert.NoError(t, p.ParseToEnd())
+	assert.Equal(t, []int{0, 12, 22}, roundStarts)
+	assert.Equal(t, map[int]int{1: 0, 2: 12, 3: 22}, p.roundStartTicks)
+
+	assert.NoError(t, p.SeekToRound(2))
+
+	gs := p.GameState()
+	assert.Equal(t, 12, gs.IngameTick())
+	assert.Equal(t, 1, gs.TotalRoundsPlayed())
+	assert.Equal(t, 1, gs.TeamCounterTerrorists().Score())
+	assert.Equal(t, 0, gs.TeamTerrorists().Score())
+	assert.Equal(t, "Team Vitality", gs.TeamTerrorists().ClanName())
+	assert.Equal(t, int32(events.RoundEndReasonStillInProgress), gs.Rules().Entity().PropertyValueMust("m_pGameRules.m_eRoundWinReason").Any)
+	assert.Equal(t, map[string]common.Team{
+		"s1mple": common.TeamCounterTerrorists,
+		"ZywOo":  common.TeamSpectators,
+	}, testPlayerTeams(gs))
+	assert.Len(t, roundStarts, 3) // no events for replayed frames
+
+	err := p.SeekToRound(4)
+	assert.ErrorIs(t, err, ErrSeekOutOfRange)
+}
+
+func TestParser_SeekToRound_Forward(t *testing.T) {
+	p := NewParserWithConfig(bytes.NewReader(testMatchDemo(t).Bytes()), ParserConfig{MsgQueueBufferSize: 0}).(*parser)
+	defer p.Close()
+
+	roundStarts := 0
+
+	p.RegisterEventHandler(func(events.RoundStart) {
+		roundStarts++
+	})
+
+	assert.NoError(t, p.SeekToRound(3))
+
+	gs := p.GameState()
+	assert.Equal(t, 22, gs.IngameTick())
+	assert.Equal(t, 2, gs.TotalRoundsPlayed())
+	assert.Equal(t, 1, gs.TeamTerrorists().Score())
+	assert.Equal(t, map[string]common.Team{
+		"s1mple": common.TeamCounterTerrorists,
+		"ZywOo":  common.TeamTerrorists,
+	}, testPlayerTeams(gs))
+	assert.Zero(t, roundStarts)
+}
+
+// closeRecorder records whether the demo has been closed.
+type closeRecorder struct {
+	*bytes.Reader
+	closed bool
+}
+
+func (r *closeRecorder) Close() error {
+	r.closed = true
+
+	return nil
+}
+
+func TestParser_SeekToTick_Close(t *testing.T) {
+	demo := &closeRecorder{Reader: bytes.NewReader(seekTestDemo(t))}
+	p := NewParser(demo)
+
+	assert.NoError(t, p.SeekToTick(15))
+	assert.NoError(t, p.SeekToTick(5))
+	assert.False(t, demo.closed)
+
+	assert.NoError(t, p.Close())
+	assert.True(t, demo.closed)
+}
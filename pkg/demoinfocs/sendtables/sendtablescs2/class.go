@@ -18,6 +18,9 @@ type class struct {
 	serializer      *serializer
 	createdHandlers []st.EntityCreatedHandler
 	fpNameCache     *fpNameTreeCache
+	skipDecoding    bool            // Entities of this class are read but not decoded, see Parser.SetEntityFilter()
+	properties      []string        // Properties that are decoded, nil means all
+	decodeCache     map[string]bool // Caches decodesProperty() results by property name
 }
 
 func (c *class) ID() int {
@@ -100,3 +103,39 @@ func (c *class) getFieldPathForName(fp *fieldPath, name string) bool {
 func (c *class) getFieldPaths(fp *fieldPath, state *fieldState) []*fieldPath {
 	return c.serializer.getFieldPaths(fp, state)
 }
+
+// decodesProperty returns true if values of the given property should be stored, see Parser.SetEntityFilter().
+// Properties match if their name is equal to one of the filter entries or if they are nested inside one of them.
+func (c *class) decodesProperty(name string) bool {
+	if c.properties == nil {
+		return true
+	}
+
+	decode, ok := c.decodeCache[name]
+	if ok {
+		return decode
+	}
+
+	for _, prop := range c.properties {
+		if name == prop || strings.HasPrefix(name, prop+".") {
+			decode = true
+
+			break
+		}
+	}
+
+	c.decodeCache[name] = decode
+
+	return decode
+}
+
+// skipFields reads the changed fields of an entity without storing them.
+// The values still need to be decoded as there is no other way to find out how many bits they take up.
+func (c *class) skipFields(r *reader, paths *[]*fieldPath) {
+	n := readFieldPaths(r, paths)
+
+	for _, fp := range (*paths)[:n] {
+		decoder, _ := c.serializer.getDecoderForFieldPath2(fp, 0)
+		decoder(r)
+	}
+}
@@ -2,9 +2,9 @@ package sendtablescs2
 
 import (
 	"fmt"
-	"os"
 	"slices"
 	"strings"
+	"time"
 
 	"github.com/golang/geo/r3"
 	"golang.org/x/exp/maps"
@@ -20,6 +20,7 @@ type Entity struct {
 	serial  int32
 	class   *class
 	active  bool
+	leftPVS bool // Whether the entity left the PVS of the recording player (POV demos), reset when it's updated again
 	state   *fieldState
 	fpCache map[string]*fieldPath
 	fpNoop  map[string]bool
@@ -418,6 +419,10 @@ func (e *Entity) readFields(r *reader, paths *[]*fieldPath) {
 
 		val := decoder(r)
 
+		if !e.class.decodesProperty(name) {
+			continue
+		}
+
 		if base && (f.model == fieldModelVariableArray || f.model == fieldModelVariableTable) {
 			fs := fieldState{}
 
@@ -466,10 +471,16 @@ func (p *Parser) OnPacketEntities(m *msg.CSVCMsg_PacketEntities) error {
 
 		r := recover()
 		if r != nil {
-			fmt.Fprintf(os.Stderr, "error in OnPacketEntities: %v\n", r)
+			p.packetEntitiesPanicWarnFunc(fmt.Errorf("error in OnPacketEntities: %v", r))
 		}
 	}()
 
+	var start time.Time
+
+	if p.entitiesDecodedHandler != nil {
+		start = time.Now()
+	}
+
 	r := newReader(m.GetEntityData())
 
 	var (
@@ -512,6 +523,17 @@ func (p *Parser) OnPacketEntities(m *msg.CSVCMsg_PacketEntities) error {
 					_panicf("unable to find new class %d", classID)
 				}
 
+				if class.skipDecoding {
+					class.skipFields(r, &p.pathCache)
+
+					delete(p.entities, index)
+					p.skippedEntities[index] = class
+
+					continue
+				}
+
+				delete(p.skippedEntities, index)
+
 				e = newEntity(index, serial, class)
 				p.entities[index] = e
 
@@ -540,20 +562,35 @@ func (p *Parser) OnPacketEntities(m *msg.CSVCMsg_PacketEntities) error {
 					continue
 				}
 
+				if class := p.skippedEntities[index]; class != nil {
+					class.skipFields(r, &p.pathCache)
+
+					continue
+				}
+
 				e = p.entities[index]
 				if e == nil {
 					_panicf("unable to find existing entity %d", index)
 				}
 
 				op = st.EntityOpUpdated
-				if !e.active {
+				if !e.active || e.leftPVS {
 					e.active = true
+					e.leftPVS = false
 					op |= st.EntityOpEntered
 				}
 
 				e.readFields(r, &p.pathCache)
 			}
 		} else {
+			if _, skipped := p.skippedEntities[index]; skipped {
+				if cmd&0x02 != 0 {
+					delete(p.skippedEntities, index)
+				}
+
+				continue
+			}
+
 			e = p.entities[index]
 			if e == nil {
 				_panicf("unable to find existing entity %d", index)
@@ -568,12 +605,20 @@ func (p *Parser) OnPacketEntities(m *msg.CSVCMsg_PacketEntities) error {
 				op |= st.EntityOpDeleted
 
 				e.Destroy()
+			} else if e.leftPVS {
+				continue // entity has already left the PVS
+			} else {
+				e.leftPVS = true
 			}
 		}
 
 		p.tuplesCache = append(p.tuplesCache, tuple{e, op})
 	}
 
+	if p.entitiesDecodedHandler != nil {
+		p.entitiesDecodedHandler(time.Since(start))
+	}
+
 	for _, t := range p.tuplesCache {
 		e := t.ent
 
@@ -588,6 +633,10 @@ func (p *Parser) OnPacketEntities(m *msg.CSVCMsg_PacketEntities) error {
 			slices.Sort(props) // TODO: should either be ordered by prop-order or handler registration order
 
 			for _, prop := range props {
+				if !e.class.decodesProperty(prop) {
+					continue
+				}
+
 				v := e.PropertyValueMust(prop)
 
 				for _, h := range e.updateHandlers[prop] {
@@ -609,3 +658,41 @@ func (p *Parser) OnPacketEntities(m *msg.CSVCMsg_PacketEntities) error {
 func (p *Parser) OnEntity(h st.EntityHandler) {
 	p.entityHandlers = append(p.entityHandlers, h)
 }
+
+// OnEntitiesDecoded registers a handler that is called with the time it took to decode
+// a PacketEntities message, excluding the time spent in entity handlers.
+//
+// Intended for internal use only.
+func (p *Parser) OnEntitiesDecoded(h func(time.Duration)) {
+	p.entitiesDecodedHandler = h
+}
+
+// ResetEntities destroys all existing entities and makes the parser accept the next
+// full (non-delta) PacketEntities message, e.g. the one contained in a DEM_FullPacket.
+//
+// Intended for internal use only.
+func (p *Parser) ResetEntities() error {
+	indices := maps.Keys(p.entities)
+	slices.Sort(indices)
+
+	for _, index := range indices {
+		e := p.entities[index]
+		if !e.active {
+			continue
+		}
+
+		e.Destroy()
+
+		for _, h := range p.entityHandlers {
+			if err := h(e, st.EntityOpLeft|st.EntityOpDeleted); err != nil {
+				return err
+			}
+		}
+	}
+
+	clear(p.entities)
+	clear(p.skippedEntities)
+	p.entityFullPackets = 0
+
+	return nil
+}
@@ -0,0 +1,267 @@
+package sendtablescs2
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/markus-wa/demoinfocs-golang/v5/internal/demotest"
+	"github.com/markus-wa/demoinfocs-golang/v5/pkg/demoinfocs/msg"
+	st "github.com/markus-wa/demoinfocs-golang/v5/pkg/demoinfocs/sendtables"
+)
+
+const (
+	testClassController = 1
+	testClassSmoke      = 2
+	testClassTeam       = 3
+)
+
+func testEntities(t *testing.T) *demotest.Entities {
+	t.Helper()
+
+	return demotest.NewEntities(t,
+		&demotest.Class{ID: testClassController, Name: "CCSPlayerController", Fields: []demotest.Field{
+			{Name: "m_iszPlayerName", Type: "CUtlString"},
+			{Name: "m_iTeamNum", Type: "uint8"},
+			{Name: "m_iScore", Type: "int32"},
+			{Name: "m_pInGameMoneyServices", Type: "CCSPlayerController_InGameMoneyServices*", Fields: []demotest.Field{
+				{Name: "m_iAccount", Type: "int32"},
+				{Name: "m_iCashSpentThisRound", Type: "int32"},
+			}},
+		}},
+		&demotest.Class{ID: testClassSmoke, Name: "CSmokeGrenadeProjectile", Fields: []demotest.Field{
+			{Name: "m_nSmokeEffectTickBegin", Type: "int32"},
+			{Name: "m_vSmokeColor", Type: "Vector"},
+			{Name: "m_bDidSmokeEffect", Type: "bool"},
+			{Name: "m_flSpawnTime", Type: "float32"},
+		}},
+		&demotest.Class{ID: testClassTeam, Name: "CCSTeam", Fields: []demotest.Field{
+			{Name: "m_iTeamNum", Type: "uint8"},
+			{Name: "m_szTeamname", Type: "char[129]"},
+			{Name: "m_iScore", Type: "int32"},
+		}},
+	)
+}
+
+func newTestParser(t *testing.T, ents *demotest.Entities) *Parser {
+	t.Helper()
+
+	p := NewParser(nil)
+
+	assert.NoError(t, p.OnServerInfo(ents.ServerInfo().Msg.(*msg.CSVCMsg_ServerInfo)))
+	assert.NoError(t, p.ParsePacket(ents.SendTables().Data))
+	assert.NoError(t, p.OnDemoClassInfo(ents.ClassInfo()))
+
+	return p
+}
+
+type testEntityOp struct {
+	index int
+	class string
+	op    st.EntityOp
+}
+
+// parseEntities decodes the messages and returns the ops passed to the entity handler.
+func parseEntities(t *testing.T, p *Parser, msgs []*msg.CSVCMsg_PacketEntities) []testEntityOp {
+	t.Helper()
+
+	var ops []testEntityOp
+
+	p.OnEntity(func(e st.Entity, op st.EntityOp) error {
+		ops = append(ops, testEntityOp{e.ID(), e.ServerClass().Name(), op})
+
+		return nil
+	})
+
+	for _, m := range msgs {
+		assert.NoError(t, p.OnPacketEntities(m))
+	}
+
+	return ops
+}
+
+// setProperties returns the properties of an entity that have a value.
+func setProperties(e *Entity) map[string]any {
+	props := e.Map()
+
+	for k, v := range props {
+		if v == nil {
+			delete(props, k)
+		}
+	}
+
+	return props
+}
+
+func entityMaps(p *Parser) map[int32]map[string]any {
+	res := make(map[int32]map[string]any)
+
+	for i, e := range p.entities {
+		if e.active {
+			res[i] = setProperties(e)
+		}
+	}
+
+	return res
+}
+
+// testEntityFilterMsgs returns PacketEntities messages where smoke grenades are interleaved with other entities,
+// including an entity index that is reused by a different class.
+func testEntityFilterMsgs(t *testing.T) []*msg.CSVCMsg_PacketEntities {
+	t.Helper()
+
+	ents := testEntities(t)
+
+	return []*msg.CSVCMsg_PacketEntities{
+		ents.PacketEntitiesMsg(false,
+			demotest.Create(1, testClassTeam, map[string]any{"m_iTeamNum": 2, "m_szTeamname": "TERRORIST", "m_iScore": 3}),
+			demotest.Create(2, testClassSmoke, map[string]any{
+				"m_nSmokeEffectTickBegin": 100,
+				"m_vSmokeColor":           []float32{1, 0.5, 0},
+				"m_flSpawnTime":           float32(12.5),
+			}),
+			demotest.Create(3, testClassController, map[string]any{
+				"m_iszPlayerName":                   "s1mple",
+				"m_iTeamNum":                        2,
+				"m_pInGameMoneyServices.m_iAccount": 800,
+			}),
+			demotest.Create(4, testClassSmoke, map[string]any{"m_bDidSmokeEffect": false}),
+		),
+		ents.PacketEntitiesMsg(true,
+			demotest.Update(2, map[string]any{"m_bDidSmokeEffect": true, "m_nSmokeEffectTickBegin": -1}),
+			demotest.Update(3, map[string]any{
+				"m_iScore":                                     -2,
+				"m_pInGameMoneyServices.m_iAccount":            1200,
+				"m_pInGameMoneyServices.m_iCashSpentThisRound": 4700,
+			}),
+			demotest.Delete(4),
+			demotest.Create(5, testClassController, map[string]any{"m_iszPlayerName": "ZywOo", "m_iTeamNum": 3}),
+		),
+		ents.PacketEntitiesMsg(true,
+			demotest.Leave(2),
+			demotest.Delete(3),
+		),
+		ents.PacketEntitiesMsg(true,
+			demotest.Delete(2),
+			demotest.Update(5, map[string]any{"m_iScore": 1}),
+		),
+		ents.PacketEntitiesMsg(true,
+			demotest.Create(2, testClassController, map[string]any{"m_iszPlayerName": "NiKo"}),
+			demotest.Create(4, testClassSmoke, nil),
+		),
+	}
+}
+
+func TestParser_OnPacketEntities(t *testing.T) {
+	p := newTestParser(t, testEntities(t))
+	ops := parseEntities(t, p, testEntityFilterMsgs(t)[:2])
+
+	assert.Equal(t, []testEntityOp{
+		{1, "CCSTeam", st.EntityOpCreated | st.EntityOpEntered},
+		{2, "CSmokeGrenadeProjectile", st.EntityOpCreated | st.EntityOpEntered},
+		{3, "CCSPlayerController", st.EntityOpCreated | st.EntityOpEntered},
+		{4, "CSmokeGrenadeProjectile", st.EntityOpCreated | st.EntityOpEntered},
+		{2, "CSmokeGrenadeProjectile", st.EntityOpUpdated},
+		{3, "CCSPlayerController", st.EntityOpUpdated},
+		{4, "CSmokeGrenadeProjectile", st.EntityOpLeft | st.EntityOpDeleted},
+		{5, "CCSPlayerController", st.EntityOpCreated | st.EntityOpEntered},
+	}, ops)
+
+	assert.Equal(t, map[int32]map[string]any{
+		1: {"m_iTeamNum": uint64(2), "m_szTeamname": "TERRORIST", "m_iScore": int32(3)},
+		2: {
+			"m_nSmokeEffectTickBegin": int32(-1),
+			"m_vSmokeColor":           []float32{1, 0.5, 0},
+			"m_bDidSmokeEffect":       true,
+			"m_flSpawnTime":           float32(12.5),
+		},
+		3: {
+			"m_iszPlayerName":                   "s1mple",
+			"m_iTeamNum":                        uint64(2),
+			"m_iScore":                          int32(-2),
+			"m_pInGameMoneyServices.m_iAccount": int32(1200),
+			"m_pInGameMoneyServices.m_iCashSpentThisRound": int32(4700),
+		},
+		5: {"m_iszPlayerName": "ZywOo", "m_iTeamNum": uint64(3)},
+	}, entityMaps(p))
+}
+
+func TestParser_SetEntityFilter(t *testing.T) {
+	msgs := testEntityFilterMsgs(t)
+
+	unfiltered := newTestParser(t, testEntities(t))
+	filtered := newTestParser(t, testEntities(t))
+	filtered.SetEntityFilter(func(sc st.ServerClass) (bool, []string) {
+		return sc.Name() != "CSmokeGrenadeProjectile", nil
+	})
+
+	var expectedOps []testEntityOp
+
+	for _, op := range parseEntities(t, unfiltered, msgs) {
+		if op.class != "CSmokeGrenadeProjectile" {
+			expectedOps = append(expectedOps, op)
+		}
+	}
+
+	assert.Equal(t, expectedOps, parseEntities(t, filtered, msgs))
+
+	expected := entityMaps(unfiltered)
+	delete(expected, 4) // smoke grenade
+
+	assert.Len(t, expected, 3)
+	assert.Equal(t, expected, entityMaps(filtered))
+	assert.Empty(t, filtered.FilterEntity(func(e *Entity) bool {
+		return e.class.name == "CSmokeGrenadeProjectile"
+	}))
+}
+
+func TestParser_SetEntityFilter_Properties(t *testing.T) {
+	p := newTestParser(t, testEntities(t))
+	p.SetEntityFilter(func(sc st.ServerClass) (bool, []string) {
+		if sc.Name() == "CCSPlayerController" {
+			return true, []string{"m_iszPlayerName", "m_pInGameMoneyServices"}
+		}
+
+		return true, nil
+	})
+
+	parseEntities(t, p, testEntityFilterMsgs(t)[:2])
+
+	assert.Equal(t, map[string]any{
+		"m_iszPlayerName":                              "s1mple",
+		"m_pInGameMoneyServices.m_iAccount":            int32(1200),
+		"m_pInGameMoneyServices.m_iCashSpentThisRound": int32(4700),
+	}, setProperties(p.entities[3]))
+	assert.Equal(t, int32(-1), p.entities[2].Get("m_nSmokeEffectTickBegin"))
+}
+
+func TestParser_OnPacketEntities_LeavePVS(t *testing.T) {
+	ents := testEntities(t)
+	p := newTestParser(t, ents)
+
+	ops := parseEntities(t, p, []*msg.CSVCMsg_PacketEntities{
+		ents.PacketEntitiesMsg(false,
+			demotest.Create(1, testClassTeam, map[string]any{"m_iScore": 1}),
+			demotest.Create(2, testClassController, map[string]any{"m_iszPlayerName": "s1mple"}),
+		),
+		ents.PacketEntitiesMsg(true, demotest.Leave(1), demotest.Leave(2)),
+		ents.PacketEntitiesMsg(true, demotest.Leave(2)), // already left, suppressed
+		ents.PacketEntitiesMsg(true, demotest.Update(2, map[string]any{"m_iScore": 5})),
+		ents.PacketEntitiesMsg(true, demotest.Update(2, map[string]any{"m_iScore": 6})),
+		ents.PacketEntitiesMsg(true, demotest.Delete(1)),
+	})
+
+	assert.Equal(t, []testEntityOp{
+		{1, "CCSTeam", st.EntityOpCreated | st.EntityOpEntered},
+		{2, "CCSPlayerController", st.EntityOpCreated | st.EntityOpEntered},
+		{1, "CCSTeam", st.EntityOpLeft},
+		{2, "CCSPlayerController", st.EntityOpLeft},
+		{2, "CCSPlayerController", st.EntityOpUpdated | st.EntityOpEntered},
+		{2, "CCSPlayerController", st.EntityOpUpdated},
+		{1, "CCSTeam", st.EntityOpLeft | st.EntityOpDeleted},
+	}, ops)
+
+	assert.Equal(t, map[int32]map[string]any{
+		2: {"m_iszPlayerName": "s1mple", "m_iScore": int32(6)},
+	}, entityMaps(p))
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"math"
 	"strings"
+	"time"
 
 	"google.golang.org/protobuf/proto"
 
@@ -68,8 +69,15 @@ type Parser struct {
 	pathCache                   []*fieldPath
 	tuplesCache                 []tuple
 	packetEntitiesPanicWarnFunc func(error)
+	entityFilter                EntityFilter
+	skippedEntities             map[int32]*class // Entities of classes that aren't decoded, by index
+	entitiesDecodedHandler      func(time.Duration)
 }
 
+// EntityFilter decides whether entities of a server class are decoded and which of their properties.
+// A nil properties slice means all properties. See Parser.SetEntityFilter().
+type EntityFilter func(sc st.ServerClass) (decode bool, properties []string)
+
 func (p *Parser) ReadEnterPVS(r *bit.BitReader, index int, entities map[int]st.Entity, slot int) st.Entity {
 	panic("implement me")
 }
@@ -114,6 +122,7 @@ func NewParser(packetEntitiesPanicWarnFunc func(error)) *Parser {
 		classesById:                 make(map[int32]*class),
 		classesByName:               make(map[string]*class),
 		entities:                    make(map[int32]*Entity),
+		skippedEntities:             make(map[int32]*class),
 		packetEntitiesPanicWarnFunc: packetEntitiesPanicWarnFunc,
 	}
 }
@@ -141,11 +150,46 @@ func (p *Parser) OnDemoClassInfo(m *msg.CDemoClassInfo) error {
 		}
 		p.classesById[class.classId] = class
 		p.classesByName[class.name] = class
+
+		p.applyEntityFilter(class)
 	}
 
 	return nil
 }
 
+// SetEntityFilter sets a filter that decides which entities and properties are decoded, nil means all of them.
+//
+// Entities of skipped classes still have to be read from PacketEntities messages, but no state is built up for them.
+// They aren't passed to entity handlers and can't be found via FindEntity().
+// Properties that aren't decoded have nil values and don't trigger update handlers.
+//
+// Intended for internal use only.
+func (p *Parser) SetEntityFilter(filter EntityFilter) {
+	p.entityFilter = filter
+
+	for _, c := range p.classesById {
+		p.applyEntityFilter(c)
+	}
+}
+
+func (p *Parser) applyEntityFilter(c *class) {
+	c.skipDecoding = false
+	c.properties = nil
+
+	if p.entityFilter == nil {
+		return
+	}
+
+	decode, properties := p.entityFilter(c)
+
+	c.skipDecoding = !decode
+
+	if decode && properties != nil {
+		c.properties = properties
+		c.decodeCache = make(map[string]bool)
+	}
+}
+
 // SetInstanceBaseline sets the raw instance-baseline data for a serverclass by ID.
 //
 // Intended for internal use only.
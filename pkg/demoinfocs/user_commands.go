@@ -0,0 +1,88 @@
+package demoinfocs
+
+import (
+	"fmt"
+	"reflect"
+
+	"google.golang.org/protobuf/proto"
+
+	common "github.com/markus-wa/demoinfocs-golang/v5/pkg/demoinfocs/common"
+	events "github.com/markus-wa/demoinfocs-golang/v5/pkg/demoinfocs/events"
+	msg "github.com/markus-wa/demoinfocs-golang/v5/pkg/demoinfocs/msg"
+)
+
+var playerInputType = reflect.TypeFor[events.PlayerInput]()
+
+// hasPlayerInputHandlers returns true if there is a handler for exactly events.PlayerInput.
+// Catch-all handlers (e.g. of Events() & RegisterEnvelopeHandler()) don't count, decoding all user commands is expensive.
+func (p *parser) hasPlayerInputHandlers() bool {
+	return p.eventDispatcher.hasHandlersOfType(playerInputType)
+}
+
+// handleDemoUserCmd handles the user commands of the recording player of POV demos.
+func (p *parser) handleDemoUserCmd(cmd *msg.CDemoUserCmd) {
+	if !p.hasPlayerInputHandlers() {
+		return
+	}
+
+	p.dispatchPlayerInput(p.RecordingPlayer(), cmd.GetCmdNumber(), cmd.GetData())
+}
+
+// handleUserCommands handles the user commands of all players that some demos contain.
+func (p *parser) handleUserCommands(cmds *msg.CSVCMsg_UserCommands) {
+	if !p.hasPlayerInputHandlers() {
+		return
+	}
+
+	for _, cmd := range cmds.GetCommands() {
+		// The player controller entity-ID is the player slot + 1
+		pl := p.gameState.playersByEntityID[int(cmd.GetPlayerSlot())+1]
+
+		p.dispatchPlayerInput(pl, cmd.GetCmdNumber(), cmd.GetData())
+	}
+}
+
+func (p *parser) dispatchPlayerInput(pl *common.Player, cmdNumber int32, data []byte) {
+	cmd := new(msg.CSGOUserCmdPB)
+
+	err := proto.Unmarshal(data, cmd)
+	if err != nil {
+		p.eventDispatcher.Dispatch(events.ParserWarn{
+			Message: fmt.Sprintf("failed to decode user command %d: %v", cmdNumber, err),
+			Type:    events.WarnTypeCorruptUserCommand,
+		})
+
+		return
+	}
+
+	base := cmd.GetBase()
+
+	subtickMoves := make([]events.SubtickMove, 0, len(base.GetSubtickMoves()))
+
+	for _, move := range base.GetSubtickMoves() {
+		subtickMoves = append(subtickMoves, events.SubtickMove{
+			Button:             events.Button(move.GetButton()),
+			Pressed:            move.GetPressed(),
+			When:               move.GetWhen(),
+			AnalogForwardDelta: move.GetAnalogForwardDelta(),
+			AnalogLeftDelta:    move.GetAnalogLeftDelta(),
+		})
+	}
+
+	p.eventDispatcher.Dispatch(events.PlayerInput{
+		Player:         pl,
+		CommandNumber:  int(cmdNumber),
+		ClientTick:     int(base.GetClientTick()),
+		Buttons:        events.Button(base.GetButtonsPb().GetButtonstate1()),
+		ButtonsChanged: events.Button(base.GetButtonsPb().GetButtonstate2()),
+		ForwardMove:    base.GetForwardmove(),
+		LeftMove:       base.GetLeftmove(),
+		UpMove:         base.GetUpmove(),
+		Pitch:          base.GetViewangles().GetX(),
+		Yaw:            base.GetViewangles().GetY(),
+		MouseDX:        int(base.GetMousedx()),
+		MouseDY:        int(base.GetMousedy()),
+		WeaponSelect:   int(base.GetWeaponselect()),
+		SubtickMoves:   subtickMoves,
+	})
+}
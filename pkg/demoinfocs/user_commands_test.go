@@ -0,0 +1,205 @@
+package demoinfocs
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/markus-wa/demoinfocs-golang/v5/internal/demotest"
+	common "github.com/markus-wa/demoinfocs-golang/v5/pkg/demoinfocs/common"
+	events "github.com/markus-wa/demoinfocs-golang/v5/pkg/demoinfocs/events"
+	msg "github.com/markus-wa/demoinfocs-golang/v5/pkg/demoinfocs/msg"
+)
+
+func testUserCmd(t *testing.T, clientTick int32, buttons events.Button) []byte {
+	t.Helper()
+
+	b, err := proto.Marshal(&msg.CSGOUserCmdPB{
+		Base: &msg.CBaseUserCmdPB{
+			ClientTick: proto.Int32(clientTick),
+			ButtonsPb: &msg.CInButtonStatePB{
+				Buttonstate1: proto.Uint64(uint64(buttons)),
+				Buttonstate2: proto.Uint64(uint64(events.ButtonJump)),
+			},
+			Viewangles:  &msg.CMsgQAngle{X: proto.Float32(10), Y: proto.Float32(90)},
+			Forwardmove: proto.Float32(1),
+			Leftmove:    proto.Float32(-1),
+			SubtickMoves: []*msg.CSubtickMoveStep{{
+				Button:  proto.Uint64(uint64(events.ButtonJump)),
+				Pressed: proto.Bool(true),
+				When:    proto.Float32(0.5),
+			}},
+		},
+	})
+	assert.NoError(t, err)
+
+	return b
+}
+
+func TestParser_PlayerInput(t *testing.T) {
+	d := demotest.New(t).TickPacket(0)
+	d.Frame(msg.EDemoCommands_DEM_UserCmd, 1, &msg.CDemoUserCmd{
+		CmdNumber: proto.Int32(5),
+		Data:      testUserCmd(t, 100, events.ButtonForward|events.ButtonJump),
+	})
+	d.Packet(2, demotest.NetMsg{Type: int32(msg.SVC_Messages_svc_UserCmds), Msg: &msg.CSVCMsg_UserCommands{
+		Commands: []*msg.CMsgServerUserCmd{{
+			CmdNumber:  proto.Int32(6),
+			PlayerSlot: proto.Int32(1),
+			Data:       testUserCmd(t, 101, events.ButtonDuck),
+		}},
+	}})
+
+	p := NewParser(bytes.NewReader(d.Stop(3).Bytes()))
+	defer p.Close()
+
+	var inputs []events.PlayerInput
+
+	On(p, func(e events.PlayerInput) {
+		inputs = append(inputs, e)
+	})
+
+	assert.NoError(t, p.ParseToEnd())
+
+	subtickMoves := []events.SubtickMove{{
+		Button:  events.ButtonJump,
+		Pressed: true,
+		When:    0.5,
+	}}
+
+	// Synthetic demos don't contain players
+	assert.Equal(t, []events.PlayerInput{{
+		CommandNumber:  5,
+		ClientTick:     100,
+		Buttons:        events.ButtonForward | events.ButtonJump,
+		ButtonsChanged: events.ButtonJump,
+		ForwardMove:    1,
+		LeftMove:       -1,
+		Pitch:          10,
+		Yaw:            90,
+		SubtickMoves:   subtickMoves,
+	}, {
+		CommandNumber:  6,
+		ClientTick:     101,
+		Buttons:        events.ButtonDuck,
+		ButtonsChanged: events.ButtonJump,
+		ForwardMove:    1,
+		LeftMove:       -1,
+		Pitch:          10,
+		Yaw:            90,
+		SubtickMoves:   subtickMoves,
+	}}, inputs)
+
+	assert.True(t, inputs[0].IsPressed(events.ButtonJump))
+	assert.False(t, inputs[0].IsPressed(events.ButtonDuck))
+}
+
+func TestParser_PlayerInput_NoHandler(t *testing.T) {
+	d := demotest.New(t).TickPacket(0)
+	d.Packet(1, demotest.NetMsg{Type: int32(msg.SVC_Messages_svc_UserCmds), Msg: &msg.CSVCMsg_UserCommands{
+		Commands: []*msg.CMsgServerUserCmd{{
+			CmdNumber: proto.Int32(6),
+			Data:      testUserCmd(t, 101, events.ButtonDuck),
+		}},
+	}})
+
+	p := NewParser(bytes.NewReader(d.Stop(2).Bytes()))
+	defer p.Close()
+
+	assert.NoError(t, p.ParseToEnd())
+
+	counts := p.NetMessageCounts()[int32(msg.SVC_Messages_svc_UserCmds)]
+	assert.Equal(t, 1, counts.Read)
+	assert.Zero(t, counts.Decoded, "user commands shouldn't be unmarshalled without PlayerInput handlers")
+}
+
+func TestParser_PlayerInput_CatchAllHandlers(t *testing.T) {
+	d := demotest.New(t).TickPacket(0)
+	d.Frame(msg.EDemoCommands_DEM_UserCmd, 1, &msg.CDemoUserCmd{
+		CmdNumber: proto.Int32(5),
+		Data:      testUserCmd(t, 100, events.ButtonForward),
+	})
+	d.Packet(2, demotest.NetMsg{Type: int32(msg.SVC_Messages_svc_UserCmds), Msg: &msg.CSVCMsg_UserCommands{
+		Commands: []*msg.CMsgServerUserCmd{{
+			CmdNumber: proto.Int32(6),
+			Data:      testUserCmd(t, 101, events.ButtonDuck),
+		}},
+	}})
+
+	p := NewParser(bytes.NewReader(d.Stop(3).Bytes()))
+	defer p.Close()
+
+	var inputs int
+
+	p.RegisterEnvelopeHandler(func(e events.Envelope) {
+		if _, ok := e.Event.(events.PlayerInput); ok {
+			inputs++
+		}
+	})
+
+	for e, err := range Events(p) {
+		assert.NoError(t, err)
+
+		if _, ok := e.(events.PlayerInput); ok {
+			inputs++
+		}
+	}
+
+	assert.Zero(t, inputs)
+	assert.Zero(t, p.NetMessageCounts()[int32(msg.SVC_Messages_svc_UserCmds)].Decoded,
+		"catch-all handlers shouldn't cause user commands to be unmarshalled")
+}
+
+func TestParser_PlayerInput_Player(t *testing.T) {
+	p := newParserWithoutInput(ParserConfig{})
+
+	recorder := &common.Player{Name: "recorder"}
+	other := &common.Player{Name: "other"}
+	p.gameState.playersByEntityID[1] = recorder
+	p.gameState.playersByEntityID[2] = other
+	p.recordingPlayerSlot = 0
+
+	var players []*common.Player
+
+	p.RegisterEventHandler(func(e events.PlayerInput) {
+		players = append(players, e.Player)
+	})
+
+	p.handleDemoUserCmd(&msg.CDemoUserCmd{Data: testUserCmd(t, 1, 0)})
+	p.handleUserCommands(&msg.CSVCMsg_UserCommands{
+		Commands: []*msg.CMsgServerUserCmd{
+			{PlayerSlot: proto.Int32(1), Data: testUserCmd(t, 1, 0)},
+			{PlayerSlot: proto.Int32(0), Data: testUserCmd(t, 1, 0)},
+		},
+	})
+
+	assert.Equal(t, []*common.Player{recorder, other, recorder}, players)
+}
+
+func TestParser_PlayerInput_Corrupt(t *testing.T) {
+	p := newParserWithoutInput(ParserConfig{})
+
+	var warnings []events.ParserWarn
+
+	p.RegisterEventHandler(func(e events.ParserWarn) {
+		warnings = append(warnings, e)
+	})
+
+	corrupt := &msg.CDemoUserCmd{Data: []byte{0xff}}
+
+	// Not decoded without handlers
+	p.handleDemoUserCmd(corrupt)
+	assert.Empty(t, warnings)
+
+	p.RegisterEventHandler(func(events.PlayerInput) {
+		assert.Fail(t, "unexpected PlayerInput")
+	})
+
+	p.handleDemoUserCmd(corrupt)
+
+	if assert.Len(t, warnings, 1) {
+		assert.Equal(t, events.WarnType(events.WarnTypeCorruptUserCommand), warnings[0].Type)
+	}
+}